@@ -0,0 +1,81 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The condition types the phase is derived from, they are set by the operator.
+const (
+	// ConditionReferencesValid is False when nothing is created, as a reference leaves the namespace.
+	ConditionReferencesValid = "ReferencesValid"
+
+	// ConditionBlocked is True when the Pod Security admission refuses some of the pods.
+	ConditionBlocked = "Blocked"
+
+	// ConditionSuspended is True when the workbench is scaled to zero, as requested.
+	ConditionSuspended = "Suspended"
+)
+
+// ComputePhase summarizes the status into a single phase, for the simple consumers.
+//
+// The first matching phase wins:
+//
+//   - Deleting, the workbench is being deleted;
+//   - Failed, the server failed, or nothing can be created (references, pod security);
+//   - Hibernated, the workbench is suspended;
+//   - Degraded, an app failed;
+//   - Hibernated, there are apps and all of them are stopped;
+//   - Creating, the server or an app is not running yet;
+//   - Ready, otherwise.
+func ComputePhase(status WorkbenchStatus, deletionTimestamp *metav1.Time) WorkbenchPhase {
+	if deletionTimestamp != nil {
+		return WorkbenchPhaseDeleting
+	}
+
+	if status.Server.Status == WorkbenchStatusServerStatusFailed ||
+		meta.IsStatusConditionFalse(status.Conditions, ConditionReferencesValid) ||
+		meta.IsStatusConditionTrue(status.Conditions, ConditionBlocked) {
+		return WorkbenchPhaseFailed
+	}
+
+	if meta.IsStatusConditionTrue(status.Conditions, ConditionSuspended) {
+		return WorkbenchPhaseHibernated
+	}
+
+	stopped := 0
+	pending := false
+
+	for _, app := range status.Apps {
+		switch app.Status {
+		case WorkbenchStatusAppStatusFailed:
+			return WorkbenchPhaseDegraded
+		case WorkbenchStatusAppStatusStopped:
+			stopped++
+		case WorkbenchStatusAppStatusUnknown, WorkbenchStatusAppStatusProgressing, WorkbenchStatusAppStatusRetrying, "":
+			pending = true
+		}
+	}
+
+	if len(status.Apps) > 0 && stopped == len(status.Apps) {
+		return WorkbenchPhaseHibernated
+	}
+
+	if status.Server.Status != WorkbenchStatusServerStatusRunning || pending {
+		return WorkbenchPhaseCreating
+	}
+
+	return WorkbenchPhaseReady
+}
+
+// UpdateStatusPhase computes the phase from the rest of the status.
+func (wb *Workbench) UpdateStatusPhase() bool {
+	phase := ComputePhase(wb.Status, wb.DeletionTimestamp)
+	if phase == wb.Status.Phase {
+		return false
+	}
+
+	wb.Status.Phase = phase
+
+	return true
+}
@@ -0,0 +1,82 @@
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Workbench phase", func() {
+	now := metav1.Now()
+
+	server := func(status WorkbenchStatusServerStatus) WorkbenchStatusServer {
+		return WorkbenchStatusServer{Status: status}
+	}
+
+	apps := func(statuses ...WorkbenchStatusAppStatus) []WorkbenchStatusApp {
+		apps := []WorkbenchStatusApp{}
+		for _, status := range statuses {
+			apps = append(apps, WorkbenchStatusApp{Status: status})
+		}
+
+		return apps
+	}
+
+	condition := func(conditionType string, status metav1.ConditionStatus) []metav1.Condition {
+		return []metav1.Condition{{Type: conditionType, Status: status}}
+	}
+
+	running := WorkbenchStatusServerStatusRunning
+
+	DescribeTable("computing the phase",
+		func(status WorkbenchStatus, deletionTimestamp *metav1.Time, phase WorkbenchPhase) {
+			Expect(ComputePhase(status, deletionTimestamp)).To(Equal(phase))
+		},
+		// Deleting wins over everything.
+		Entry("a deleted workbench", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusRunning)}, &now, WorkbenchPhaseDeleting),
+		Entry("a deleted failed workbench", WorkbenchStatus{Server: server(WorkbenchStatusServerStatusFailed)}, &now, WorkbenchPhaseDeleting),
+
+		// Failed
+		Entry("a failed server", WorkbenchStatus{Server: server(WorkbenchStatusServerStatusFailed)}, nil, WorkbenchPhaseFailed),
+		Entry("a failed server and app", WorkbenchStatus{Server: server(WorkbenchStatusServerStatusFailed), Apps: apps(WorkbenchStatusAppStatusFailed)}, nil, WorkbenchPhaseFailed),
+		Entry("a reference out of the namespace", WorkbenchStatus{Conditions: condition(ConditionReferencesValid, metav1.ConditionFalse)}, nil, WorkbenchPhaseFailed),
+		Entry("blocked pods", WorkbenchStatus{Server: server(running), Conditions: condition(ConditionBlocked, metav1.ConditionTrue)}, nil, WorkbenchPhaseFailed),
+
+		// Degraded
+		Entry("a failed app", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusRunning, WorkbenchStatusAppStatusFailed)}, nil, WorkbenchPhaseDegraded),
+		Entry("a failed app among stopped ones", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusStopped, WorkbenchStatusAppStatusFailed)}, nil, WorkbenchPhaseDegraded),
+		Entry("a failed app on a starting server", WorkbenchStatus{Server: server(WorkbenchStatusServerStatusProgressing), Apps: apps(WorkbenchStatusAppStatusFailed)}, nil, WorkbenchPhaseDegraded),
+
+		// Hibernated
+		Entry("all the apps stopped", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusStopped, WorkbenchStatusAppStatusStopped)}, nil, WorkbenchPhaseHibernated),
+		Entry("all the apps stopped on a starting server", WorkbenchStatus{Server: server(WorkbenchStatusServerStatusProgressing), Apps: apps(WorkbenchStatusAppStatusStopped)}, nil, WorkbenchPhaseHibernated),
+		Entry("a suspended workbench", WorkbenchStatus{Server: server(WorkbenchStatusServerStatusProgressing), Apps: apps(WorkbenchStatusAppStatusFailed), Conditions: condition(ConditionSuspended, metav1.ConditionTrue)}, nil, WorkbenchPhaseHibernated),
+
+		// Creating
+		Entry("an empty status", WorkbenchStatus{}, nil, WorkbenchPhaseCreating),
+		Entry("a starting server", WorkbenchStatus{Server: server(WorkbenchStatusServerStatusProgressing)}, nil, WorkbenchPhaseCreating),
+		Entry("a starting app", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusRunning, WorkbenchStatusAppStatusProgressing)}, nil, WorkbenchPhaseCreating),
+		Entry("an unknown app", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusUnknown)}, nil, WorkbenchPhaseCreating),
+		Entry("a stopped app and a starting one", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusStopped, WorkbenchStatusAppStatusProgressing)}, nil, WorkbenchPhaseCreating),
+
+		// Ready
+		Entry("a server without apps", WorkbenchStatus{Server: server(running)}, nil, WorkbenchPhaseReady),
+		Entry("running apps", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusRunning, WorkbenchStatusAppStatusRunning)}, nil, WorkbenchPhaseReady),
+		Entry("a complete app and a stopped one", WorkbenchStatus{Server: server(running), Apps: apps(WorkbenchStatusAppStatusComplete, WorkbenchStatusAppStatusStopped)}, nil, WorkbenchPhaseReady),
+		Entry("valid references", WorkbenchStatus{Server: server(running), Conditions: condition(ConditionReferencesValid, metav1.ConditionTrue)}, nil, WorkbenchPhaseReady),
+		Entry("unblocked pods", WorkbenchStatus{Server: server(running), Conditions: condition(ConditionBlocked, metav1.ConditionFalse)}, nil, WorkbenchPhaseReady),
+	)
+
+	It("should only report the phase changes", func() {
+		workbench := Workbench{}
+		workbench.Status.Server.Status = running
+
+		Expect(workbench.UpdateStatusPhase()).To(BeTrue())
+		Expect(workbench.Status.Phase).To(Equal(WorkbenchPhaseReady))
+		Expect(workbench.UpdateStatusPhase()).To(BeFalse())
+
+		workbench.DeletionTimestamp = &now
+		Expect(workbench.UpdateStatusPhase()).To(BeTrue())
+		Expect(workbench.Status.Phase).To(Equal(WorkbenchPhaseDeleting))
+	})
+})
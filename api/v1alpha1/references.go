@@ -0,0 +1,111 @@
+package v1alpha1
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// crossNamespaceDetail explains why a qualified reference is refused.
+const crossNamespaceDetail = "must name a resource of the workbench namespace, not of another one"
+
+// ValidateReferences refuses the references to namespaced resources outside of the workbench namespace.
+//
+// The references are plain names, a "namespace/name" one is a wrong assumption that would
+// either fail late or, worse, reach the resources of another namespace, e.g. the operator's.
+func (wb *Workbench) ValidateReferences() field.ErrorList {
+	errs := field.ErrorList{}
+
+	specPath := field.NewPath("spec")
+
+	errs = append(errs, validateLocalName(wb.Spec.ServiceAccount, specPath.Child("serviceAccountName"))...)
+
+	for index, name := range wb.Spec.ImagePullSecrets {
+		errs = append(errs, validateLocalName(name, specPath.Child("imagePullSecrets").Index(index))...)
+	}
+
+	// The paths are only built for the containers with references, most have none.
+	appsPath := specPath.Child("apps")
+	for index, app := range wb.Spec.Apps {
+		for mountIndex, mount := range app.ExtraMounts {
+			if !strings.Contains(mount.ConfigMap+mount.Secret, "/") {
+				continue
+			}
+
+			mountPath := appsPath.Index(index).Child("extraMounts").Index(mountIndex)
+			errs = append(errs, validateLocalName(mount.ConfigMap, mountPath.Child("configMap"))...)
+			errs = append(errs, validateLocalName(mount.Secret, mountPath.Child("secret"))...)
+		}
+
+		for sidecarIndex, sidecar := range app.SidecarContainers {
+			if !hasReferences(sidecar) {
+				continue
+			}
+
+			sidecarPath := appsPath.Index(index).Child("sidecarContainers").Index(sidecarIndex)
+			errs = append(errs, validateContainerReferences(sidecar, sidecarPath)...)
+		}
+	}
+
+	return errs
+}
+
+// hasReferences tells whether the environment of the container is read from anything.
+func hasReferences(container corev1.Container) bool {
+	if len(container.EnvFrom) > 0 {
+		return true
+	}
+
+	for _, env := range container.Env {
+		if env.ValueFrom != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateContainerReferences checks the secrets and config maps the environment is read from.
+func validateContainerReferences(container corev1.Container, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for index, env := range container.Env {
+		if env.ValueFrom == nil {
+			continue
+		}
+
+		valueFromPath := path.Child("env").Index(index).Child("valueFrom")
+
+		if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+			errs = append(errs, validateLocalName(ref.Name, valueFromPath.Child("secretKeyRef", "name"))...)
+		}
+
+		if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+			errs = append(errs, validateLocalName(ref.Name, valueFromPath.Child("configMapKeyRef", "name"))...)
+		}
+	}
+
+	for index, envFrom := range container.EnvFrom {
+		envFromPath := path.Child("envFrom").Index(index)
+
+		if ref := envFrom.SecretRef; ref != nil {
+			errs = append(errs, validateLocalName(ref.Name, envFromPath.Child("secretRef", "name"))...)
+		}
+
+		if ref := envFrom.ConfigMapRef; ref != nil {
+			errs = append(errs, validateLocalName(ref.Name, envFromPath.Child("configMapRef", "name"))...)
+		}
+	}
+
+	return errs
+}
+
+// validateLocalName refuses a name qualified by a namespace.
+func validateLocalName(name string, path *field.Path) field.ErrorList {
+	if !strings.Contains(name, "/") {
+		return nil
+	}
+
+	return field.ErrorList{field.Invalid(path, name, crossNamespaceDetail)}
+}
@@ -0,0 +1,96 @@
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Workbench references", func() {
+	withSidecar := func(sidecar corev1.Container) WorkbenchSpec {
+		sidecar.Name = "sidecar"
+
+		return WorkbenchSpec{
+			Apps: []WorkbenchApp{
+				{Name: "wezterm", SidecarContainers: []corev1.Container{sidecar}},
+			},
+		}
+	}
+
+	secretKey := func(name string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Key:                  "key",
+			},
+		}
+	}
+
+	configMapKey := func(name string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Key:                  "key",
+			},
+		}
+	}
+
+	DescribeTable("validating the references",
+		func(spec WorkbenchSpec, invalidField string) {
+			workbench := &Workbench{Spec: spec}
+
+			errs := workbench.ValidateReferences()
+			if invalidField == "" {
+				Expect(errs).To(BeEmpty())
+				return
+			}
+
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal(invalidField))
+		},
+		Entry("without references", WorkbenchSpec{}, ""),
+		Entry("local references", WorkbenchSpec{
+			ServiceAccount:   "apps",
+			ImagePullSecrets: []string{"registry"},
+		}, ""),
+		Entry("a foreign service account", WorkbenchSpec{
+			ServiceAccount: "workbench-operator-system/controller-manager",
+		}, "spec.serviceAccountName"),
+		Entry("a foreign pull secret", WorkbenchSpec{
+			ImagePullSecrets: []string{"registry", "workbench-operator-system/registry"},
+		}, "spec.imagePullSecrets[1]"),
+		Entry("local extra mounts", WorkbenchSpec{
+			Apps: []WorkbenchApp{{Name: "app", ExtraMounts: []AppMount{
+				{ConfigMap: "settings", MountPath: "/etc/app"},
+				{Secret: "license", MountPath: "/opt/license"},
+			}}},
+		}, ""),
+		Entry("a foreign extra mount", WorkbenchSpec{
+			Apps: []WorkbenchApp{{Name: "app", ExtraMounts: []AppMount{
+				{ConfigMap: "settings", MountPath: "/etc/app"},
+				{Secret: "other/license", MountPath: "/opt/license"},
+			}}},
+		}, "spec.apps[0].extraMounts[1].secret"),
+		Entry("a foreign env secret", withSidecar(corev1.Container{
+			Env: []corev1.EnvVar{
+				{Name: "PLAIN", Value: "value"},
+				{Name: "TOKEN", ValueFrom: secretKey("other/token")},
+			},
+		}), "spec.apps[0].sidecarContainers[0].env[1].valueFrom.secretKeyRef.name"),
+		Entry("a foreign env config map", withSidecar(corev1.Container{
+			Env: []corev1.EnvVar{
+				{Name: "SETTING", ValueFrom: configMapKey("other/settings")},
+			},
+		}), "spec.apps[0].sidecarContainers[0].env[0].valueFrom.configMapKeyRef.name"),
+		Entry("a foreign envFrom secret", withSidecar(corev1.Container{
+			EnvFrom: []corev1.EnvFromSource{
+				{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "other/env"}}},
+			},
+		}), "spec.apps[0].sidecarContainers[0].envFrom[0].secretRef.name"),
+		Entry("a foreign envFrom config map", withSidecar(corev1.Container{
+			EnvFrom: []corev1.EnvFromSource{
+				{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "other/env"}}},
+			},
+		}), "spec.apps[0].sidecarContainers[0].envFrom[0].configMapRef.name"),
+	)
+})
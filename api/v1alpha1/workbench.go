@@ -1,10 +1,16 @@
 package v1alpha1
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // UpdateStatusFromDeployment enriches the workbench status based on the deployment.
@@ -67,10 +73,60 @@ func (wb *Workbench) UpdateStatusFromDeployment(deployment appsv1.Deployment) bo
 	return updated
 }
 
-// UpdateStatusAppFromDeployment enriches the workbench status based on the deployment.
+// UpdateStatusFromServerPods reports the health of the Xpra server container, from its pods.
+//
+// The deployment only tells whether a replica is available, not that it keeps restarting.
+func (wb *Workbench) UpdateStatusFromServerPods(restarts int32, message string) bool {
+	updated := false
+
+	if wb.Status.Server.Restarts != restarts {
+		wb.Status.Server.Restarts = restarts
+		updated = true
+	}
+
+	if wb.Status.Server.Message != message {
+		wb.Status.Server.Message = message
+		updated = true
+	}
+
+	return updated
+}
+
+// UpdateStatusFromObservers sets the health of the observer servers.
+func (wb *Workbench) UpdateStatusFromObservers(observers []WorkbenchStatusObserver) bool {
+	if equality.Semantic.DeepEqual(wb.Status.Server.Observers, observers) {
+		return false
+	}
+
+	wb.Status.Server.Observers = observers
+
+	return true
+}
+
+// UpdateStatusFromActivity moves the last activity of the server forward, never back.
+func (wb *Workbench) UpdateStatusFromActivity(activity metav1.Time) bool {
+	if activity.IsZero() {
+		return false
+	}
+
+	last := wb.Status.Server.LastActivity
+	if last != nil && !last.Before(&activity) {
+		return false
+	}
+
+	wb.Status.Server.LastActivity = activity.DeepCopy()
+
+	return true
+}
+
+// UpdateStatusFromJob enriches the workbench status based on the job.
 //
 // It's not a *best* practice to do so, but it's very convenient.
-func (wb *Workbench) UpdateStatusFromJob(index int, job batchv1.Job) bool {
+//
+// To absorb the flapping of evicted pods, a status is held for at least the damping
+// duration before it's replaced, except by a terminal one. The returned duration
+// tells when the held change may be published, or when the app runs out of startup time.
+func (wb *Workbench) UpdateStatusFromJob(index int, job batchv1.Job, now metav1.Time, damping time.Duration) (bool, time.Duration) {
 	// Grow the slice of StatusApps for the new index.
 	for len(wb.Status.Apps) < index+1 {
 		wb.Status.Apps = append(wb.Status.Apps, WorkbenchStatusApp{
@@ -81,26 +137,320 @@ func (wb *Workbench) UpdateStatusFromJob(index int, job batchv1.Job) bool {
 
 	app := wb.Status.Apps[index]
 
+	// The desired state tells apart a user stop from a crash or a completion.
+	desiredState := WorkbenchAppStateRunning
+	if index < len(wb.Spec.Apps) && wb.Spec.Apps[index].State != "" {
+		desiredState = wb.Spec.Apps[index].State
+	}
+
 	// Default status
 	status := app.Status
 
+	// Only a job out of retries has definitely failed.
+	terminal := false
+
+	ready := job.Status.Active == 1 && job.Status.Ready != nil && *job.Status.Ready >= 1
+
+	var startupDeadline time.Duration
+	if index < len(wb.Spec.Apps) && wb.Spec.Apps[index].StartupDeadlineSeconds != nil {
+		startupDeadline = time.Duration(*wb.Spec.Apps[index].StartupDeadlineSeconds) * time.Second
+	}
+
+	message := ""
+	var startupLeft time.Duration
+
 	if job.Status.Active == 1 {
-		if job.Status.Ready != nil && *job.Status.Ready >= 1 {
+		exceeded, left := startupDeadlineExceeded(app, job, startupDeadline, now)
+		if !ready {
+			startupLeft = left
+		}
+
+		if ready {
 			status = WorkbenchStatusAppStatusRunning
+		} else if exceeded {
+			status = WorkbenchStatusAppStatusFailed
+			terminal = true
+			message = startupDeadlineMessage(startupDeadline)
+		} else if app.Attempts > 0 {
+			status = WorkbenchStatusAppStatusRetrying
 		} else {
 			status = WorkbenchStatusAppStatusProgressing
 		}
 	} else {
-		if job.Status.Succeeded >= 1 {
+		terminal = true
+
+		if desiredState != WorkbenchAppStateRunning {
+			status = WorkbenchStatusAppStatusStopped
+		} else if job.Status.Succeeded >= 1 {
 			status = WorkbenchStatusAppStatusComplete
 		} else {
 			status = WorkbenchStatusAppStatusFailed
+			terminal = isJobFailed(job)
+
+			// Killed by the operator once out of startup time.
+			if startupDeadline > 0 && jobFailedReason(job) == batchv1.JobReasonDeadlineExceeded {
+				message = startupDeadlineMessage(startupDeadline)
+			}
 		}
 	}
 
-	// Save it back
+	updated := false
+
+	if desiredState != app.DesiredState {
+		app.DesiredState = desiredState
+		updated = true
+	}
+
+	var retryAfter time.Duration
+
 	if status != app.Status {
-		app.Status = status
+		var held time.Duration
+		if !terminal && app.LastTransitionTime != nil {
+			held = damping - now.Sub(app.LastTransitionTime.Time)
+		}
+
+		if held > 0 {
+			retryAfter = held
+		} else {
+			app.Status = status
+			app.LastTransitionTime = &now
+			updated = true
+		}
+	}
+
+	if startupLeft > 0 && (retryAfter == 0 || startupLeft < retryAfter) {
+		retryAfter = startupLeft
+	}
+
+	// The job exists, a previous rejection is over.
+	if app.Message != message {
+		app.Message = message
+		updated = true
+	}
+
+	// The first readiness in the job ends its startup, even if the app restarts later on.
+	if ready && !isReadyInJob(app, job) {
+		app.ReadyTime = &now
+		updated = true
+	}
+
+	// Kept after the job is reaped, so the completed app is not started again.
+	if app.Status == WorkbenchStatusAppStatusComplete {
+		if app.CompletionTime == nil {
+			completionTime := now
+			if job.Status.CompletionTime != nil {
+				completionTime = *job.Status.CompletionTime
+			}
+
+			app.CompletionTime = &completionTime
+			app.CompletedImage = jobImage(job)
+			updated = true
+		}
+	} else if clearCompletion(&app) {
+		updated = true
+	}
+
+	// Save it back
+	if updated {
+		wb.Status.Apps[index] = app
+	}
+
+	return updated, retryAfter
+}
+
+// isJobFailed tells whether the job gave up, e.g. its backoff limit was reached.
+func isJobFailed(job batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StartupDeadlineExceeded starts the message of the apps not ready within their startup deadline.
+const StartupDeadlineExceeded = "StartupDeadlineExceeded"
+
+// startupDeadlineMessage explains, in the app status, why a running job is failed.
+func startupDeadlineMessage(deadline time.Duration) string {
+	return fmt.Sprintf("%s: the app was not ready within %s", StartupDeadlineExceeded, deadline)
+}
+
+// startupDeadlineExceeded tells whether the app was never ready in its job within the deadline,
+// and otherwise how long it has left.
+//
+// It's measured from the start of the job, reset when resumed, so it survives the restarts of
+// the operator; a zero deadline never expires.
+func startupDeadlineExceeded(app WorkbenchStatusApp, job batchv1.Job, deadline time.Duration, now metav1.Time) (bool, time.Duration) {
+	if deadline == 0 || job.Status.StartTime == nil || isReadyInJob(app, job) {
+		return false, 0
+	}
+
+	left := deadline - now.Sub(job.Status.StartTime.Time)
+	if left <= 0 {
+		return true, 0
+	}
+
+	return false, left
+}
+
+// isReadyInJob tells whether the app was ready since its job started last.
+func isReadyInJob(app WorkbenchStatusApp, job batchv1.Job) bool {
+	if app.ReadyTime == nil {
+		return false
+	}
+
+	return job.Status.StartTime == nil || !app.ReadyTime.Before(job.Status.StartTime)
+}
+
+// IsStartupDeadlineExceeded tells whether the app is failed for not being ready in time.
+func (wb *Workbench) IsStartupDeadlineExceeded(index int) bool {
+	return index < len(wb.Status.Apps) && strings.HasPrefix(wb.Status.Apps[index].Message, StartupDeadlineExceeded+":")
+}
+
+// jobFailedReason is the reason of the Failed condition of the job, if any.
+func jobFailedReason(job batchv1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return condition.Reason
+		}
+	}
+
+	return ""
+}
+
+// jobImage is the image of the app container of the job.
+func jobImage(job batchv1.Job) string {
+	if len(job.Spec.Template.Spec.Containers) == 0 {
+		return ""
+	}
+
+	return job.Spec.Template.Spec.Containers[0].Image
+}
+
+// clearCompletion forgets that the app completed, it tells whether it did.
+func clearCompletion(app *WorkbenchStatusApp) bool {
+	if app.CompletionTime == nil && app.CompletedImage == "" {
+		return false
+	}
+
+	app.CompletionTime = nil
+	app.CompletedImage = ""
+
+	return true
+}
+
+// IsAppCompleted tells whether the app completed with the image of the job, and is still to
+// be running. Its job is then not recreated once reaped.
+func (wb *Workbench) IsAppCompleted(index int, job batchv1.Job) bool {
+	if index >= len(wb.Status.Apps) {
+		return false
+	}
+
+	app := wb.Status.Apps[index]
+
+	return app.Status == WorkbenchStatusAppStatusComplete &&
+		app.CompletionTime != nil &&
+		app.CompletedImage == jobImage(job) &&
+		(job.Spec.Suspend == nil || !*job.Spec.Suspend)
+}
+
+// defaultBackoffLimit is the retries of a job not telling its backoff limit.
+const defaultBackoffLimit = int32(6)
+
+// UpdateStatusFromRetries counts the failures of the app job, and the retries it has left.
+//
+// The app pods are restarted in place, their restarts are failures the job does not count
+// until it gives up. It must be done before updating the status from the job, which tells a
+// retrying app apart from a starting one.
+func (wb *Workbench) UpdateStatusFromRetries(index int, job batchv1.Job, restarts int32) bool {
+	// Grow the slice of StatusApps for the new index.
+	for len(wb.Status.Apps) < index+1 {
+		wb.Status.Apps = append(wb.Status.Apps, WorkbenchStatusApp{
+			Revision: -1,
+			Status:   WorkbenchStatusAppStatusUnknown,
+		})
+	}
+
+	app := wb.Status.Apps[index]
+
+	// The backoff limit is the number of retries, each failure uses one.
+	limit := defaultBackoffLimit
+	if job.Spec.BackoffLimit != nil {
+		limit = *job.Spec.BackoffLimit
+	}
+
+	attempts := job.Status.Failed + restarts
+
+	// The pods of a job out of retries are gone with their restarts.
+	if isJobBackoffLimitExceeded(job) {
+		attempts = max(attempts, limit)
+	}
+
+	var remaining *int32
+	if attempts > 0 {
+		left := max(limit-attempts, 0)
+		remaining = &left
+	}
+
+	if app.Attempts == attempts && equalInt32(app.RemainingRetries, remaining) {
+		return false
+	}
+
+	app.Attempts = attempts
+	app.RemainingRetries = remaining
+
+	wb.Status.Apps[index] = app
+
+	return true
+}
+
+// isJobBackoffLimitExceeded tells whether the job gave up after too many failures.
+func isJobBackoffLimitExceeded(job batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return condition.Reason == batchv1.JobReasonBackoffLimitExceeded
+		}
+	}
+
+	return false
+}
+
+// equalInt32 compares two optional values.
+func equalInt32(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// UpdateStatusFromSuspended marks the app without a job as stopped, e.g. it was never started.
+//
+// The message tells why there is no job, if it's not the usual.
+func (wb *Workbench) UpdateStatusFromSuspended(index int, message string) bool {
+	// Grow the slice of StatusApps for the new index.
+	for len(wb.Status.Apps) < index+1 {
+		wb.Status.Apps = append(wb.Status.Apps, WorkbenchStatusApp{
+			Revision: -1,
+			Status:   WorkbenchStatusAppStatusUnknown,
+		})
+	}
+
+	app := wb.Status.Apps[index]
+
+	desiredState := WorkbenchAppStateStopped
+	if index < len(wb.Spec.Apps) && wb.Spec.Apps[index].State != "" {
+		desiredState = wb.Spec.Apps[index].State
+	}
+
+	if app.Status != WorkbenchStatusAppStatusStopped || app.DesiredState != desiredState || app.Message != message || app.Pod != nil {
+		app.Status = WorkbenchStatusAppStatusStopped
+		app.DesiredState = desiredState
+		app.Message = message
+		clearCompletion(&app)
+		app.Pod = nil
 
 		wb.Status.Apps[index] = app
 		return true
@@ -108,3 +458,85 @@ func (wb *Workbench) UpdateStatusFromJob(index int, job batchv1.Job) bool {
 
 	return false
 }
+
+// UpdateStatusFromRejected marks the app whose job was refused by the API server, with its message.
+func (wb *Workbench) UpdateStatusFromRejected(index int, message string, now metav1.Time) bool {
+	// Grow the slice of StatusApps for the new index.
+	for len(wb.Status.Apps) < index+1 {
+		wb.Status.Apps = append(wb.Status.Apps, WorkbenchStatusApp{
+			Revision: -1,
+			Status:   WorkbenchStatusAppStatusUnknown,
+		})
+	}
+
+	app := wb.Status.Apps[index]
+
+	desiredState := WorkbenchAppStateRunning
+	if index < len(wb.Spec.Apps) && wb.Spec.Apps[index].State != "" {
+		desiredState = wb.Spec.Apps[index].State
+	}
+
+	if app.Status == WorkbenchStatusAppStatusFailed && app.DesiredState == desiredState && app.Message == message && app.Pod == nil {
+		return false
+	}
+
+	if app.Status != WorkbenchStatusAppStatusFailed {
+		app.LastTransitionTime = &now
+	}
+
+	app.Status = WorkbenchStatusAppStatusFailed
+	app.DesiredState = desiredState
+	app.Message = message
+	clearCompletion(&app)
+	app.Pod = nil
+
+	wb.Status.Apps[index] = app
+
+	return true
+}
+
+// UpdateStatusFromAppPod sets the health of the newest pod of the job of an app.
+func (wb *Workbench) UpdateStatusFromAppPod(index int, pod *WorkbenchStatusAppPod) bool {
+	if index >= len(wb.Status.Apps) || equality.Semantic.DeepEqual(wb.Status.Apps[index].Pod, pod) {
+		return false
+	}
+
+	wb.Status.Apps[index].Pod = pod
+
+	return true
+}
+
+// UpdateStatusAppCounts counts the configured apps, and the ones actively running.
+//
+// Stopped, killed or completed apps are not running, neither are the status entries left
+// by apps removed from the spec.
+func (wb *Workbench) UpdateStatusAppCounts() bool {
+	appCount := len(wb.Spec.Apps)
+
+	runningAppCount := 0
+	for index, app := range wb.Status.Apps {
+		if index < appCount && app.Status == WorkbenchStatusAppStatusRunning {
+			runningAppCount++
+		}
+	}
+
+	if appCount == wb.Status.AppCount && runningAppCount == wb.Status.RunningAppCount {
+		return false
+	}
+
+	wb.Status.AppCount = appCount
+	wb.Status.RunningAppCount = runningAppCount
+
+	return true
+}
+
+// UpdateStatusEffective saves the effective configuration, telling whether it changed.
+func (wb *Workbench) UpdateStatusEffective(effective WorkbenchStatusEffective) bool {
+	if wb.Status.Effective != nil && equality.Semantic.DeepEqual(*wb.Status.Effective, effective) {
+		return false
+	}
+
+	wb.Status.Effective = effective.DeepCopy()
+
+	return true
+}
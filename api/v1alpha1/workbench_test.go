@@ -0,0 +1,538 @@
+package v1alpha1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Workbench status", func() {
+	now := metav1.NewTime(time.Unix(1000, 0))
+
+	Context("When updating the status from a job", func() {
+		var workbench *Workbench
+
+		BeforeEach(func() {
+			workbench = &Workbench{
+				Spec: WorkbenchSpec{
+					Apps: []WorkbenchApp{
+						{
+							Name: "wezterm",
+						},
+					},
+				},
+			}
+		})
+
+		It("reports a running app", func() {
+			one := int32(1)
+			job := batchv1.Job{}
+			job.Status.Active = 1
+			job.Status.Ready = &one
+
+			Expect(workbench.UpdateStatusFromJob(0, job, now, 0)).To(BeTrue())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusRunning))
+			Expect(workbench.Status.Apps[0].DesiredState).To(Equal(WorkbenchAppStateRunning))
+		})
+
+		It("reports a completed app when it finished its work", func() {
+			job := batchv1.Job{}
+			job.Status.Succeeded = 1
+
+			Expect(workbench.UpdateStatusFromJob(0, job, now, 0)).To(BeTrue())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusComplete))
+		})
+
+		It("remembers a completed app, until it is started again", func() {
+			job := batchv1.Job{}
+			job.Spec.Template.Spec.Containers = []corev1.Container{{Name: "wezterm", Image: "apps/wezterm:1.0"}}
+			job.Status.Succeeded = 1
+
+			Expect(workbench.IsAppCompleted(0, job)).To(BeFalse())
+
+			Expect(workbench.UpdateStatusFromJob(0, job, now, 0)).To(BeTrue())
+			Expect(workbench.Status.Apps[0].CompletionTime).To(HaveValue(Equal(now)))
+			Expect(workbench.Status.Apps[0].CompletedImage).To(Equal("apps/wezterm:1.0"))
+			Expect(workbench.IsAppCompleted(0, job)).To(BeTrue())
+
+			// Another image is another app.
+			upgraded := *job.DeepCopy()
+			upgraded.Spec.Template.Spec.Containers[0].Image = "apps/wezterm:2.0"
+			Expect(workbench.IsAppCompleted(0, upgraded)).To(BeFalse())
+
+			// So is a stopped, then started, one.
+			workbench.Spec.Apps[0].State = WorkbenchAppStateStopped
+			Expect(workbench.UpdateStatusFromSuspended(0, "")).To(BeTrue())
+			Expect(workbench.Status.Apps[0].CompletionTime).To(BeNil())
+			Expect(workbench.IsAppCompleted(0, job)).To(BeFalse())
+		})
+
+		It("reports a stopped app when the user stopped it", func() {
+			workbench.Spec.Apps[0].State = WorkbenchAppStateStopped
+
+			// A suspended job has no active, nor succeeded, pods.
+			tru := true
+			job := batchv1.Job{}
+			job.Spec.Suspend = &tru
+
+			Expect(workbench.UpdateStatusFromJob(0, job, now, 0)).To(BeTrue())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusStopped))
+			Expect(workbench.Status.Apps[0].DesiredState).To(Equal(WorkbenchAppStateStopped))
+
+			// The outcome does not depend on how the pod terminated.
+			job.Status.Succeeded = 1
+
+			Expect(workbench.UpdateStatusFromJob(0, job, now, 0)).To(BeFalse())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusStopped))
+		})
+
+		It("reports a failed app when it crashed", func() {
+			job := batchv1.Job{}
+			job.Status.Failed = 1
+
+			Expect(workbench.UpdateStatusFromJob(0, job, now, 0)).To(BeTrue())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusFailed))
+		})
+	})
+
+	Context("When the app pod flaps", func() {
+		const damping = 15 * time.Second
+
+		one := int32(1)
+
+		running := batchv1.Job{}
+		running.Status.Active = 1
+		running.Status.Ready = &one
+
+		progressing := batchv1.Job{}
+		progressing.Status.Active = 1
+
+		evicted := batchv1.Job{}
+		evicted.Status.Failed = 1
+
+		var workbench *Workbench
+
+		BeforeEach(func() {
+			workbench = &Workbench{
+				Spec: WorkbenchSpec{
+					Apps: []WorkbenchApp{
+						{
+							Name: "wezterm",
+						},
+					},
+				},
+			}
+		})
+
+		at := func(seconds int64) metav1.Time {
+			return metav1.NewTime(now.Add(time.Duration(seconds) * time.Second))
+		}
+
+		It("holds the quick transitions", func() {
+			// The first status is immediate.
+			updated, retryAfter := workbench.UpdateStatusFromJob(0, running, at(0), damping)
+			Expect(updated).To(BeTrue())
+			Expect(retryAfter).To(BeZero())
+
+			// Running -> Failed -> Progressing -> Running, within seconds.
+			published := []WorkbenchStatusAppStatus{}
+			for i, job := range []batchv1.Job{evicted, progressing, running} {
+				updated, _ = workbench.UpdateStatusFromJob(0, job, at(int64(2+i)), damping)
+				Expect(updated).To(BeFalse())
+
+				published = append(published, workbench.Status.Apps[0].Status)
+			}
+
+			// A held change tells when to look again.
+			_, retryAfter = workbench.UpdateStatusFromJob(0, evicted, at(5), damping)
+			Expect(retryAfter).To(Equal(10 * time.Second))
+
+			Expect(published).To(Equal([]WorkbenchStatusAppStatus{
+				WorkbenchStatusAppStatusRunning,
+				WorkbenchStatusAppStatusRunning,
+				WorkbenchStatusAppStatusRunning,
+			}))
+		})
+
+		It("publishes a lasting transition", func() {
+			workbench.UpdateStatusFromJob(0, running, at(0), damping)
+
+			updated, _ := workbench.UpdateStatusFromJob(0, progressing, at(5), damping)
+			Expect(updated).To(BeFalse())
+
+			updated, retryAfter := workbench.UpdateStatusFromJob(0, progressing, at(15), damping)
+			Expect(updated).To(BeTrue())
+			Expect(retryAfter).To(BeZero())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusProgressing))
+			Expect(workbench.Status.Apps[0].LastTransitionTime).To(HaveValue(Equal(at(15))))
+		})
+
+		It("publishes the terminal transitions immediately", func() {
+			workbench.UpdateStatusFromJob(0, running, at(0), damping)
+
+			failed := *evicted.DeepCopy()
+			failed.Status.Conditions = []batchv1.JobCondition{
+				{
+					Type:   batchv1.JobFailed,
+					Status: corev1.ConditionTrue,
+					Reason: "BackoffLimitExceeded",
+				},
+			}
+
+			updated, retryAfter := workbench.UpdateStatusFromJob(0, failed, at(1), damping)
+			Expect(updated).To(BeTrue())
+			Expect(retryAfter).To(BeZero())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusFailed))
+
+			completed := batchv1.Job{}
+			completed.Status.Succeeded = 1
+
+			updated, _ = workbench.UpdateStatusFromJob(0, completed, at(2), damping)
+			Expect(updated).To(BeTrue())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusComplete))
+		})
+	})
+
+	Context("When the app job retries", func() {
+		var workbench *Workbench
+
+		BeforeEach(func() {
+			workbench = &Workbench{
+				Spec: WorkbenchSpec{
+					Apps: []WorkbenchApp{
+						{
+							Name: "wezterm",
+						},
+					},
+				},
+			}
+		})
+
+		// The app pods are restarted in place, the job only counts its failures in the end.
+		job := func(restarts int32) (batchv1.Job, int32) {
+			limit := int32(3)
+
+			job := batchv1.Job{}
+			job.Spec.BackoffLimit = &limit
+			job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+			job.Status.Active = 1
+
+			return job, restarts
+		}
+
+		update := func(job batchv1.Job, restarts int32) WorkbenchStatusApp {
+			workbench.UpdateStatusFromRetries(0, job, restarts)
+			workbench.UpdateStatusFromJob(0, job, now, 0)
+
+			return workbench.Status.Apps[0]
+		}
+
+		It("counts the attempts until the backoff limit", func() {
+			app := update(job(0))
+			Expect(app.Status).To(Equal(WorkbenchStatusAppStatusProgressing))
+			Expect(app.Attempts).To(BeZero())
+			Expect(app.RemainingRetries).To(BeNil())
+
+			statuses := []WorkbenchStatusAppStatus{}
+			remaining := []int32{}
+			for restarts := range int32(3) {
+				app = update(job(restarts + 1))
+
+				statuses = append(statuses, app.Status)
+				remaining = append(remaining, *app.RemainingRetries)
+				Expect(app.Attempts).To(Equal(restarts + 1))
+			}
+
+			Expect(statuses).To(Equal([]WorkbenchStatusAppStatus{
+				WorkbenchStatusAppStatusRetrying,
+				WorkbenchStatusAppStatusRetrying,
+				WorkbenchStatusAppStatusRetrying,
+			}))
+			Expect(remaining).To(Equal([]int32{2, 1, 0}))
+
+			same, _ := job(0)
+			Expect(workbench.UpdateStatusFromRetries(0, same, 3)).To(BeFalse())
+
+			// Out of retries, its pods are gone.
+			failed, _ := job(0)
+			failed.Status.Active = 0
+			failed.Status.Failed = 1
+			failed.Status.Conditions = []batchv1.JobCondition{
+				{
+					Type:   batchv1.JobFailed,
+					Status: corev1.ConditionTrue,
+					Reason: batchv1.JobReasonBackoffLimitExceeded,
+				},
+			}
+
+			app = update(failed, 0)
+			Expect(app.Status).To(Equal(WorkbenchStatusAppStatusFailed))
+			Expect(app.Attempts).To(Equal(int32(3)))
+			Expect(app.RemainingRetries).To(HaveValue(BeZero()))
+		})
+
+		It("reports a restarted app running again", func() {
+			one := int32(1)
+
+			running, _ := job(0)
+			running.Status.Ready = &one
+
+			app := update(running, 1)
+			Expect(app.Status).To(Equal(WorkbenchStatusAppStatusRunning))
+			Expect(app.Attempts).To(Equal(int32(1)))
+			Expect(app.RemainingRetries).To(HaveValue(Equal(int32(2))))
+		})
+
+		It("defaults to the backoff limit of the jobs", func() {
+			replaced := batchv1.Job{}
+			replaced.Status.Active = 1
+			replaced.Status.Failed = 2
+
+			app := update(replaced, 0)
+			Expect(app.Status).To(Equal(WorkbenchStatusAppStatusRetrying))
+			Expect(app.Attempts).To(Equal(int32(2)))
+			Expect(app.RemainingRetries).To(HaveValue(Equal(int32(4))))
+
+			// A new job starts over.
+			app = update(job(0))
+			Expect(app.Status).To(Equal(WorkbenchStatusAppStatusProgressing))
+			Expect(app.Attempts).To(BeZero())
+			Expect(app.RemainingRetries).To(BeNil())
+		})
+	})
+
+	Context("When the app has a startup deadline", func() {
+		var workbench *Workbench
+
+		deadline := int32(60)
+		started := metav1.NewTime(now.Add(-time.Minute))
+
+		BeforeEach(func() {
+			workbench = &Workbench{
+				Spec: WorkbenchSpec{
+					Apps: []WorkbenchApp{
+						{
+							Name:                   "wezterm",
+							StartupDeadlineSeconds: &deadline,
+						},
+					},
+				},
+			}
+		})
+
+		job := func(ready int32) batchv1.Job {
+			job := batchv1.Job{}
+			job.Status.Active = 1
+			job.Status.Ready = &ready
+			job.Status.StartTime = &started
+
+			return job
+		}
+
+		It("waits until the deadline", func() {
+			before := metav1.NewTime(now.Add(-time.Second))
+
+			_, retryAfter := workbench.UpdateStatusFromJob(0, job(0), before, 0)
+			Expect(retryAfter).To(Equal(time.Second))
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusProgressing))
+			Expect(workbench.IsStartupDeadlineExceeded(0)).To(BeFalse())
+		})
+
+		It("fails the app not ready in time", func() {
+			updated, retryAfter := workbench.UpdateStatusFromJob(0, job(0), now, time.Hour)
+			Expect(updated).To(BeTrue())
+			Expect(retryAfter).To(BeZero())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusFailed))
+			Expect(workbench.Status.Apps[0].Message).To(Equal("StartupDeadlineExceeded: the app was not ready within 1m0s"))
+			Expect(workbench.IsStartupDeadlineExceeded(0)).To(BeTrue())
+
+			updated, _ = workbench.UpdateStatusFromJob(0, job(0), now, 0)
+			Expect(updated).To(BeFalse())
+
+			By("being killed")
+			killed := batchv1.Job{}
+			killed.Status.StartTime = &started
+			killed.Status.Conditions = []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: batchv1.JobReasonDeadlineExceeded},
+			}
+
+			updated, _ = workbench.UpdateStatusFromJob(0, killed, now, 0)
+			Expect(updated).To(BeFalse())
+			Expect(workbench.IsStartupDeadlineExceeded(0)).To(BeTrue())
+		})
+
+		It("does not fail an app that was ready once", func() {
+			early := metav1.NewTime(now.Add(-30 * time.Second))
+
+			workbench.UpdateStatusFromJob(0, job(1), early, 0)
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusRunning))
+			Expect(workbench.Status.Apps[0].ReadyTime).To(Equal(&early))
+
+			// Restarting, past the deadline.
+			workbench.UpdateStatusFromJob(0, job(0), now, 0)
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusProgressing))
+
+			By("being resumed")
+			resumed := job(0)
+			resumed.Status.StartTime = &now
+
+			later := metav1.NewTime(now.Add(2 * time.Minute))
+			workbench.UpdateStatusFromJob(0, resumed, later, 0)
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusFailed))
+		})
+	})
+
+	Context("When an app is never started", func() {
+		It("reports it stopped", func() {
+			workbench := &Workbench{
+				Spec: WorkbenchSpec{
+					Apps: []WorkbenchApp{
+						{
+							Name: "wezterm",
+						},
+						{
+							Name:  "kitty",
+							State: WorkbenchAppStateStopped,
+						},
+					},
+				},
+			}
+
+			Expect(workbench.UpdateStatusFromSuspended(1, "")).To(BeTrue())
+			Expect(workbench.Status.Apps).To(HaveLen(2))
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusUnknown))
+			Expect(workbench.Status.Apps[1].Status).To(Equal(WorkbenchStatusAppStatusStopped))
+			Expect(workbench.Status.Apps[1].DesiredState).To(Equal(WorkbenchAppStateStopped))
+			Expect(workbench.Status.Apps[1].Revision).To(Equal(-1))
+
+			Expect(workbench.UpdateStatusFromSuspended(1, "")).To(BeFalse())
+
+			Expect(workbench.UpdateStatusFromSuspended(1, "deleted instead")).To(BeTrue())
+			Expect(workbench.Status.Apps[1].Message).To(Equal("deleted instead"))
+		})
+	})
+
+	Context("When an app job is rejected", func() {
+		It("reports it failed with the message until the job exists", func() {
+			workbench := &Workbench{
+				Spec: WorkbenchSpec{
+					Apps: []WorkbenchApp{
+						{
+							Name: "wezterm",
+						},
+					},
+				},
+			}
+
+			Expect(workbench.UpdateStatusFromRejected(0, "exceeded quota", now)).To(BeTrue())
+			Expect(workbench.Status.Apps).To(HaveLen(1))
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusFailed))
+			Expect(workbench.Status.Apps[0].DesiredState).To(Equal(WorkbenchAppStateRunning))
+			Expect(workbench.Status.Apps[0].Message).To(Equal("exceeded quota"))
+			Expect(workbench.Status.Apps[0].LastTransitionTime).To(HaveValue(Equal(now)))
+
+			Expect(workbench.UpdateStatusFromRejected(0, "exceeded quota", now)).To(BeFalse())
+
+			// Once created, the job tells the status.
+			job := batchv1.Job{}
+			job.Status.Active = 1
+
+			updated, _ := workbench.UpdateStatusFromJob(0, job, now, 0)
+			Expect(updated).To(BeTrue())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(WorkbenchStatusAppStatusProgressing))
+			Expect(workbench.Status.Apps[0].Message).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("counting the apps",
+		func(statuses []WorkbenchStatusAppStatus, specApps, running int) {
+			workbench := &Workbench{}
+			for i := 0; i < specApps; i++ {
+				workbench.Spec.Apps = append(workbench.Spec.Apps, WorkbenchApp{Name: "app"})
+			}
+
+			for _, status := range statuses {
+				workbench.Status.Apps = append(workbench.Status.Apps, WorkbenchStatusApp{Status: status})
+			}
+
+			Expect(workbench.UpdateStatusAppCounts()).To(Equal(specApps > 0 || running > 0))
+			Expect(workbench.Status.AppCount).To(Equal(specApps))
+			Expect(workbench.Status.RunningAppCount).To(Equal(running))
+
+			Expect(workbench.UpdateStatusAppCounts()).To(BeFalse())
+		},
+		Entry("no apps", []WorkbenchStatusAppStatus{}, 0, 0),
+		Entry("all running", []WorkbenchStatusAppStatus{WorkbenchStatusAppStatusRunning, WorkbenchStatusAppStatusRunning}, 2, 2),
+		Entry("stopped, completed and failed are not running", []WorkbenchStatusAppStatus{
+			WorkbenchStatusAppStatusRunning,
+			WorkbenchStatusAppStatusStopped,
+			WorkbenchStatusAppStatusComplete,
+			WorkbenchStatusAppStatusFailed,
+		}, 4, 1),
+		Entry("progressing and unknown are not running", []WorkbenchStatusAppStatus{
+			WorkbenchStatusAppStatusProgressing,
+			WorkbenchStatusAppStatusUnknown,
+		}, 2, 0),
+		Entry("apps without a status yet", []WorkbenchStatusAppStatus{WorkbenchStatusAppStatusRunning}, 3, 1),
+		Entry("removed apps are not counted", []WorkbenchStatusAppStatus{
+			WorkbenchStatusAppStatusRunning,
+			WorkbenchStatusAppStatusRunning,
+		}, 1, 1),
+	)
+
+	Context("When updating the effective configuration", func() {
+		It("reports only the changes", func() {
+			workbench := &Workbench{}
+
+			effective := WorkbenchStatusEffective{
+				ServerImage: "xpra-server:latest",
+				Apps: []WorkbenchStatusEffectiveApp{
+					{
+						Name:  "wezterm",
+						Image: "wezterm:latest",
+					},
+				},
+			}
+
+			Expect(workbench.UpdateStatusEffective(effective)).To(BeTrue())
+			Expect(workbench.UpdateStatusEffective(*effective.DeepCopy())).To(BeFalse())
+
+			effective.Apps[0].Image = "wezterm:1.0.0"
+			Expect(workbench.UpdateStatusEffective(effective)).To(BeTrue())
+			Expect(workbench.Status.Effective.Apps[0].Image).To(Equal("wezterm:1.0.0"))
+		})
+	})
+
+	Context("When updating the last activity", func() {
+		It("only moves it forward", func() {
+			workbench := &Workbench{}
+
+			Expect(workbench.UpdateStatusFromActivity(metav1.Time{})).To(BeFalse())
+			Expect(workbench.UpdateStatusFromActivity(metav1.Unix(2000, 0))).To(BeTrue())
+			Expect(workbench.UpdateStatusFromActivity(metav1.Unix(1000, 0))).To(BeFalse())
+			Expect(workbench.UpdateStatusFromActivity(metav1.Unix(2000, 0))).To(BeFalse())
+			Expect(workbench.UpdateStatusFromActivity(metav1.Unix(3000, 0))).To(BeTrue())
+			Expect(workbench.Status.Server.LastActivity.Time).To(Equal(metav1.Unix(3000, 0).Time))
+		})
+	})
+
+	Context("When updating the health of the pod of an app", func() {
+		It("reports only the changes, and forgets it without a job", func() {
+			workbench := &Workbench{}
+			pod := &WorkbenchStatusAppPod{Name: "wezterm", Phase: corev1.PodPending}
+
+			Expect(workbench.UpdateStatusFromAppPod(0, pod)).To(BeFalse())
+
+			Expect(workbench.UpdateStatusFromRejected(0, "forbidden", metav1.Now())).To(BeTrue())
+			Expect(workbench.UpdateStatusFromAppPod(0, pod)).To(BeTrue())
+			Expect(workbench.UpdateStatusFromAppPod(0, pod.DeepCopy())).To(BeFalse())
+
+			Expect(workbench.UpdateStatusFromSuspended(0, "")).To(BeTrue())
+			Expect(workbench.Status.Apps[0].Pod).To(BeNil())
+		})
+	})
+})
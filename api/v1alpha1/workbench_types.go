@@ -1,8 +1,10 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -28,6 +30,18 @@ const (
 	WorkbenchAppStateKilled WorkbenchAppState = "Killed"
 )
 
+// WorkbenchGPU requests GPUs for a container.
+type WorkbenchGPU struct {
+	// Count is the number of GPUs.
+	// +kubebuilder:validation:Minimum:=1
+	Count int32 `json:"count"`
+
+	// ResourceName is the extended resource of the GPUs, e.g. amd.com/gpu.
+	// +optional
+	// +default:value="nvidia.com/gpu"
+	ResourceName corev1.ResourceName `json:"resourceName,omitempty"`
+}
+
 // WorkbenchServer defines the server configuration.
 type WorkbenchServer struct {
 	// Version defines the version to use.
@@ -35,7 +49,37 @@ type WorkbenchServer struct {
 	// +default:value="latest"
 	Version string `json:"version,omitempty"`
 
-	// TODO: add anything you'd like to configure. E.g. resources, Xpra options, auth, etc.
+	// PersistentSession keeps the Xpra session state on a volume, surviving the server restarts.
+	// +optional
+	PersistentSession bool `json:"persistentSession,omitempty"`
+
+	// Observers are the read-only Xpra servers shadowing the session, e.g. for an instructor.
+	//
+	// Each one listens on its own service port, observer-1 on 8081 and so on. They require
+	// the persistent session.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=3
+	Observers int32 `json:"observers,omitempty"`
+
+	// IdleTimeout is how long the server may go without activity before the workbench is
+	// suspended, e.g. "8h". Never when unset.
+	//
+	// It's resumed by the next spec change, or by the "chorus-tre.ch/wake" annotation.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// GPU requests GPUs for the Xpra server, to accelerate the rendering.
+	// +optional
+	GPU *WorkbenchGPU `json:"gpu,omitempty"`
+
+	// Resources of the Xpra server container, the operator defaults otherwise.
+	//
+	// The GPUs are added to the limits.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// TODO: add anything you'd like to configure. E.g. Xpra options, auth, etc.
 }
 
 // Image represents the configuration of a custom image for an app.
@@ -51,6 +95,30 @@ type Image struct {
 	Tag string `json:"tag,omitempty"`
 }
 
+// ShmMountPath is where the app container gets its shmSize volume.
+const ShmMountPath = "/dev/shm"
+
+// AppMount mounts a ConfigMap or a Secret of the workbench namespace into the app container,
+// e.g. a license file.
+type AppMount struct {
+	// ConfigMap is the name of the ConfigMap to mount, instead of a Secret.
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+	// Secret is the name of the Secret to mount, instead of a ConfigMap.
+	// +optional
+	Secret string `json:"secret,omitempty"`
+	// MountPath is where it's mounted in the app container, outside of the operator managed
+	// path, i.e. /dev/shm.
+	// +kubebuilder:validation:Pattern:="^/"
+	MountPath string `json:"mountPath"`
+	// SubPath mounts a single key, as a file, instead of all of them as a directory.
+	// +optional
+	SubPath string `json:"subPath,omitempty"`
+	// ReadOnly mounts it read-only.
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
 // WorkbenchApp defines one application running in the workbench.
 type WorkbenchApp struct {
 	// Name is the application name (likely its OCI image name as well)
@@ -84,6 +152,67 @@ type WorkbenchApp struct {
 	// +optional
 	ShmSize *resource.Quantity `json:"shmSize,omitempty"`
 
+	// SidecarContainers are companion processes started before the app and stopped after it.
+	//
+	// They are run as native sidecars (init containers that keep running), the completion
+	// of the app being driven by its main container only.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=array
+	// +kubebuilder:validation:items:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	SidecarContainers []corev1.Container `json:"sidecarContainers,omitempty"`
+
+	// GPU requests GPUs for the app, e.g. for CUDA.
+	// +optional
+	GPU *WorkbenchGPU `json:"gpu,omitempty"`
+
+	// Resources of the app container, those of its resource profile or the operator
+	// defaults otherwise.
+	//
+	// The GPUs are added to the limits.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// ResourceProfile names a size of resources configured on the operator, e.g. small.
+	// +optional
+	ResourceProfile string `json:"resourceProfile,omitempty"`
+
+	// NodeSelector places the app on the matching nodes, instead of the workbench one.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations allow the app on the tainted nodes, instead of the workbench ones.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity constrains the nodes of the app, instead of the workbench one.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Prewarm pulls the image on the nodes before the app is started, see the workbench one.
+	// +optional
+	Prewarm bool `json:"prewarm,omitempty"`
+
+	// StartupDeadlineSeconds is how long the app may take to be ready, from the start of its
+	// job, before it is failed with StartupDeadlineExceeded.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	StartupDeadlineSeconds *int32 `json:"startupDeadlineSeconds,omitempty"`
+
+	// JobTTLSecondsAfterFinished is how long the job of the app is kept once finished, the
+	// operator default otherwise. Zero deletes it right away.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	JobTTLSecondsAfterFinished *int32 `json:"jobTTLSecondsAfterFinished,omitempty"`
+
+	// ExtraMounts are the ConfigMaps and Secrets mounted into the app container, not its sidecars.
+	// +optional
+	ExtraMounts []AppMount `json:"extraMounts,omitempty"`
+
 	// TODO: add anything you'd like to configure. E.g. resources, (App data) volume, etc.
 }
 
@@ -103,13 +232,47 @@ type WorkbenchSpec struct {
 	// +optional
 	// +kubebuilder:validation:items:MinLength:=1
 	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// DisplayName is the name shown to the users, it can change unlike the resource name.
+	// +optional
+	// +kubebuilder:validation:MaxLength:=128
+	DisplayName string `json:"displayName,omitempty"`
+	// NodeSelector places the server and the apps on the matching nodes, e.g. a node pool.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations allow the server and the apps on the tainted nodes.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity constrains the nodes of the server and the apps.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// Prewarm pulls the images of all the apps on the nodes before they are started.
+	//
+	// A short lived job runs each image with a no-op command, so the stopped apps start
+	// without the minutes of pulling the large images.
+	// +optional
+	Prewarm bool `json:"prewarm,omitempty"`
+	// TargetNamespace is where the server and the apps run, instead of the namespace of the
+	// workbench. The target namespace must allow it, see the README. It cannot change.
+	// +optional
+	// +kubebuilder:validation:MaxLength:=63
+	// +kubebuilder:validation:Pattern:="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// Suspended scales the server to zero and suspends all the apps, e.g. overnight.
+	//
+	// The storage and the service are kept, so resuming is fast. Once resumed, the apps
+	// whose state is Running start again.
+	// +optional
+	Suspended bool `json:"suspended,omitempty"`
 }
 
 // WorkbenchStatusAppStatus are the effective status of a launched app.
 //
 // It matches the Job Status,
 // See https://kubernetes.io/docs/reference/kubernetes-api/workload-resources/job-v1/#JobStatus
-// +kubebuilder:validation:Enum=Unknown;Running;Complete;Progressing;Failed
+// +kubebuilder:validation:Enum=Unknown;Running;Complete;Progressing;Retrying;Failed;Stopped
 type WorkbenchStatusAppStatus string
 
 const (
@@ -125,8 +288,14 @@ const (
 	// WorkbenchStatusAppStatusProgressing describes a pending app.
 	WorkbenchStatusAppStatusProgressing WorkbenchStatusAppStatus = "Progressing"
 
+	// WorkbenchStatusAppStatusRetrying describes an app that failed, and is being restarted.
+	WorkbenchStatusAppStatusRetrying WorkbenchStatusAppStatus = "Retrying"
+
 	// WorkbenchStatusAppStatusFailed describes a failed app.
 	WorkbenchStatusAppStatusFailed WorkbenchStatusAppStatus = "Failed"
+
+	// WorkbenchStatusAppStatusStopped describes an app stopped on purpose.
+	WorkbenchStatusAppStatusStopped WorkbenchStatusAppStatus = "Stopped"
 )
 
 // WorkbenchStatusServerStatus is identical to the App status.
@@ -144,6 +313,30 @@ const (
 	WorkbenchStatusServerStatusFailed WorkbenchStatusServerStatus = "Failed"
 )
 
+// WorkbenchPhase summarizes the lifecycle of the workbench, see ComputePhase.
+// +kubebuilder:validation:Enum=Creating;Ready;Degraded;Hibernated;Deleting;Failed
+type WorkbenchPhase string
+
+const (
+	// WorkbenchPhaseCreating describes a workbench whose server or apps are not running yet.
+	WorkbenchPhaseCreating WorkbenchPhase = "Creating"
+
+	// WorkbenchPhaseReady describes a workbench whose server and apps are running, or complete.
+	WorkbenchPhaseReady WorkbenchPhase = "Ready"
+
+	// WorkbenchPhaseDegraded describes a workbench with a failed app.
+	WorkbenchPhaseDegraded WorkbenchPhase = "Degraded"
+
+	// WorkbenchPhaseHibernated describes a workbench whose apps are all stopped.
+	WorkbenchPhaseHibernated WorkbenchPhase = "Hibernated"
+
+	// WorkbenchPhaseDeleting describes a workbench being deleted.
+	WorkbenchPhaseDeleting WorkbenchPhase = "Deleting"
+
+	// WorkbenchPhaseFailed describes a workbench whose server failed, or that cannot be created.
+	WorkbenchPhaseFailed WorkbenchPhase = "Failed"
+)
+
 // WorkbenchStatusServer represents the server status.
 type WorkbenchStatusServer struct {
 	// Revision is the values of the "deployment.kubernetes.io/revision" metadata.
@@ -151,6 +344,36 @@ type WorkbenchStatusServer struct {
 
 	// Status informs about the real state of the app.
 	Status WorkbenchStatusServerStatus `json:"status"`
+
+	// Restarts is how many times the Xpra server container restarted, in its current pods.
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+
+	// Message tells how the Xpra server container last terminated, e.g. while crash looping.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Observers are the health of the read-only observer servers, in order.
+	// +optional
+	Observers []WorkbenchStatusObserver `json:"observers,omitempty"`
+
+	// LastActivity is when the server was last seen active, either as told by the server
+	// through the "chorus-tre.ch/last-activity" pod annotation, or when it became ready.
+	// +optional
+	LastActivity *metav1.Time `json:"lastActivity,omitempty"`
+}
+
+// WorkbenchStatusObserver informs about the state of an observer server.
+type WorkbenchStatusObserver struct {
+	// Name is the observer container, and its service port.
+	Name string `json:"name"`
+
+	// Ready tells whether the observer accepts the clients.
+	Ready bool `json:"ready"`
+
+	// Restarts is how many times the observer container restarted, in its current pods.
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
 }
 
 // WorkbenchStatusappStatus informs about the state of the apps.
@@ -160,18 +383,163 @@ type WorkbenchStatusApp struct {
 
 	// Status informs about the real state of the app.
 	Status WorkbenchStatusAppStatus `json:"status"`
+
+	// DesiredState is the state that was requested when the status was computed.
+	// +optional
+	DesiredState WorkbenchAppState `json:"desiredState,omitempty"`
+
+	// LastTransitionTime is when the status last changed.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Message explains the status, e.g. a rejection.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Attempts is how many times the app failed, it is restarted until its backoff limit.
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// RemainingRetries is how many more times the app is restarted, out of its backoff limit.
+	// +optional
+	RemainingRetries *int32 `json:"remainingRetries,omitempty"`
+
+	// CompletionTime is when the app completed, it is not started again once its job is reaped.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// CompletedImage is the image of the completed app, another image starts it again.
+	// +optional
+	CompletedImage string `json:"completedImage,omitempty"`
+
+	// ReadyTime is when the app was first ready in its job, which ended its startup.
+	// +optional
+	ReadyTime *metav1.Time `json:"readyTime,omitempty"`
+
+	// Pod is the health of the newest pod of the job, e.g. to tell why the app is not starting.
+	// +optional
+	Pod *WorkbenchStatusAppPod `json:"pod,omitempty"`
+}
+
+// WorkbenchStatusAppPod informs about the newest pod of the job of an app.
+type WorkbenchStatusAppPod struct {
+	// Name is the pod name.
+	Name string `json:"name"`
+
+	// Phase is the phase of the pod.
+	// +optional
+	Phase corev1.PodPhase `json:"phase,omitempty"`
+
+	// Containers are the init containers, i.e. the sidecars, then the app containers.
+	// +optional
+	Containers []WorkbenchStatusAppContainer `json:"containers,omitempty"`
+}
+
+// WorkbenchStatusAppContainer informs about a container of the pod of an app.
+type WorkbenchStatusAppContainer struct {
+	// Name is the container name.
+	Name string `json:"name"`
+
+	// Init tells an init container apart.
+	// +optional
+	Init bool `json:"init,omitempty"`
+
+	// Ready tells whether the container passes its readiness probe.
+	Ready bool `json:"ready"`
+
+	// Restarts is how many times the container restarted, in this pod.
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+
+	// State tells how the container is, e.g. "waiting, ImagePullBackOff" or "terminated, OOMKilled".
+	State string `json:"state"`
+}
+
+// WorkbenchStatusEffectiveApp is the configuration actually used for an app.
+type WorkbenchStatusEffectiveApp struct {
+	// Name is the application name.
+	Name string `json:"name"`
+
+	// Image is the resolved OCI image of the app.
+	Image string `json:"image"`
+
+	// Resources are the resolved resources of the app container, GPUs included.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// WorkbenchStatusEffective is the configuration actually used, once all the defaults are applied.
+//
+// It's strictly informational.
+type WorkbenchStatusEffective struct {
+	// ServerImage is the resolved OCI image of the Xpra server.
+	ServerImage string `json:"serverImage,omitempty"`
+
+	// SidecarImage is the resolved OCI image of the X11 socket sidecar.
+	SidecarImage string `json:"sidecarImage,omitempty"`
+
+	// ServerResources are the resolved resources of the Xpra server container, GPUs included.
+	// +optional
+	ServerResources corev1.ResourceRequirements `json:"serverResources,omitempty"`
+
+	// Apps are the resolved apps, in the same order as the spec, up to 50 of them.
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	Apps []WorkbenchStatusEffectiveApp `json:"apps,omitempty"`
+
+	// OmittedApps counts the apps left out of Apps, bounding the size of the status.
+	// +optional
+	OmittedApps int32 `json:"omittedApps,omitempty"`
 }
 
 // WorkbenchStatus defines the observed state of Workbench
 type WorkbenchStatus struct {
+	// Phase summarizes the rest of the status.
+	// +optional
+	Phase WorkbenchPhase `json:"phase,omitempty"`
+
 	Server WorkbenchStatusServer `json:"server"`
 	Apps   []WorkbenchStatusApp  `json:"apps,omitempty"`
+
+	// AppCount is the number of apps in the spec, whatever their state.
+	// +optional
+	AppCount int `json:"appCount"`
+
+	// RunningAppCount is the number of apps actively running.
+	// +optional
+	RunningAppCount int `json:"runningAppCount"`
+
+	// Effective is the configuration actually used by the operator.
+	// +optional
+	Effective *WorkbenchStatusEffective `json:"effective,omitempty"`
+
+	// Conditions are the observations of the workbench state.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// SessionSecretName is the secret holding the token of the Xpra session.
+	// +optional
+	SessionSecretName string `json:"sessionSecretName,omitempty"`
+
+	// ServiceUID tells when the service was recreated.
+	// +optional
+	ServiceUID types.UID `json:"serviceUID,omitempty"`
+
+	// FirstDeletionAttempt is when the removal of the children started.
+	// +optional
+	FirstDeletionAttempt *metav1.Time `json:"firstDeletionAttempt,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=wb,categories=chorus
+// +kubebuilder:printcolumn:name="Display Name",type=string,JSONPath=`.spec.displayName`
 // +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.server.version`
 // +kubebuilder:printcolumn:name="Apps",type=string,JSONPath=`.spec.apps[*].name`
+// +kubebuilder:printcolumn:name="Running",type=integer,JSONPath=`.status.runningAppCount`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Workbench is the Schema for the workbenches API
@@ -0,0 +1,55 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkbenchSummaryName is the name of the single summary of a namespace.
+const WorkbenchSummaryName = "workbenches"
+
+// WorkbenchSummaryStatus is the aggregated state of the workbenches of a namespace.
+type WorkbenchSummaryStatus struct {
+	// Workbenches is the number of workbenches in the namespace.
+	Workbenches int `json:"workbenches"`
+
+	// ServerStatuses counts the workbenches per server status.
+	// +optional
+	ServerStatuses map[string]int `json:"serverStatuses,omitempty"`
+
+	// RunningApps is the total number of apps actively running.
+	RunningApps int `json:"runningApps"`
+
+	// LastUpdateTime is when the counts last changed.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=ws,categories=chorus
+// +kubebuilder:printcolumn:name="Workbenches",type=integer,JSONPath=`.status.workbenches`
+// +kubebuilder:printcolumn:name="Running Apps",type=integer,JSONPath=`.status.runningApps`
+// +kubebuilder:printcolumn:name="Updated",type=date,JSONPath=`.status.lastUpdateTime`
+
+// WorkbenchSummary is the overview of the workbenches of a namespace.
+//
+// It's maintained by the operator, so reading it does not require to list the workbenches.
+type WorkbenchSummary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status WorkbenchSummaryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkbenchSummaryList contains a list of WorkbenchSummary
+type WorkbenchSummaryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkbenchSummary `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkbenchSummary{}, &WorkbenchSummaryList{})
+}
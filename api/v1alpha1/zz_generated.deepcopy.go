@@ -5,9 +5,26 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppMount) DeepCopyInto(out *AppMount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppMount.
+func (in *AppMount) DeepCopy() *AppMount {
+	if in == nil {
+		return nil
+	}
+	out := new(AppMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Image) DeepCopyInto(out *Image) {
 	*out = *in
@@ -63,6 +80,57 @@ func (in *WorkbenchApp) DeepCopyInto(out *WorkbenchApp) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.SidecarContainers != nil {
+		in, out := &in.SidecarContainers, &out.SidecarContainers
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GPU != nil {
+		in, out := &in.GPU, &out.GPU
+		*out = new(WorkbenchGPU)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StartupDeadlineSeconds != nil {
+		in, out := &in.StartupDeadlineSeconds, &out.StartupDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.JobTTLSecondsAfterFinished != nil {
+		in, out := &in.JobTTLSecondsAfterFinished, &out.JobTTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ExtraMounts != nil {
+		in, out := &in.ExtraMounts, &out.ExtraMounts
+		*out = make([]AppMount, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchApp.
@@ -75,6 +143,21 @@ func (in *WorkbenchApp) DeepCopy() *WorkbenchApp {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchGPU) DeepCopyInto(out *WorkbenchGPU) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchGPU.
+func (in *WorkbenchGPU) DeepCopy() *WorkbenchGPU {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchGPU)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkbenchList) DeepCopyInto(out *WorkbenchList) {
 	*out = *in
@@ -110,6 +193,21 @@ func (in *WorkbenchList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkbenchServer) DeepCopyInto(out *WorkbenchServer) {
 	*out = *in
+	if in.GPU != nil {
+		in, out := &in.GPU, &out.GPU
+		*out = new(WorkbenchGPU)
+		**out = **in
+	}
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchServer.
@@ -125,7 +223,7 @@ func (in *WorkbenchServer) DeepCopy() *WorkbenchServer {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkbenchSpec) DeepCopyInto(out *WorkbenchSpec) {
 	*out = *in
-	out.Server = in.Server
+	in.Server.DeepCopyInto(&out.Server)
 	if in.Apps != nil {
 		in, out := &in.Apps, &out.Apps
 		*out = make([]WorkbenchApp, len(*in))
@@ -138,6 +236,25 @@ func (in *WorkbenchSpec) DeepCopyInto(out *WorkbenchSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchSpec.
@@ -153,11 +270,29 @@ func (in *WorkbenchSpec) DeepCopy() *WorkbenchSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkbenchStatus) DeepCopyInto(out *WorkbenchStatus) {
 	*out = *in
-	out.Server = in.Server
+	in.Server.DeepCopyInto(&out.Server)
 	if in.Apps != nil {
 		in, out := &in.Apps, &out.Apps
 		*out = make([]WorkbenchStatusApp, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Effective != nil {
+		in, out := &in.Effective, &out.Effective
+		*out = new(WorkbenchStatusEffective)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FirstDeletionAttempt != nil {
+		in, out := &in.FirstDeletionAttempt, &out.FirstDeletionAttempt
+		*out = (*in).DeepCopy()
 	}
 }
 
@@ -174,6 +309,28 @@ func (in *WorkbenchStatus) DeepCopy() *WorkbenchStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkbenchStatusApp) DeepCopyInto(out *WorkbenchStatusApp) {
 	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RemainingRetries != nil {
+		in, out := &in.RemainingRetries, &out.RemainingRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ReadyTime != nil {
+		in, out := &in.ReadyTime, &out.ReadyTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Pod != nil {
+		in, out := &in.Pod, &out.Pod
+		*out = new(WorkbenchStatusAppPod)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchStatusApp.
@@ -186,9 +343,92 @@ func (in *WorkbenchStatusApp) DeepCopy() *WorkbenchStatusApp {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchStatusAppContainer) DeepCopyInto(out *WorkbenchStatusAppContainer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchStatusAppContainer.
+func (in *WorkbenchStatusAppContainer) DeepCopy() *WorkbenchStatusAppContainer {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchStatusAppContainer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchStatusAppPod) DeepCopyInto(out *WorkbenchStatusAppPod) {
+	*out = *in
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]WorkbenchStatusAppContainer, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchStatusAppPod.
+func (in *WorkbenchStatusAppPod) DeepCopy() *WorkbenchStatusAppPod {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchStatusAppPod)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchStatusEffective) DeepCopyInto(out *WorkbenchStatusEffective) {
+	*out = *in
+	in.ServerResources.DeepCopyInto(&out.ServerResources)
+	if in.Apps != nil {
+		in, out := &in.Apps, &out.Apps
+		*out = make([]WorkbenchStatusEffectiveApp, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchStatusEffective.
+func (in *WorkbenchStatusEffective) DeepCopy() *WorkbenchStatusEffective {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchStatusEffective)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchStatusEffectiveApp) DeepCopyInto(out *WorkbenchStatusEffectiveApp) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchStatusEffectiveApp.
+func (in *WorkbenchStatusEffectiveApp) DeepCopy() *WorkbenchStatusEffectiveApp {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchStatusEffectiveApp)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkbenchStatusServer) DeepCopyInto(out *WorkbenchStatusServer) {
 	*out = *in
+	if in.Observers != nil {
+		in, out := &in.Observers, &out.Observers
+		*out = make([]WorkbenchStatusObserver, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastActivity != nil {
+		in, out := &in.LastActivity, &out.LastActivity
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchStatusServer.
@@ -200,3 +440,102 @@ func (in *WorkbenchStatusServer) DeepCopy() *WorkbenchStatusServer {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchStatusObserver) DeepCopyInto(out *WorkbenchStatusObserver) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchStatusObserver.
+func (in *WorkbenchStatusObserver) DeepCopy() *WorkbenchStatusObserver {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchStatusObserver)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchSummary) DeepCopyInto(out *WorkbenchSummary) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchSummary.
+func (in *WorkbenchSummary) DeepCopy() *WorkbenchSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WorkbenchSummary) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchSummaryList) DeepCopyInto(out *WorkbenchSummaryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WorkbenchSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchSummaryList.
+func (in *WorkbenchSummaryList) DeepCopy() *WorkbenchSummaryList {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchSummaryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WorkbenchSummaryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkbenchSummaryStatus) DeepCopyInto(out *WorkbenchSummaryStatus) {
+	*out = *in
+	if in.ServerStatuses != nil {
+		in, out := &in.ServerStatuses, &out.ServerStatuses
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkbenchSummaryStatus.
+func (in *WorkbenchSummaryStatus) DeepCopy() *WorkbenchSummaryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkbenchSummaryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -3,15 +3,22 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -20,6 +27,7 @@ import (
 
 	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
 	"github.com/CHORUS-TRE/workbench-operator/internal/controller"
+	webhookdefaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -45,6 +53,37 @@ func main() {
 	var appsRepository string
 	var xpraServerImage string
 	var socatImage string
+	var propagatedLabelKeys string
+	var statusDamping time.Duration
+	var appJobTTLAfterFinished time.Duration
+	var maxAppsPerReconcile int
+	var appsReconcileBudget time.Duration
+	var disableSessionAuth bool
+	var disableJobDryRun bool
+	var killOnStartupDeadline bool
+	var convergenceMetrics bool
+	var summaryDebounce time.Duration
+	var recreateOnSelectorChange bool
+	var restartAppsOnServiceRecreation bool
+	var finalizationWarningAfter time.Duration
+	var enableWebhooks bool
+	var maxShmSize string
+	var serverCPURequest string
+	var serverMemoryRequest string
+	var serverMemoryLimit string
+	var appCPURequest string
+	var appMemoryRequest string
+	var appEphemeralStorageRequest string
+	var appCPULimit string
+	var appMemoryLimit string
+	var appEphemeralStorageLimit string
+	var resourceProfiles string
+	var gpuNodeSelector string
+	var gpuTolerationKeys string
+	var replicatePullSecret string
+	var orphanSweep bool
+	var orphanSweepDryRun bool
+	var syntheticProbe controller.SyntheticProbeConfig
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to. "+
 		"Use the port :8080. If not set, it will be 0 in order to disable the metrics server")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -59,6 +98,41 @@ func main() {
 	flag.StringVar(&appsRepository, "apps-repository", "apps", "The repository holding the apps")
 	flag.StringVar(&xpraServerImage, "xpra-server-image", "", "Xpra server OCI image name (version is part of the CRD)")
 	flag.StringVar(&socatImage, "socat-image", "", "socat OCI image (please specify the version)")
+	flag.StringVar(&propagatedLabelKeys, "propagated-label-keys", "", "Comma-separated workbench label keys copied onto all its children")
+	flag.BoolVar(&disableSessionAuth, "disable-xpra-session-auth", false, "Do not protect the Xpra sessions with a per-workbench token")
+	flag.BoolVar(&disableJobDryRun, "disable-job-dry-run", false, "Create the app jobs without a dry run first (the rejections then fail the whole reconcile)")
+	flag.BoolVar(&killOnStartupDeadline, "kill-on-startup-deadline", false, "Stop the jobs of the apps not ready within their startupDeadlineSeconds, instead of only failing them")
+	flag.BoolVar(&convergenceMetrics, "convergence-metrics", false, "Export the time the spec changes take to converge, as the workbench_convergence_seconds histogram")
+	flag.BoolVar(&recreateOnSelectorChange, "recreate-on-selector-change", false, "Recreate the server deployments whose selector is outdated (briefly stopping the server)")
+	flag.BoolVar(&restartAppsOnServiceRecreation, "restart-apps-on-service-recreation", false, "Restart the app pods of a workbench whose service was recreated, so they reconnect to it")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the validating webhook of the Workbenches (requires the webhook certificates)")
+	flag.StringVar(&replicatePullSecret, "replicate-pull-secret", "", "The namespace/name of a pull secret copied into, and used by, all the workbench namespaces")
+	flag.StringVar(&maxShmSize, "max-shm-size", "", "The largest /dev/shm size an app may request, e.g. 8Gi (unbounded if empty)")
+	flag.StringVar(&serverCPURequest, "server-cpu-request", "100m", "The CPU request of the Xpra servers not telling their resources (none if empty)")
+	flag.StringVar(&serverMemoryRequest, "server-memory-request", "256Mi", "The memory request of the Xpra servers not telling their resources (none if empty)")
+	flag.StringVar(&serverMemoryLimit, "server-memory-limit", "", "The memory limit of the Xpra servers not telling their resources (none if empty)")
+	flag.StringVar(&appCPURequest, "app-cpu-request", "", "The CPU request of the apps not telling their resources (none if empty)")
+	flag.StringVar(&appMemoryRequest, "app-memory-request", "", "The memory request of the apps not telling their resources (none if empty)")
+	flag.StringVar(&appEphemeralStorageRequest, "app-ephemeral-storage-request", "", "The ephemeral storage request of the apps not telling their resources (none if empty)")
+	flag.StringVar(&appCPULimit, "app-cpu-limit", "", "The CPU limit of the apps not telling their resources (none if empty)")
+	flag.StringVar(&appMemoryLimit, "app-memory-limit", "", "The memory limit of the apps not telling their resources (none if empty)")
+	flag.StringVar(&appEphemeralStorageLimit, "app-ephemeral-storage-limit", "", "The ephemeral storage limit of the apps not telling their resources (none if empty)")
+	flag.StringVar(&resourceProfiles, "resource-profiles", "", "Semicolon-separated name=cpu,memory resource profiles the apps may name, e.g. small=1,2Gi;large=8,32Gi (none if empty)")
+	flag.StringVar(&gpuNodeSelector, "gpu-node-selector", "", "Comma-separated key=value node labels of the GPU nodes, given to the pods requesting GPUs")
+	flag.StringVar(&gpuTolerationKeys, "gpu-toleration-keys", "", "Comma-separated taint keys of the GPU nodes, tolerated by the pods requesting GPUs")
+	flag.DurationVar(&finalizationWarningAfter, "finalization-warning-after", 5*time.Minute, "The time the deletion of a workbench may take before a warning event")
+	flag.DurationVar(&summaryDebounce, "summary-debounce", 5*time.Second, "The time the workbench events are coalesced before updating the namespace summary")
+	flag.IntVar(&maxAppsPerReconcile, "max-apps-per-reconcile", 0, "The number of apps handled by a single reconcile, the others continue right after (unbounded if zero)")
+	flag.DurationVar(&appsReconcileBudget, "apps-reconcile-budget", 0, "The time a single reconcile may spend on the apps, the others continue right after (unbounded if zero)")
+	flag.DurationVar(&appJobTTLAfterFinished, "app-job-ttl-after-finished", 24*time.Hour, "The time the finished jobs of the apps not telling theirs are kept, with their pods and logs")
+	flag.DurationVar(&statusDamping, "status-damping", 15*time.Second, "The minimum time an app status is held before a non-terminal change")
+	flag.BoolVar(&orphanSweep, "orphan-sweep", false, "Delete, at startup, the children left behind by the workbenches deleted while the operator was down")
+	flag.BoolVar(&orphanSweepDryRun, "orphan-sweep-dry-run", false, "Only report the orphaned children found by the startup sweep")
+	flag.BoolVar(&syntheticProbe.Enabled, "synthetic-probe", false, "Periodically create a throw-away workbench to probe the cluster")
+	flag.StringVar(&syntheticProbe.Namespace, "synthetic-probe-namespace", "default", "The namespace of the synthetic workbenches")
+	flag.DurationVar(&syntheticProbe.Interval, "synthetic-probe-interval", 15*time.Minute, "The time between two synthetic probes")
+	flag.DurationVar(&syntheticProbe.Timeout, "synthetic-probe-timeout", 5*time.Minute, "The time a synthetic workbench has to become ready")
+	flag.StringVar(&syntheticProbe.App, "synthetic-probe-app", "", "The app started by the synthetic workbench (none if empty)")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -115,20 +189,179 @@ func main() {
 		os.Exit(1)
 	}
 
+	config := controller.Config{
+		Images: controller.ImagesConfig{
+			Registry:       registry,
+			AppsRepository: appsRepository,
+		},
+		Server: controller.ServerConfig{
+			XpraServerImage:          xpraServerImage,
+			SocatImage:               socatImage,
+			RecreateOnSelectorChange: recreateOnSelectorChange,
+			DisableSessionAuth:       disableSessionAuth,
+		},
+		Apps: controller.AppConfig{
+			StatusDamping:              statusDamping,
+			MaxPerReconcile:            maxAppsPerReconcile,
+			ReconcileBudget:            appsReconcileBudget,
+			RestartOnServiceRecreation: restartAppsOnServiceRecreation,
+			DisableJobDryRun:           disableJobDryRun,
+			KillOnStartupDeadline:      killOnStartupDeadline,
+			JobTTLAfterFinished:        appJobTTLAfterFinished,
+		},
+		Finalization: controller.FinalizationConfig{
+			WarningAfter: finalizationWarningAfter,
+		},
+		Metrics: controller.MetricsConfig{
+			Convergence: convergenceMetrics,
+		},
+		OrphanSweep: controller.OrphanSweepConfig{
+			Enabled: orphanSweep,
+			DryRun:  orphanSweepDryRun,
+		},
+		SyntheticProbe: syntheticProbe,
+	}
+
+	nativeSidecars, err := controller.SupportsNativeSidecars(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to get the server version")
+		os.Exit(1)
+	}
+
+	if !nativeSidecars {
+		setupLog.Info("native sidecars are not supported, running the app sidecars as plain containers")
+		config.Apps.DisableNativeSidecars = true
+	}
+
+	if propagatedLabelKeys != "" {
+		for _, key := range strings.Split(propagatedLabelKeys, ",") {
+			config.Labels.PropagatedKeys = append(config.Labels.PropagatedKeys, strings.TrimSpace(key))
+		}
+	}
+
+	if gpuNodeSelector != "" {
+		config.GPU.NodeSelector = map[string]string{}
+
+		for _, pair := range strings.Split(gpuNodeSelector, ",") {
+			key, value, found := strings.Cut(pair, "=")
+			if !found || key == "" {
+				setupLog.Error(fmt.Errorf("%q is not a key=value label", pair), "invalid gpu node selector")
+				os.Exit(1)
+			}
+
+			config.GPU.NodeSelector[key] = value
+		}
+	}
+
+	if gpuTolerationKeys != "" {
+		for _, key := range strings.Split(gpuTolerationKeys, ",") {
+			config.GPU.Tolerations = append(config.GPU.Tolerations, corev1.Toleration{
+				Key:      key,
+				Operator: corev1.TolerationOpExists,
+			})
+		}
+	}
+
+	if replicatePullSecret != "" {
+		namespace, name, _ := strings.Cut(replicatePullSecret, "/")
+		config.Images.ReplicatePullSecret = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+
+	if maxShmSize != "" {
+		config.Apps.MaxShmSize, err = resource.ParseQuantity(maxShmSize)
+		if err != nil {
+			setupLog.Error(err, "invalid max shm size")
+			os.Exit(1)
+		}
+	}
+
+	for _, quantity := range []struct {
+		flag  string
+		value string
+		list  *corev1.ResourceList
+		name  corev1.ResourceName
+	}{
+		{"server-cpu-request", serverCPURequest, &config.Server.Resources.Requests, corev1.ResourceCPU},
+		{"server-memory-request", serverMemoryRequest, &config.Server.Resources.Requests, corev1.ResourceMemory},
+		{"server-memory-limit", serverMemoryLimit, &config.Server.Resources.Limits, corev1.ResourceMemory},
+		{"app-cpu-request", appCPURequest, &config.Apps.Resources.Requests, corev1.ResourceCPU},
+		{"app-memory-request", appMemoryRequest, &config.Apps.Resources.Requests, corev1.ResourceMemory},
+		{"app-ephemeral-storage-request", appEphemeralStorageRequest, &config.Apps.Resources.Requests, corev1.ResourceEphemeralStorage},
+		{"app-cpu-limit", appCPULimit, &config.Apps.Resources.Limits, corev1.ResourceCPU},
+		{"app-memory-limit", appMemoryLimit, &config.Apps.Resources.Limits, corev1.ResourceMemory},
+		{"app-ephemeral-storage-limit", appEphemeralStorageLimit, &config.Apps.Resources.Limits, corev1.ResourceEphemeralStorage},
+	} {
+		if err := controller.SetQuantity(quantity.list, quantity.name, quantity.value); err != nil {
+			setupLog.Error(err, "invalid resources", "flag", quantity.flag)
+			os.Exit(1)
+		}
+	}
+
+	if resourceProfiles != "" {
+		config.Apps.ResourceProfiles, err = controller.ParseResourceProfiles(resourceProfiles)
+		if err != nil {
+			setupLog.Error(err, "invalid resource profiles")
+			os.Exit(1)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		setupLog.Error(err, "invalid configuration")
+		os.Exit(1)
+	}
+
+	if !config.Images.HasAppsRegistry() {
+		setupLog.Info("no registry nor apps repository, the apps without an image will fail; set --registry or --apps-repository")
+	}
+
 	if err = (&controller.WorkbenchReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("workbench-controller"),
-		Config: controller.Config{
-			Registry:        registry,
-			AppsRepository:  appsRepository,
-			SocatImage:      socatImage,
-			XpraServerImage: xpraServerImage,
-		},
+		Config:   config,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Workbench")
 		os.Exit(1)
 	}
+
+	if err = (&controller.WorkbenchSummaryReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Debounce: summaryDebounce,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WorkbenchSummary")
+		os.Exit(1)
+	}
+
+	if enableWebhooks {
+		if err = webhookdefaultv1alpha1.SetupWorkbenchWebhookWithManager(mgr, config.Apps.MaxShmSize, config.Images.HasAppsRegistry(), config.Apps.ResourceProfileNames()); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Workbench")
+			os.Exit(1)
+		}
+	}
+
+	if config.OrphanSweep.Enabled {
+		if err := mgr.Add(&controller.OrphanSweeper{
+			Client: mgr.GetClient(),
+			Reader: mgr.GetAPIReader(),
+			DryRun: config.OrphanSweep.DryRun,
+		}); err != nil {
+			setupLog.Error(err, "unable to add the orphan sweep")
+			os.Exit(1)
+		}
+	}
+
+	if config.SyntheticProbe.Enabled {
+		if err := mgr.Add(&controller.SyntheticProbe{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("synthetic-probe"),
+			Config:   config.SyntheticProbe,
+			Clock:    clock.RealClock{},
+		}); err != nil {
+			setupLog.Error(err, "unable to add the synthetic probe")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -139,6 +372,16 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("cache-sync", controller.CacheSyncChecker(mgr.GetCache())); err != nil {
+		setupLog.Error(err, "unable to set up cache sync check")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// jobFailure tells why the job failed, as told by its Failed condition.
+func jobFailure(job batchv1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type != batchv1.JobFailed || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		if condition.Message != "" {
+			return condition.Reason + ": " + condition.Message
+		}
+
+		return condition.Reason
+	}
+
+	return ""
+}
+
+// reportAppTransition emits the end of an app, only when its status just changed to it.
+func (r *WorkbenchReconciler) reportAppTransition(workbench *defaultv1alpha1.Workbench, app defaultv1alpha1.WorkbenchApp, before defaultv1alpha1.WorkbenchStatusAppStatus, status defaultv1alpha1.WorkbenchStatusApp, job batchv1.Job) {
+	if before == status.Status {
+		return
+	}
+
+	switch status.Status {
+	case defaultv1alpha1.WorkbenchStatusAppStatusComplete:
+		emitEvent(
+			r.Recorder,
+			workbench,
+			corev1.EventTypeNormal,
+			eventReasonAppCompleted,
+			"The app %q completed",
+			app.Name,
+		)
+	case defaultv1alpha1.WorkbenchStatusAppStatusFailed:
+		// The status message is more telling, e.g. for the startup deadline.
+		reason := status.Message
+		if reason == "" {
+			reason = jobFailure(job)
+		}
+
+		if reason == "" {
+			reason = "unknown reason"
+		}
+
+		emitEvent(
+			r.Recorder,
+			workbench,
+			corev1.EventTypeWarning,
+			eventReasonAppFailed,
+			"The app %q failed: %s",
+			app.Name,
+			reason,
+		)
+	}
+}
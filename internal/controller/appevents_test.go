@@ -0,0 +1,60 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("App events", func() {
+	app := defaultv1alpha1.WorkbenchApp{Name: "wezterm"}
+
+	failedJob := batchv1.Job{}
+	failedJob.Status.Conditions = []batchv1.JobCondition{
+		{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: batchv1.JobReasonBackoffLimitExceeded, Message: "Job has reached the specified backoff limit"},
+	}
+
+	It("should tell the completions and the failures, with their reason", func() {
+		recorder := record.NewFakeRecorder(10)
+		r := &WorkbenchReconciler{Recorder: recorder}
+		workbench := &defaultv1alpha1.Workbench{}
+
+		r.reportAppTransition(workbench, app, defaultv1alpha1.WorkbenchStatusAppStatusRunning, defaultv1alpha1.WorkbenchStatusApp{
+			Status: defaultv1alpha1.WorkbenchStatusAppStatusComplete,
+		}, batchv1.Job{})
+		Expect(recorder.Events).To(Receive(Equal(`Normal AppCompleted The app "wezterm" completed`)))
+
+		r.reportAppTransition(workbench, app, defaultv1alpha1.WorkbenchStatusAppStatusRunning, defaultv1alpha1.WorkbenchStatusApp{
+			Status: defaultv1alpha1.WorkbenchStatusAppStatusFailed,
+		}, failedJob)
+		Expect(recorder.Events).To(Receive(Equal(`Warning AppFailed The app "wezterm" failed: BackoffLimitExceeded: Job has reached the specified backoff limit`)))
+
+		r.reportAppTransition(workbench, app, defaultv1alpha1.WorkbenchStatusAppStatusProgressing, defaultv1alpha1.WorkbenchStatusApp{
+			Status:  defaultv1alpha1.WorkbenchStatusAppStatusFailed,
+			Message: "StartupDeadlineExceeded: not ready within 60s",
+		}, batchv1.Job{})
+		Expect(recorder.Events).To(Receive(ContainSubstring("failed: StartupDeadlineExceeded")))
+	})
+
+	It("should only tell the transitions", func() {
+		recorder := record.NewFakeRecorder(10)
+		r := &WorkbenchReconciler{Recorder: recorder}
+		workbench := &defaultv1alpha1.Workbench{}
+
+		// Already failed on the previous pass.
+		r.reportAppTransition(workbench, app, defaultv1alpha1.WorkbenchStatusAppStatusFailed, defaultv1alpha1.WorkbenchStatusApp{
+			Status: defaultv1alpha1.WorkbenchStatusAppStatusFailed,
+		}, failedJob)
+
+		// Nothing worth telling.
+		r.reportAppTransition(workbench, app, defaultv1alpha1.WorkbenchStatusAppStatusProgressing, defaultv1alpha1.WorkbenchStatusApp{
+			Status: defaultv1alpha1.WorkbenchStatusAppStatusRunning,
+		}, batchv1.Job{})
+
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
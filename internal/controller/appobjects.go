@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// appUIDLabel identifies the app an object was made for, within its workbench.
+const appUIDLabel = "chorus-tre.ch/app-uid"
+
+// childKind is a kind of objects made for the workbenches.
+type childKind struct {
+	kind    string
+	newList func() client.ObjectList
+}
+
+// appObjectKinds are the kinds of the per-app objects, they are deleted with their app.
+//
+// A new per-app object only has to be labelled with appLabels and its kind listed here.
+var appObjectKinds = []childKind{
+	{"Job", func() client.ObjectList { return &batchv1.JobList{} }},
+	{"Service", func() client.ObjectList { return &corev1.ServiceList{} }},
+}
+
+// appUID is the identifier of the app, its position and name like in the job name.
+func appUID(index int, app defaultv1alpha1.WorkbenchApp) string {
+	return fmt.Sprintf("%d-%s", index, app.Name)
+}
+
+// appLabels are the labels put on every object made for the given app.
+func appLabels(workbench defaultv1alpha1.Workbench, labelKeys []string, index int, app defaultv1alpha1.WorkbenchApp) map[string]string {
+	labels := childLabels(workbench, labelKeys)
+	labels[appUIDLabel] = appUID(index, app)
+
+	return labels
+}
+
+// deleteStaleAppObjects deletes the per-app objects whose app was removed from the spec.
+//
+// The stopped apps are still in the spec, they keep their objects.
+func (r *WorkbenchReconciler) deleteStaleAppObjects(ctx context.Context, workbench defaultv1alpha1.Workbench) error {
+	log := log.FromContext(ctx)
+
+	uids := map[string]bool{}
+	for index, app := range workbench.Spec.Apps {
+		uids[appUID(index, app)] = true
+	}
+
+	for _, kind := range appObjectKinds {
+		list := kind.newList()
+
+		if err := r.List(
+			ctx,
+			list,
+			client.InNamespace(targetNamespace(workbench)),
+			childSelector(workbench),
+			client.HasLabels{appUIDLabel},
+		); err != nil {
+			return err
+		}
+
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			obj, ok := object.(client.Object)
+			if !ok || uids[obj.GetLabels()[appUIDLabel]] {
+				continue
+			}
+
+			log.V(1).Info("Stale app object found, removing", "kind", kind.kind, "name", obj.GetName())
+
+			err := r.Delete(ctx, obj, client.PropagationPolicy("Background"))
+			if client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
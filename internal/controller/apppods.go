@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// appPods lists the pods of the job of an app.
+func (r *WorkbenchReconciler) appPods(ctx context.Context, job batchv1.Job) ([]corev1.Pod, error) {
+	pods := corev1.PodList{}
+	if err := r.List(
+		ctx,
+		&pods,
+		client.InNamespace(job.Namespace),
+		client.MatchingLabels{batchv1.JobNameLabel: job.Name},
+	); err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+// appPodHealth tells how the newest pod of the job is, its init containers first, nil without
+// any pod.
+func appPodHealth(pods []corev1.Pod) *defaultv1alpha1.WorkbenchStatusAppPod {
+	newest := newestPod(pods)
+	if newest == nil {
+		return nil
+	}
+
+	health := defaultv1alpha1.WorkbenchStatusAppPod{
+		Name:  newest.Name,
+		Phase: newest.Status.Phase,
+	}
+
+	containers := func(statuses []corev1.ContainerStatus, init bool) {
+		for _, status := range statuses {
+			state := describeContainer(status)
+			if state == "" {
+				state = "unknown"
+			}
+
+			health.Containers = append(health.Containers, defaultv1alpha1.WorkbenchStatusAppContainer{
+				Name:     status.Name,
+				Init:     init,
+				Ready:    status.Ready,
+				Restarts: status.RestartCount,
+				State:    state,
+			})
+		}
+	}
+
+	containers(newest.Status.InitContainerStatuses, true)
+	containers(newest.Status.ContainerStatuses, false)
+
+	return &health
+}
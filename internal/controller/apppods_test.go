@@ -0,0 +1,80 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("App pods", func() {
+	newPod := func(name string, created int64, statuses ...corev1.ContainerStatus) corev1.Pod {
+		pod := corev1.Pod{}
+		pod.Name = name
+		pod.CreationTimestamp = metav1.Unix(created, 0)
+		pod.Status.Phase = corev1.PodPending
+		pod.Status.ContainerStatuses = statuses
+
+		return pod
+	}
+
+	It("should have no health without a pod", func() {
+		Expect(appPodHealth(nil)).To(BeNil())
+	})
+
+	It("should tell a waiting container, from the newest pod", func() {
+		older := newPod("wezterm-older", 1000, corev1.ContainerStatus{
+			Name:  "wezterm",
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		})
+
+		newer := newPod("wezterm-newer", 2000, corev1.ContainerStatus{
+			Name:  "wezterm",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+		})
+
+		Expect(appPodHealth([]corev1.Pod{older, newer})).To(Equal(&defaultv1alpha1.WorkbenchStatusAppPod{
+			Name:  "wezterm-newer",
+			Phase: corev1.PodPending,
+			Containers: []defaultv1alpha1.WorkbenchStatusAppContainer{
+				{Name: "wezterm", State: "waiting, ImagePullBackOff"},
+			},
+		}))
+	})
+
+	It("should tell an out of memory kill", func() {
+		pod := newPod("wezterm", 1000, corev1.ContainerStatus{
+			Name:         "wezterm",
+			RestartCount: 3,
+			State:        corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+		})
+		pod.Status.Phase = corev1.PodRunning
+
+		health := appPodHealth([]corev1.Pod{pod})
+		Expect(health.Phase).To(Equal(corev1.PodRunning))
+		Expect(health.Containers).To(Equal([]defaultv1alpha1.WorkbenchStatusAppContainer{
+			{Name: "wezterm", Restarts: 3, State: "terminated, OOMKilled"},
+		}))
+	})
+
+	It("should tell the init containers first, then a ready app", func() {
+		pod := newPod("wezterm", 1000, corev1.ContainerStatus{
+			Name:  "wezterm",
+			Ready: true,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		})
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+			{Name: "setup", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			{Name: "license"},
+		}
+
+		Expect(appPodHealth([]corev1.Pod{pod}).Containers).To(Equal([]defaultv1alpha1.WorkbenchStatusAppContainer{
+			{Name: "setup", Init: true, State: "running, not ready"},
+			{Name: "license", Init: true, State: "unknown"},
+			{Name: "wezterm", Ready: true, State: "running"},
+		}))
+	})
+})
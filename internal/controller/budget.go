@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// appsBudgetRequeueAfter is how soon a pass that ran out of budget continues.
+const appsBudgetRequeueAfter = 100 * time.Millisecond
+
+// appsBudget bounds the apps handled by a single reconcile.
+//
+// On a very large workbench, a long pass could outlast the leader election renew deadline.
+type appsBudget struct {
+	maxApps  int
+	duration time.Duration
+	start    time.Time
+	handled  int
+}
+
+// newAppsBudget starts the budget of a pass, zeros mean unbounded.
+func newAppsBudget(config AppConfig, now time.Time) *appsBudget {
+	return &appsBudget{
+		maxApps:  config.MaxPerReconcile,
+		duration: config.ReconcileBudget,
+		start:    now,
+	}
+}
+
+// take tells whether one more app may be handled, and counts it.
+//
+// The first app is always handled, so each pass makes progress.
+func (b *appsBudget) take(now time.Time) bool {
+	if b.handled > 0 {
+		if b.maxApps > 0 && b.handled >= b.maxApps {
+			return false
+		}
+
+		if b.duration > 0 && now.Sub(b.start) >= b.duration {
+			return false
+		}
+	}
+
+	b.handled++
+
+	return true
+}
+
+// appCursors remembers where the passes out of budget stopped, per workbench.
+//
+// It's only in memory: after a restart, or a leader change, the apps are handled from the start.
+// The cursors are kept by name, so the gone workbenches are forgotten without their UID, and a
+// cursor only applies to the workbench that set it, not to a new one of the same name.
+type appCursors struct {
+	mu      sync.Mutex
+	cursors map[types.NamespacedName]appCursor
+}
+
+// appCursor is the index of the first app of the next pass of the workbench.
+type appCursor struct {
+	uid   types.UID
+	index int
+}
+
+// get returns the index of the first app to handle.
+func (c *appCursors) get(key types.NamespacedName, uid types.UID) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cursor, ok := c.cursors[key]
+	if !ok || cursor.uid != uid {
+		return 0
+	}
+
+	return cursor.index
+}
+
+// set records the index of the first app of the next pass, zero forgets it.
+func (c *appCursors) set(key types.NamespacedName, uid types.UID, index int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if index == 0 {
+		delete(c.cursors, key)
+		return
+	}
+
+	if c.cursors == nil {
+		c.cursors = map[types.NamespacedName]appCursor{}
+	}
+
+	c.cursors[key] = appCursor{uid: uid, index: index}
+}
+
+// forget drops the cursor of a deleted workbench.
+func (c *appCursors) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.cursors, key)
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Apps budget", func() {
+	start := time.Unix(1000, 0)
+
+	It("should be unbounded by default", func() {
+		budget := newAppsBudget(AppConfig{}, start)
+
+		for range 100 {
+			Expect(budget.take(start.Add(time.Hour))).To(BeTrue())
+		}
+	})
+
+	It("should bound the number of apps", func() {
+		budget := newAppsBudget(AppConfig{MaxPerReconcile: 2}, start)
+
+		Expect(budget.take(start)).To(BeTrue())
+		Expect(budget.take(start)).To(BeTrue())
+		Expect(budget.take(start)).To(BeFalse())
+	})
+
+	It("should bound the time, after the first app", func() {
+		budget := newAppsBudget(AppConfig{ReconcileBudget: time.Second}, start)
+
+		Expect(budget.take(start.Add(time.Minute))).To(BeTrue())
+		Expect(budget.take(start.Add(time.Minute))).To(BeFalse())
+	})
+
+	It("should remember the cursors per workbench", func() {
+		cursors := appCursors{}
+		a := types.NamespacedName{Namespace: "default", Name: "a"}
+		b := types.NamespacedName{Namespace: "default", Name: "b"}
+
+		Expect(cursors.get(a, "uid-a")).To(Equal(0))
+
+		cursors.set(a, "uid-a", 3)
+		Expect(cursors.get(a, "uid-a")).To(Equal(3))
+		Expect(cursors.get(b, "uid-b")).To(Equal(0))
+
+		// A new workbench of the same name starts from the first app.
+		Expect(cursors.get(a, "uid-a2")).To(Equal(0))
+
+		cursors.set(a, "uid-a", 0)
+		Expect(cursors.cursors).NotTo(HaveKey(a))
+
+		// The deleted ones are forgotten by name.
+		cursors.set(b, "uid-b", 2)
+		cursors.forget(b)
+		Expect(cursors.cursors).NotTo(HaveKey(b))
+	})
+})
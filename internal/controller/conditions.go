@@ -0,0 +1,29 @@
+package controller
+
+import (
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// Condition types of the workbench status.
+const (
+	// conditionImagePullSecrets tells whether all the image pull secrets are usable.
+	conditionImagePullSecrets = "ImagePullSecretsValid"
+
+	// conditionReferences tells whether all the references stay within the workbench namespace.
+	conditionReferences = defaultv1alpha1.ConditionReferencesValid
+
+	// conditionSelectorMigration tells whether the server deployment has an outdated selector.
+	conditionSelectorMigration = "SelectorMigrationRequired"
+
+	// conditionDeleting tells what is still blocking the deletion of the workbench.
+	conditionDeleting = "Deleting"
+
+	// conditionGPUScheduled tells whether the pods requesting GPUs could be placed on a node.
+	conditionGPUScheduled = "GPUScheduled"
+
+	// conditionBlocked tells whether the Pod Security admission refuses some of the pods.
+	conditionBlocked = defaultv1alpha1.ConditionBlocked
+
+	// conditionSuspended tells whether the workbench is scaled to zero.
+	conditionSuspended = defaultv1alpha1.ConditionSuspended
+)
@@ -1,13 +1,360 @@
 package controller
 
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
 // Config holds the global configuration that was given to the controller.
+//
+// It's split by area, the builders only given the areas they use.
 type Config struct {
+	// Images locates the server and apps OCI images.
+	Images ImagesConfig
+	// Server configures the Xpra server deployments.
+	Server ServerConfig
+	// Apps configures the app jobs.
+	Apps AppConfig
+	// GPU places the pods requesting GPUs.
+	GPU GPUConfig
+	// Labels are the labels of the children.
+	Labels LabelsConfig
+	// Finalization follows the deletion of the workbenches.
+	Finalization FinalizationConfig
+	// Metrics turns the optional metrics on.
+	Metrics MetricsConfig
+	// OrphanSweep cleans, at startup, the children left behind.
+	OrphanSweep OrphanSweepConfig
+	// SyntheticProbe configures the periodic synthetic workbench.
+	SyntheticProbe SyntheticProbeConfig
+}
+
+// ImagesConfig holds where the server and apps OCI images are pulled from.
+type ImagesConfig struct {
 	// Registry contains the hostname of the server and apps OCI images.
 	Registry string
 	// AppsRepository holds the repository where to find the applications.
 	AppsRepository string
-	// SocatImage is the image (with version) to expose X11 on a TCP socket.
-	SocatImage string
+	// ReplicatePullSecret is the shared pull secret copied into the workbench namespaces, if any.
+	ReplicatePullSecret types.NamespacedName
+}
+
+// ServerConfig holds the configuration of the Xpra server deployments.
+type ServerConfig struct {
 	// XpraServerImage is the image (no version) used as the server.
 	XpraServerImage string
+	// SocatImage is the image (with version) to expose X11 on a TCP socket.
+	SocatImage string
+	// Resources are the resources of the Xpra server containers not telling theirs.
+	Resources corev1.ResourceRequirements
+	// RecreateOnSelectorChange replaces the server deployments whose selector is outdated.
+	RecreateOnSelectorChange bool
+	// DisableSessionAuth leaves the Xpra session unprotected, for the images not supporting it.
+	DisableSessionAuth bool
+}
+
+// AppConfig holds the configuration of the app jobs.
+type AppConfig struct {
+	// StatusDamping is the minimum time an app status is held, to absorb flapping.
+	StatusDamping time.Duration
+	// MaxShmSize bounds the /dev/shm size an app may request, zero means unbounded.
+	MaxShmSize resource.Quantity
+	// MaxPerReconcile bounds the apps handled by a single reconcile, zero means unbounded.
+	MaxPerReconcile int
+	// ReconcileBudget bounds the time spent on the apps by a single reconcile, zero means unbounded.
+	ReconcileBudget time.Duration
+	// RestartOnServiceRecreation restarts the app pods when the service is recreated.
+	RestartOnServiceRecreation bool
+	// DisableJobDryRun creates the jobs without checking them with a dry run first, saving a call.
+	DisableJobDryRun bool
+	// KillOnStartupDeadline stops the jobs of the apps out of startup time, not only failing them.
+	KillOnStartupDeadline bool
+	// DisableNativeSidecars renders the app sidecars as plain containers, for clusters
+	// older than Kubernetes 1.29.
+	DisableNativeSidecars bool
+	// Resources are the resources of the app containers not telling theirs.
+	Resources corev1.ResourceRequirements
+	// ResourceProfiles are the resources of the app containers naming a profile instead.
+	ResourceProfiles map[string]corev1.ResourceRequirements
+	// JobTTLAfterFinished is how long the jobs of the apps not telling theirs are kept once
+	// finished, zero means a day.
+	JobTTLAfterFinished time.Duration
+}
+
+// GPUConfig holds where the pods requesting GPUs are placed.
+type GPUConfig struct {
+	// NodeSelector places the pods requesting GPUs on the GPU nodes.
+	NodeSelector map[string]string
+	// Tolerations let the pods requesting GPUs run on the tainted GPU nodes.
+	Tolerations []corev1.Toleration
+}
+
+// LabelsConfig holds the labels put on the children of the workbenches.
+type LabelsConfig struct {
+	// PropagatedKeys are the workbench labels copied onto all its children.
+	PropagatedKeys []string
+}
+
+// FinalizationConfig holds how the deletion of the workbenches is followed.
+type FinalizationConfig struct {
+	// WarningAfter is how long the deletion of the children may take before a warning.
+	WarningAfter time.Duration
+}
+
+// MetricsConfig holds the optional metrics.
+type MetricsConfig struct {
+	// Convergence measures the time the spec changes take to converge, in memory.
+	Convergence bool
+}
+
+// OrphanSweepConfig holds the startup sweep of the orphaned children.
+type OrphanSweepConfig struct {
+	// Enabled deletes, at startup, the children whose workbench is gone.
+	Enabled bool
+	// DryRun only reports the orphaned children.
+	DryRun bool
+}
+
+// SyntheticProbeConfig holds the configuration of the synthetic workbench probe.
+type SyntheticProbeConfig struct {
+	// Enabled turns the probe on.
+	Enabled bool
+	// Namespace is where the probe workbenches are created.
+	Namespace string
+	// Interval is the time between two probes.
+	Interval time.Duration
+	// Timeout is how long a probe workbench has to become ready.
+	Timeout time.Duration
+	// App is the name of the minimal app to run, empty means server only.
+	App string
+}
+
+// Validate canonicalizes the configuration and rejects the invalid combinations.
+//
+// Each area is validated on its own.
+func (c *Config) Validate() error {
+	if err := c.Images.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Server.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Apps.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Labels.Validate(); err != nil {
+		return err
+	}
+
+	return c.SyntheticProbe.Validate()
+}
+
+// Validate canonicalizes the images configuration.
+//
+// The registry and repository lose their surrounding slashes, so the builders can
+// simply join them.
+func (c *ImagesConfig) Validate() error {
+	c.Registry = strings.TrimRight(c.Registry, "/")
+	c.AppsRepository = strings.Trim(c.AppsRepository, "/")
+
+	if (c.ReplicatePullSecret.Name == "") != (c.ReplicatePullSecret.Namespace == "") {
+		return fmt.Errorf("pull secret to replicate %q must be a namespace/name", c.ReplicatePullSecret)
+	}
+
+	return nil
+}
+
+// HasAppsRegistry tells whether the apps without an image get a name the puller can resolve.
+//
+// Without both, "myapp:latest" would be looked up on the Docker Hub, where it does not exist.
+func (c ImagesConfig) HasAppsRegistry() bool {
+	return c.Registry != "" || c.AppsRepository != ""
+}
+
+// Validate rejects the invalid server configurations.
+func (c ServerConfig) Validate() error {
+	// The server version is part of the workbench, the image cannot hold one.
+	if hasTag(c.XpraServerImage) {
+		return fmt.Errorf("xpra server image %q must not contain a tag or a digest", c.XpraServerImage)
+	}
+
+	if err := validateResources(c.Resources); err != nil {
+		return fmt.Errorf("server resources: %w", err)
+	}
+
+	return nil
+}
+
+// Validate rejects the invalid apps configurations.
+func (c AppConfig) Validate() error {
+	if c.StatusDamping < 0 {
+		return fmt.Errorf("status damping must not be negative")
+	}
+
+	if c.MaxPerReconcile < 0 || c.ReconcileBudget < 0 {
+		return fmt.Errorf("apps reconcile budget must not be negative")
+	}
+
+	if c.MaxShmSize.Sign() < 0 {
+		return fmt.Errorf("max shm size must not be negative")
+	}
+
+	if c.JobTTLAfterFinished < 0 {
+		return fmt.Errorf("job ttl after finished must not be negative")
+	}
+
+	// The jobs keep it in seconds, as an int32.
+	if c.JobTTLAfterFinished > math.MaxInt32*time.Second {
+		return fmt.Errorf("job ttl after finished must not exceed %s", math.MaxInt32*time.Second)
+	}
+
+	if err := validateResources(c.Resources); err != nil {
+		return fmt.Errorf("app resources: %w", err)
+	}
+
+	for name, resources := range c.ResourceProfiles {
+		if name == "" {
+			return fmt.Errorf("resource profile name is required")
+		}
+
+		if err := validateResources(resources); err != nil {
+			return fmt.Errorf("resource profile %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ResourceProfileNames are the sorted names of the resource profiles.
+func (c AppConfig) ResourceProfileNames() []string {
+	names := make([]string, 0, len(c.ResourceProfiles))
+	for name := range c.ResourceProfiles {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Validate rejects the propagated label keys the operator cannot copy.
+func (c LabelsConfig) Validate() error {
+	return ValidatePropagatedLabelKeys(c.PropagatedKeys)
+}
+
+// Validate rejects an incomplete synthetic probe, when enabled.
+func (c SyntheticProbeConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Namespace == "" {
+		return fmt.Errorf("synthetic probe namespace is required")
+	}
+
+	if c.Interval <= 0 || c.Timeout <= 0 {
+		return fmt.Errorf("synthetic probe interval and timeout must be positive")
+	}
+
+	return nil
+}
+
+// SetQuantity parses the quantity of a resource given on the command line into the list,
+// nothing is set when it's empty.
+func SetQuantity(list *corev1.ResourceList, name corev1.ResourceName, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s quantity %q: %w", name, value, err)
+	}
+
+	if *list == nil {
+		*list = corev1.ResourceList{}
+	}
+
+	(*list)[name] = quantity
+
+	return nil
+}
+
+// ParseResourceProfiles parses the semicolon-separated name=cpu,memory profiles given on the
+// command line, e.g. small=1,2Gi;large=8,32Gi.
+//
+// The quantities are both the requests and the limits, so the apps get what their size tells.
+func ParseResourceProfiles(value string) (map[string]corev1.ResourceRequirements, error) {
+	profiles := map[string]corev1.ResourceRequirements{}
+
+	for _, profile := range strings.Split(value, ";") {
+		name, quantities, found := strings.Cut(profile, "=")
+		cpu, memory, paired := strings.Cut(quantities, ",")
+		if !found || !paired || name == "" {
+			return nil, fmt.Errorf("%q is not a name=cpu,memory profile", profile)
+		}
+
+		if _, ok := profiles[name]; ok {
+			return nil, fmt.Errorf("resource profile %q is given twice", name)
+		}
+
+		resources := corev1.ResourceRequirements{}
+		for _, list := range []*corev1.ResourceList{&resources.Requests, &resources.Limits} {
+			if err := SetQuantity(list, corev1.ResourceCPU, cpu); err != nil {
+				return nil, fmt.Errorf("resource profile %q: %w", name, err)
+			}
+
+			if err := SetQuantity(list, corev1.ResourceMemory, memory); err != nil {
+				return nil, fmt.Errorf("resource profile %q: %w", name, err)
+			}
+		}
+
+		profiles[name] = resources
+	}
+
+	return profiles, nil
+}
+
+// validateResources rejects the negative quantities and the requests above their limits.
+func validateResources(resources corev1.ResourceRequirements) error {
+	for name, limit := range resources.Limits {
+		if limit.Sign() < 0 {
+			return fmt.Errorf("%s limit must not be negative", name)
+		}
+	}
+
+	for name, request := range resources.Requests {
+		if request.Sign() < 0 {
+			return fmt.Errorf("%s request must not be negative", name)
+		}
+
+		if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			return fmt.Errorf("%s request %s is above its limit %s", name, request.String(), limit.String())
+		}
+	}
+
+	return nil
+}
+
+// hasTag tells whether the image reference carries a tag or a digest.
+//
+// The port of the registry, e.g. localhost:5000/image, is not a tag.
+func hasTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return true
+	}
+
+	name := image[strings.LastIndex(image, "/")+1:]
+
+	return strings.Contains(name, ":")
 }
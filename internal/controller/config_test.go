@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"math"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Config", func() {
+	It("should normalize the registry and repository", func() {
+		config := Config{
+			Images: ImagesConfig{
+				Registry:       "quay.io/",
+				AppsRepository: "/chorus/apps/",
+			},
+		}
+
+		Expect(config.Validate()).To(Succeed())
+		Expect(config.Images.Registry).To(Equal("quay.io"))
+		Expect(config.Images.AppsRepository).To(Equal("chorus/apps"))
+	})
+
+	It("should tell whether the apps have a registry", func() {
+		Expect(ImagesConfig{}.HasAppsRegistry()).To(BeFalse())
+		Expect(ImagesConfig{Registry: "quay.io"}.HasAppsRegistry()).To(BeTrue())
+		Expect(ImagesConfig{AppsRepository: "apps"}.HasAppsRegistry()).To(BeTrue())
+	})
+
+	It("should accept an untagged server image", func() {
+		for _, image := range []string{"", "xpra-server", "quay.io/chorus/xpra-server", "localhost:5000/xpra-server"} {
+			config := Config{Server: ServerConfig{XpraServerImage: image}}
+			Expect(config.Validate()).To(Succeed(), image)
+		}
+	})
+
+	It("should reject a tagged server image", func() {
+		for _, image := range []string{"xpra-server:6.2", "localhost:5000/xpra-server:6.2", "quay.io/xpra-server@sha256:abcd"} {
+			config := Config{Server: ServerConfig{XpraServerImage: image}}
+			Expect(config.Validate()).NotTo(Succeed(), image)
+		}
+	})
+
+	It("should reject the operator managed label keys", func() {
+		for _, key := range managedLabelKeys {
+			config := Config{Labels: LabelsConfig{PropagatedKeys: []string{key}}}
+			Expect(config.Validate()).NotTo(Succeed(), key)
+		}
+
+		config := Config{Labels: LabelsConfig{PropagatedKeys: []string{prePullLabel}}}
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+
+	It("should reject the invalid label keys", func() {
+		for _, key := range []string{"cost center", "-cost-center", "a/b/c", "example.com/"} {
+			config := Config{Labels: LabelsConfig{PropagatedKeys: []string{key}}}
+			Expect(config.Validate()).NotTo(Succeed(), key)
+		}
+
+		config := Config{Labels: LabelsConfig{PropagatedKeys: []string{"cost-center", "example.com/team"}}}
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should reject a negative status damping", func() {
+		config := Config{Apps: AppConfig{StatusDamping: -time.Second}}
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+
+	It("should reject a negative max shm size", func() {
+		config := Config{Apps: AppConfig{MaxShmSize: resource.MustParse("-1Gi")}}
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+
+	It("should require a complete pull secret to replicate", func() {
+		config := Config{Images: ImagesConfig{ReplicatePullSecret: types.NamespacedName{Name: "registry"}}}
+		Expect(config.Validate()).NotTo(Succeed())
+
+		config.Images.ReplicatePullSecret.Namespace = "chorus"
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should require a complete synthetic probe", func() {
+		config := Config{
+			SyntheticProbe: SyntheticProbeConfig{
+				Enabled:  true,
+				Interval: time.Minute,
+				Timeout:  time.Minute,
+			},
+		}
+		Expect(config.Validate()).NotTo(Succeed())
+
+		config.SyntheticProbe.Namespace = "default"
+		Expect(config.Validate()).To(Succeed())
+
+		config.SyntheticProbe.Timeout = 0
+		Expect(config.Validate()).NotTo(Succeed())
+
+		// Disabled, nothing is checked.
+		config.SyntheticProbe.Enabled = false
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should validate the images on their own", func() {
+		images := ImagesConfig{Registry: "localhost:5000/"}
+		Expect(images.Validate()).To(Succeed())
+		Expect(images.Registry).To(Equal("localhost:5000"))
+
+		images.ReplicatePullSecret.Namespace = "chorus"
+		Expect(images.Validate()).NotTo(Succeed())
+	})
+
+	It("should validate the server on its own", func() {
+		Expect(ServerConfig{}.Validate()).To(Succeed())
+		Expect(ServerConfig{XpraServerImage: "xpra-server:6.2"}.Validate()).NotTo(Succeed())
+	})
+
+	It("should validate the apps on their own", func() {
+		Expect(AppConfig{}.Validate()).To(Succeed())
+		Expect(AppConfig{MaxPerReconcile: -1}.Validate()).NotTo(Succeed())
+		Expect(AppConfig{ReconcileBudget: -time.Second}.Validate()).NotTo(Succeed())
+		Expect(AppConfig{StatusDamping: -time.Second}.Validate()).NotTo(Succeed())
+		Expect(AppConfig{JobTTLAfterFinished: -time.Second}.Validate()).NotTo(Succeed())
+		Expect(AppConfig{JobTTLAfterFinished: math.MaxInt32 * time.Second}.Validate()).To(Succeed())
+		Expect(AppConfig{JobTTLAfterFinished: (math.MaxInt32 + 1) * time.Second}.Validate()).NotTo(Succeed())
+	})
+
+	It("should parse the resource quantities of the flags", func() {
+		resources := corev1.ResourceRequirements{}
+
+		Expect(SetQuantity(&resources.Requests, corev1.ResourceCPU, "")).To(Succeed())
+		Expect(resources.Requests).To(BeNil())
+
+		Expect(SetQuantity(&resources.Requests, corev1.ResourceCPU, "500m")).To(Succeed())
+		Expect(SetQuantity(&resources.Limits, corev1.ResourceEphemeralStorage, "10Gi")).To(Succeed())
+		Expect(resources.Requests.Cpu().String()).To(Equal("500m"))
+		Expect(resources.Limits.StorageEphemeral().String()).To(Equal("10Gi"))
+
+		Expect(SetQuantity(&resources.Limits, corev1.ResourceMemory, "2GB of RAM")).To(MatchError(ContainSubstring(`invalid memory quantity "2GB of RAM"`)))
+	})
+
+	It("should parse the resource profiles of the flags", func() {
+		profiles, err := ParseResourceProfiles("small=1,2Gi;large=8,32Gi")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profiles).To(HaveLen(2))
+		large := profiles["large"]
+		Expect(large.Requests.Cpu().String()).To(Equal("8"))
+		Expect(large.Limits.Memory().String()).To(Equal("32Gi"))
+		Expect(AppConfig{ResourceProfiles: profiles}.ResourceProfileNames()).To(Equal([]string{"large", "small"}))
+		Expect(AppConfig{ResourceProfiles: profiles}.Validate()).To(Succeed())
+
+		_, err = ParseResourceProfiles("small=1")
+		Expect(err).To(MatchError(ContainSubstring(`"small=1" is not a name=cpu,memory profile`)))
+		_, err = ParseResourceProfiles("=1,2Gi")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseResourceProfiles("small=1,2Gi;small=2,4Gi")
+		Expect(err).To(MatchError(ContainSubstring("given twice")))
+		_, err = ParseResourceProfiles("small=one,2Gi")
+		Expect(err).To(MatchError(ContainSubstring(`resource profile "small": invalid cpu quantity "one"`)))
+
+		profiles, err = ParseResourceProfiles("small=-1,2Gi")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(AppConfig{ResourceProfiles: profiles}.Validate()).To(MatchError(ContainSubstring(`resource profile "small"`)))
+	})
+
+	It("should reject the default app resources out of their limits", func() {
+		config := AppConfig{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+		}
+		Expect(config.Validate()).To(MatchError(ContainSubstring("app resources: memory request 4Gi is above its limit 2Gi")))
+
+		config.Resources.Limits[corev1.ResourceMemory] = resource.MustParse("8Gi")
+		Expect(config.Validate()).To(Succeed())
+
+		config.Resources.Requests[corev1.ResourceCPU] = resource.MustParse("-1")
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+
+	It("should validate the synthetic probe on its own", func() {
+		Expect(SyntheticProbeConfig{}.Validate()).To(Succeed())
+		Expect(SyntheticProbeConfig{Enabled: true, Namespace: "default"}.Validate()).NotTo(Succeed())
+	})
+})
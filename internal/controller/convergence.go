@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// pendingGeneration is a generation of the spec not converged yet, and since when.
+type pendingGeneration struct {
+	generation int64
+	since      time.Time
+}
+
+// convergenceTracker measures how long the generations of the workbenches take to converge.
+//
+// It's only in memory: after a restart, or a leader change, the pending generations are
+// measured from the first pass seeing them.
+type convergenceTracker struct {
+	mu      sync.Mutex
+	pending map[types.UID]pendingGeneration
+	// done are the last generations measured, the later passes over them are not samples.
+	done map[types.UID]int64
+}
+
+// observe remembers when the generation of the workbench was first seen.
+//
+// The first generation is measured from the creation, which covers the time spent in the queue.
+func (t *convergenceTracker) observe(workbench defaultv1alpha1.Workbench, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if pending, ok := t.pending[workbench.UID]; ok && pending.generation >= workbench.Generation {
+		return
+	}
+
+	if done, ok := t.done[workbench.UID]; ok && done >= workbench.Generation {
+		return
+	}
+
+	since := now
+	if workbench.Generation <= 1 && !workbench.CreationTimestamp.IsZero() {
+		since = workbench.CreationTimestamp.Time
+	}
+
+	if t.pending == nil {
+		t.pending = map[types.UID]pendingGeneration{}
+	}
+
+	t.pending[workbench.UID] = pendingGeneration{
+		generation: workbench.Generation,
+		since:      since,
+	}
+}
+
+// converged tells how long the generation of the workbench took to converge, once.
+func (t *convergenceTracker) converged(workbench defaultv1alpha1.Workbench, now time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending, ok := t.pending[workbench.UID]
+	if !ok || pending.generation > workbench.Generation {
+		return 0, false
+	}
+
+	delete(t.pending, workbench.UID)
+
+	if t.done == nil {
+		t.done = map[types.UID]int64{}
+	}
+
+	t.done[workbench.UID] = pending.generation
+
+	return max(now.Sub(pending.since), 0), true
+}
+
+// forget drops the generations of a deleted workbench.
+func (t *convergenceTracker) forget(uid types.UID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending, uid)
+	delete(t.done, uid)
+}
+
+// isSettled tells whether the pass left the workbench as its spec wants it: the apps were all
+// handled, nothing was created and nothing is still starting.
+func isSettled(workbench defaultv1alpha1.Workbench, appsCompleted, childCreated bool) bool {
+	if !appsCompleted || childCreated {
+		return false
+	}
+
+	return defaultv1alpha1.ComputePhase(workbench.Status, workbench.DeletionTimestamp) != defaultv1alpha1.WorkbenchPhaseCreating
+}
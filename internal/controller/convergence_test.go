@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Convergence", func() {
+	newWorkbench := func(generation int64) defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.UID = types.UID("workbench")
+		workbench.Generation = generation
+
+		return workbench
+	}
+
+	It("should measure a generation from the pass first seeing it", func() {
+		fakeClock := clocktesting.NewFakeClock(time.Unix(1000, 0))
+		tracker := convergenceTracker{}
+
+		tracker.observe(newWorkbench(2), fakeClock.Now())
+
+		fakeClock.Step(3 * time.Second)
+		tracker.observe(newWorkbench(2), fakeClock.Now())
+
+		fakeClock.Step(2 * time.Second)
+		elapsed, ok := tracker.converged(newWorkbench(2), fakeClock.Now())
+		Expect(ok).To(BeTrue())
+		Expect(elapsed).To(Equal(5 * time.Second))
+
+		// It's only measured once.
+		_, ok = tracker.converged(newWorkbench(2), fakeClock.Now())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should measure a generation once, whatever the passes after it", func() {
+		fakeClock := clocktesting.NewFakeClock(time.Unix(1000, 0))
+		tracker := convergenceTracker{}
+
+		workbench := newWorkbench(1)
+		workbench.CreationTimestamp = metav1.NewTime(fakeClock.Now())
+
+		tracker.observe(workbench, fakeClock.Now())
+		fakeClock.Step(time.Second)
+		_, ok := tracker.converged(workbench, fakeClock.Now())
+		Expect(ok).To(BeTrue())
+
+		// E.g. a resync, a long time after.
+		fakeClock.Step(time.Hour)
+		tracker.observe(workbench, fakeClock.Now())
+		_, ok = tracker.converged(workbench, fakeClock.Now())
+		Expect(ok).To(BeFalse())
+
+		// The next generation is measured.
+		tracker.observe(newWorkbench(2), fakeClock.Now())
+		fakeClock.Step(time.Second)
+		elapsed, ok := tracker.converged(newWorkbench(2), fakeClock.Now())
+		Expect(ok).To(BeTrue())
+		Expect(elapsed).To(Equal(time.Second))
+	})
+
+	It("should measure the first generation from the creation", func() {
+		fakeClock := clocktesting.NewFakeClock(time.Unix(1000, 0))
+		tracker := convergenceTracker{}
+
+		workbench := newWorkbench(1)
+		workbench.CreationTimestamp = metav1.NewTime(fakeClock.Now())
+
+		fakeClock.Step(time.Minute)
+		tracker.observe(workbench, fakeClock.Now())
+
+		fakeClock.Step(time.Second)
+		elapsed, ok := tracker.converged(workbench, fakeClock.Now())
+		Expect(ok).To(BeTrue())
+		Expect(elapsed).To(Equal(61 * time.Second))
+	})
+
+	It("should not measure an older generation, nor a forgotten one", func() {
+		fakeClock := clocktesting.NewFakeClock(time.Unix(1000, 0))
+		tracker := convergenceTracker{}
+
+		tracker.observe(newWorkbench(3), fakeClock.Now())
+
+		_, ok := tracker.converged(newWorkbench(2), fakeClock.Now())
+		Expect(ok).To(BeFalse())
+
+		tracker.forget(types.UID("workbench"))
+		_, ok = tracker.converged(newWorkbench(3), fakeClock.Now())
+		Expect(ok).To(BeFalse())
+		Expect(tracker.done).To(BeEmpty())
+	})
+
+	It("should only settle the complete passes creating nothing", func() {
+		workbench := newWorkbench(1)
+		Expect(isSettled(workbench, true, false)).To(BeFalse())
+
+		workbench.Status.Server.Status = defaultv1alpha1.WorkbenchStatusServerStatusRunning
+		Expect(isSettled(workbench, true, false)).To(BeTrue())
+		Expect(isSettled(workbench, false, false)).To(BeFalse())
+		Expect(isSettled(workbench, true, true)).To(BeFalse())
+	})
+})
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// maxDeletionStragglers bounds the names listed in the Deleting condition.
+const maxDeletionStragglers = 5
+
+// finalizedKinds are the children deleted once the apps and the server are gone.
+//
+// The garbage collector would only take them after the workbench, whose deletion could not
+// then tell about them.
+var finalizedKinds = []childKind{
+	{"Service", func() client.ObjectList { return &corev1.ServiceList{} }},
+	{"Secret", func() client.ObjectList { return &corev1.SecretList{} }},
+	{"PersistentVolumeClaim", func() client.ObjectList { return &corev1.PersistentVolumeClaimList{} }},
+	{"ConfigMap", func() client.ObjectList { return &corev1.ConfigMapList{} }},
+}
+
+// deletionSummary lists the children still present while the workbench is finalized.
+type deletionSummary struct {
+	// kinds keeps the order in which the kinds were added.
+	kinds  []string
+	counts map[string]int
+	names  []string
+}
+
+// add records the children of the given kind still present.
+func (s *deletionSummary) add(kind string, names ...string) {
+	if len(names) == 0 {
+		return
+	}
+
+	if s.counts == nil {
+		s.counts = map[string]int{}
+	}
+
+	if !slices.Contains(s.kinds, kind) {
+		s.kinds = append(s.kinds, kind)
+	}
+
+	s.counts[kind] += len(names)
+
+	for _, name := range names {
+		if len(s.names) < maxDeletionStragglers {
+			s.names = append(s.names, kind+"/"+name)
+		}
+	}
+}
+
+// total is the number of children still present.
+func (s deletionSummary) total() int {
+	total := 0
+	for _, count := range s.counts {
+		total += count
+	}
+
+	return total
+}
+
+// String describes what is still present, e.g. "2 Job(s): Job/a, Job/b".
+func (s deletionSummary) String() string {
+	counts := make([]string, 0, len(s.kinds))
+	for _, kind := range s.kinds {
+		counts = append(counts, fmt.Sprintf("%d %s(s)", s.counts[kind], kind))
+	}
+
+	message := strings.Join(counts, ", ") + ": " + strings.Join(s.names, ", ")
+	if len(s.names) < s.total() {
+		message += ", ..."
+	}
+
+	return message
+}
+
+// deletingCondition reports the progress of the finalization.
+//
+// The reason becomes FinalizationStuck once it has taken longer than the warning duration.
+func deletingCondition(workbench defaultv1alpha1.Workbench, summary deletionSummary, now time.Time, warnAfter time.Duration) metav1.Condition {
+	elapsed := time.Duration(0)
+	if workbench.Status.FirstDeletionAttempt != nil {
+		elapsed = now.Sub(workbench.Status.FirstDeletionAttempt.Time)
+	}
+
+	condition := metav1.Condition{
+		Type:   conditionDeleting,
+		Status: metav1.ConditionTrue,
+		Reason: "WaitingForChildren",
+		Message: fmt.Sprintf(
+			"Waiting for %s (since %s)",
+			summary,
+			elapsed.Round(time.Second),
+		),
+		ObservedGeneration: workbench.Generation,
+	}
+
+	if warnAfter > 0 && elapsed > warnAfter {
+		condition.Reason = string(eventReasonFinalizationStuck)
+	}
+
+	return condition
+}
+
+// deleteChildren deletes the children of the given kind, returning the ones still present.
+//
+// The ones already terminating are not deleted again, but still counted.
+func (r *WorkbenchReconciler) deleteChildren(ctx context.Context, workbench defaultv1alpha1.Workbench, kind childKind) ([]string, error) {
+	list := kind.newList()
+
+	if err := r.List(
+		ctx,
+		list,
+		client.InNamespace(targetNamespace(workbench)),
+		childSelector(workbench),
+	); err != nil {
+		return nil, err
+	}
+
+	objects, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+
+	for _, object := range objects {
+		obj, ok := object.(client.Object)
+		if !ok {
+			continue
+		}
+
+		names = append(names, obj.GetName())
+
+		if obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		err := r.Delete(ctx, obj, client.PropagationPolicy("Background"))
+		if client.IgnoreNotFound(err) != nil {
+			return names, err
+		}
+	}
+
+	return names, nil
+}
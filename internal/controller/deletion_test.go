@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Deletion summary", func() {
+	It("should count per kind and list a few names", func() {
+		summary := deletionSummary{}
+		summary.add("Job", "a", "b")
+		summary.add("Deployment", "c")
+		summary.add("Service")
+
+		Expect(summary.total()).To(Equal(3))
+		Expect(summary.String()).To(Equal("2 Job(s), 1 Deployment(s): Job/a, Job/b, Deployment/c"))
+	})
+
+	It("should bound the listed names", func() {
+		summary := deletionSummary{}
+		for i := 0; i < 2*maxDeletionStragglers; i++ {
+			summary.add("Job", fmt.Sprintf("job-%d", i))
+		}
+
+		Expect(summary.String()).To(HavePrefix(fmt.Sprintf("%d Job(s): ", 2*maxDeletionStragglers)))
+		Expect(summary.String()).To(HaveSuffix("Job/job-4, ..."))
+	})
+
+	It("should escalate once it takes too long", func() {
+		start := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Status.FirstDeletionAttempt = &start
+
+		summary := deletionSummary{}
+		summary.add("Job", "stuck")
+
+		condition := deletingCondition(workbench, summary, start.Add(time.Minute), 5*time.Minute)
+		Expect(condition.Reason).To(Equal("WaitingForChildren"))
+		Expect(condition.Message).To(Equal("Waiting for 1 Job(s): Job/stuck (since 1m0s)"))
+
+		condition = deletingCondition(workbench, summary, start.Add(10*time.Minute), 5*time.Minute)
+		Expect(condition.Reason).To(Equal("FinalizationStuck"))
+
+		// Disabled.
+		condition = deletingCondition(workbench, summary, start.Add(10*time.Minute), 0)
+		Expect(condition.Reason).To(Equal("WaitingForChildren"))
+	})
+})
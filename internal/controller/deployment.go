@@ -7,6 +7,8 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -14,27 +16,47 @@ import (
 	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
 )
 
+// socatResources are the resources of the socat sidecar, only forwarding the X11 socket.
+var socatResources = corev1.ResourceRequirements{
+	Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("10m"),
+		corev1.ResourceMemory: resource.MustParse("16Mi"),
+	},
+	Limits: corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("64Mi"),
+	},
+}
+
 // initDeployment creates the Xpra server deployment.
 //
 // Xpra listens on port 8080 and starts a X11 socket in the tmp folder.
 // That folder is shared with a socat sidecar that turns the socket into a nice
 // and shiny TCP listener, on port 6080.
-func initDeployment(workbench defaultv1alpha1.Workbench, config Config) appsv1.Deployment {
+func initDeployment(workbench defaultv1alpha1.Workbench, images ImagesConfig, server ServerConfig, gpu GPUConfig, labelKeys []string) appsv1.Deployment {
 	deployment := appsv1.Deployment{}
 	deployment.Name = fmt.Sprintf("%s-server", workbench.Name)
-	deployment.Namespace = workbench.Namespace
+	deployment.Namespace = targetNamespace(workbench)
 
 	// Labels
-	labels := map[string]string{
-		matchingLabel: workbench.Name,
-	}
+	labels := map[string]string(childSelector(workbench))
 
-	deployment.Labels = labels
+	deployment.Labels = childLabels(workbench, labelKeys)
 	deployment.Spec.Selector = &metav1.LabelSelector{
 		MatchLabels: labels,
 	}
 	deployment.Spec.Template.Labels = labels
 
+	// A suspended workbench, or an idle one, keeps its deployment, scaled to zero.
+	replicas := int32(1)
+	if isWorkbenchSuspended(workbench) {
+		replicas = 0
+	}
+
+	deployment.Spec.Replicas = &replicas
+
+	deployment.Annotations = displayNameAnnotations(workbench)
+	deployment.Spec.Template.Annotations = displayNameAnnotations(workbench)
+
 	// Service account is an alternative to the image Pull Secrets
 	serviceAccountName := workbench.Spec.ServiceAccount
 	if serviceAccountName != "" {
@@ -57,14 +79,10 @@ func initDeployment(workbench defaultv1alpha1.Workbench, config Config) appsv1.D
 
 	deployment.Spec.Template.Spec.Volumes = []corev1.Volume{volume}
 
-	for _, imagePullSecret := range workbench.Spec.ImagePullSecrets {
-		deployment.Spec.Template.Spec.ImagePullSecrets = append(deployment.Spec.Template.Spec.ImagePullSecrets, corev1.LocalObjectReference{
-			Name: imagePullSecret,
-		})
-	}
+	deployment.Spec.Template.Spec.ImagePullSecrets = imagePullSecrets(workbench, images)
 
 	// socatImage and its pull policy
-	socatImage := config.SocatImage
+	socatImage := server.SocatImage
 	if socatImage == "" {
 		socatImage = "alpine/socat:latest"
 	}
@@ -95,17 +113,18 @@ func initDeployment(workbench defaultv1alpha1.Workbench, config Config) appsv1.D
 			"UNIX-CONNECT:/tmp/.X11-unix/X80",
 		},
 		VolumeMounts: volumeMounts,
+		Resources:    *socatResources.DeepCopy(),
 	}
 
 	// Non-empty registry requires a / to concatenate with the Xpra server one.
-	registry := config.Registry
+	registry := images.Registry
 	if registry != "" {
-		registry = strings.TrimRight(registry, "/") + "/"
+		registry += "/"
 	}
 
-	appsRepository := config.AppsRepository
+	appsRepository := images.AppsRepository
 	if appsRepository != "" {
-		appsRepository = strings.Trim(appsRepository, "/") + "/"
+		appsRepository += "/"
 	}
 
 	// TODO: put default values via the admission webhook.
@@ -114,7 +133,7 @@ func initDeployment(workbench defaultv1alpha1.Workbench, config Config) appsv1.D
 		serverVersion = "latest" // nolint:goconst
 	}
 
-	xpraServerImage := config.XpraServerImage
+	xpraServerImage := server.XpraServerImage
 	if xpraServerImage == "" {
 		xpraServerImage = fmt.Sprintf("%s%s%s", registry, appsRepository, "xpra-server")
 	}
@@ -127,7 +146,7 @@ func initDeployment(workbench defaultv1alpha1.Workbench, config Config) appsv1.D
 	}
 
 	serverContainer := corev1.Container{
-		Name:            "xpra-server",
+		Name:            serverContainerName,
 		Image:           serverImage,
 		ImagePullPolicy: serverImagePullPolicy,
 		Ports: []corev1.ContainerPort{
@@ -138,7 +157,6 @@ func initDeployment(workbench defaultv1alpha1.Workbench, config Config) appsv1.D
 		},
 		Env: []corev1.EnvVar{
 			{
-				// Will be needed for GPU.
 				Name:  "CARD",
 				Value: "",
 			},
@@ -146,6 +164,20 @@ func initDeployment(workbench defaultv1alpha1.Workbench, config Config) appsv1.D
 		VolumeMounts: volumeMounts,
 	}
 
+	// Without a GPU, the server renders in software.
+	if workbench.Spec.Server.GPU != nil {
+		serverContainer.Env[0].Value = gpuCard
+	}
+
+	// Without any request, the server would be the first pod evicted under node pressure.
+	serverContainer.Resources = *server.Resources.DeepCopy()
+	if workbench.Spec.Server.Resources != nil {
+		serverContainer.Resources = *workbench.Spec.Server.Resources.DeepCopy()
+	}
+
+	addScheduling(&deployment.Spec.Template.Spec, workbench, nil)
+	addGPU(&deployment.Spec.Template.Spec, &serverContainer, workbench.Spec.Server.GPU, gpu)
+
 	deployment.Spec.Template.Spec.InitContainers = []corev1.Container{sidecarContainer}
 	deployment.Spec.Template.Spec.Containers = []corev1.Container{serverContainer}
 
@@ -153,37 +185,170 @@ func initDeployment(workbench defaultv1alpha1.Workbench, config Config) appsv1.D
 }
 
 // updateDeployment makes the destination deployment (Server) like the source.
-func updateDeployment(source appsv1.Deployment, destination *appsv1.Deployment) bool {
-	updated := false
+func updateDeployment(source appsv1.Deployment, destination *appsv1.Deployment, labelKeys []string) bool {
+	updated := updateLabels(source.Labels, &destination.Labels, labelKeys)
+
+	if updateDisplayName(source.Annotations, &destination.Annotations) {
+		updated = true
+	}
+
+	// Renaming the pod template rolls the server.
+	if updateDisplayName(source.Spec.Template.Annotations, &destination.Spec.Template.Annotations) {
+		updated = true
+	}
 
-	containers := destination.Spec.Template.Spec.Containers
-	if len(containers) != 1 {
+	replicas := source.Spec.Replicas
+	if destination.Spec.Replicas == nil || *destination.Spec.Replicas != *replicas {
+		destination.Spec.Replicas = replicas
+		updated = true
+	}
+
+	// The observers come and go after the server.
+	if len(destination.Spec.Template.Spec.Containers) != len(source.Spec.Template.Spec.Containers) {
 		destination.Spec.Template.Spec.Containers = source.Spec.Template.Spec.Containers
 		updated = true
 	}
 
+	for index, observer := range source.Spec.Template.Spec.Containers[1:] {
+		if observerChanged(observer, destination.Spec.Template.Spec.Containers[index+1]) {
+			destination.Spec.Template.Spec.Containers[index+1] = observer
+			updated = true
+		}
+	}
+
 	serverImage := source.Spec.Template.Spec.Containers[0].Image
-	if containers[0].Image != serverImage {
+	if destination.Spec.Template.Spec.Containers[0].Image != serverImage {
 		destination.Spec.Template.Spec.Containers[0].Image = serverImage
 		updated = true
 	}
 
-	initContainers := destination.Spec.Template.Spec.InitContainers
-	if len(initContainers) != 1 {
+	if len(destination.Spec.Template.Spec.InitContainers) != 1 {
 		destination.Spec.Template.Spec.InitContainers = source.Spec.Template.Spec.InitContainers
 		updated = true
 	}
 
+	// Read after the replacement, the live deployment may have had no sidecar.
+	initContainers := destination.Spec.Template.Spec.InitContainers
+
+	sidecarResources := source.Spec.Template.Spec.InitContainers[0].Resources
+	if !equality.Semantic.DeepEqual(initContainers[0].Resources, sidecarResources) {
+		destination.Spec.Template.Spec.InitContainers[0].Resources = sidecarResources
+		updated = true
+	}
+
 	sidecarImage := source.Spec.Template.Spec.InitContainers[0].Image
 	if initContainers[0].Image != sidecarImage {
 		destination.Spec.Template.Spec.InitContainers[0].Image = sidecarImage
 		updated = true
 	}
 
+	// The session secret is read from the environment.
+	serverEnv := source.Spec.Template.Spec.Containers[0].Env
+	if !equality.Semantic.DeepEqual(destination.Spec.Template.Spec.Containers[0].Env, serverEnv) {
+		destination.Spec.Template.Spec.Containers[0].Env = serverEnv
+		updated = true
+	}
+
+	// The resources and the GPUs come and go with the server spec.
+	serverResources := source.Spec.Template.Spec.Containers[0].Resources
+	if !equality.Semantic.DeepEqual(destination.Spec.Template.Spec.Containers[0].Resources, serverResources) {
+		destination.Spec.Template.Spec.Containers[0].Resources = serverResources
+		updated = true
+	}
+
+	nodeSelector := source.Spec.Template.Spec.NodeSelector
+	if !equality.Semantic.DeepEqual(destination.Spec.Template.Spec.NodeSelector, nodeSelector) {
+		destination.Spec.Template.Spec.NodeSelector = nodeSelector
+		updated = true
+	}
+
+	tolerations := source.Spec.Template.Spec.Tolerations
+	if !equality.Semantic.DeepEqual(destination.Spec.Template.Spec.Tolerations, tolerations) {
+		destination.Spec.Template.Spec.Tolerations = tolerations
+		updated = true
+	}
+
+	// A new affinity reschedules the server, like the node selector and the tolerations.
+	affinity := source.Spec.Template.Spec.Affinity
+	if !equality.Semantic.DeepEqual(destination.Spec.Template.Spec.Affinity, affinity) {
+		destination.Spec.Template.Spec.Affinity = affinity
+		updated = true
+	}
+
+	pullSecrets := source.Spec.Template.Spec.ImagePullSecrets
+	if !equality.Semantic.DeepEqual(destination.Spec.Template.Spec.ImagePullSecrets, pullSecrets) {
+		destination.Spec.Template.Spec.ImagePullSecrets = pullSecrets
+		updated = true
+	}
+
+	// The session state volume comes and goes with the persistent session.
+	volumes := source.Spec.Template.Spec.Volumes
+	if !equality.Semantic.DeepEqual(destination.Spec.Template.Spec.Volumes, volumes) {
+		destination.Spec.Template.Spec.Volumes = volumes
+		updated = true
+	}
+
+	serverVolumeMounts := source.Spec.Template.Spec.Containers[0].VolumeMounts
+	if !equality.Semantic.DeepEqual(destination.Spec.Template.Spec.Containers[0].VolumeMounts, serverVolumeMounts) {
+		destination.Spec.Template.Spec.Containers[0].VolumeMounts = serverVolumeMounts
+		updated = true
+	}
+
+	// Changing the revision of the session secret restarts the server.
+	revision, ok := source.Spec.Template.Annotations[sessionSecretRevisionAnnotation]
+	if current, found := destination.Spec.Template.Annotations[sessionSecretRevisionAnnotation]; current != revision || found != ok {
+		if ok {
+			if destination.Spec.Template.Annotations == nil {
+				destination.Spec.Template.Annotations = map[string]string{}
+			}
+
+			destination.Spec.Template.Annotations[sessionSecretRevisionAnnotation] = revision
+		} else {
+			delete(destination.Spec.Template.Annotations, sessionSecretRevisionAnnotation)
+		}
+
+		updated = true
+	}
+
 	return updated
 }
 
-func (r *WorkbenchReconciler) deleteDeployments(ctx context.Context, workbench defaultv1alpha1.Workbench) (int, error) {
+// observerChanged tells whether the existing observer container is outdated.
+//
+// The fields defaulted by the API server are left out.
+func observerChanged(source corev1.Container, destination corev1.Container) bool {
+	return source.Name != destination.Name ||
+		source.Image != destination.Image ||
+		!equality.Semantic.DeepEqual(source.Env, destination.Env) ||
+		!equality.Semantic.DeepEqual(source.VolumeMounts, destination.VolumeMounts) ||
+		!equality.Semantic.DeepEqual(source.Resources, destination.Resources)
+}
+
+// checkDeploymentSelector tells whether the selector of the existing deployment is outdated.
+//
+// A deployment selector is immutable, such a deployment cannot be updated, only replaced.
+func checkDeploymentSelector(source appsv1.Deployment, destination appsv1.Deployment, generation int64) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               conditionSelectorMigration,
+		Status:             metav1.ConditionFalse,
+		Reason:             "SelectorUpToDate",
+		Message:            "The server deployment selector is up to date",
+		ObservedGeneration: generation,
+	}
+
+	if !equality.Semantic.DeepEqual(source.Spec.Selector, destination.Spec.Selector) {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = string(eventReasonSelectorChanged)
+		condition.Message = fmt.Sprintf(
+			"The selector of the deployment %q changed, it has to be recreated",
+			destination.Name,
+		)
+	}
+
+	return condition
+}
+
+func (r *WorkbenchReconciler) deleteDeployments(ctx context.Context, workbench defaultv1alpha1.Workbench) ([]string, error) {
 	log := log.FromContext(ctx)
 
 	// Find all the deployments linked with the workbench.
@@ -192,15 +357,16 @@ func (r *WorkbenchReconciler) deleteDeployments(ctx context.Context, workbench d
 	err := r.List(
 		ctx,
 		&deploymentList,
-		client.MatchingLabels{matchingLabel: workbench.Name},
+		client.InNamespace(targetNamespace(workbench)),
+		childSelector(workbench),
 	)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	// Done.
 	if len(deploymentList.Items) == 0 {
-		return 0, nil
+		return nil, nil
 	}
 
 	log.V(1).Info("Delete all deployments")
@@ -208,11 +374,16 @@ func (r *WorkbenchReconciler) deleteDeployments(ctx context.Context, workbench d
 	if err := r.DeleteAllOf(
 		ctx,
 		&appsv1.Deployment{},
-		client.InNamespace(workbench.Namespace),
-		client.MatchingLabels{matchingLabel: workbench.Name},
+		client.InNamespace(targetNamespace(workbench)),
+		childSelector(workbench),
 	); err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	names := make([]string, 0, len(deploymentList.Items))
+	for _, deployment := range deploymentList.Items {
+		names = append(names, deployment.Name)
 	}
 
-	return len(deploymentList.Items), nil
+	return names, nil
 }
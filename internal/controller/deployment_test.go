@@ -0,0 +1,127 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Server resources", func() {
+	config := Config{
+		Server: ServerConfig{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("256Mi"),
+				},
+			},
+		},
+	}
+
+	newWorkbench := func() defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+
+		return workbench
+	}
+
+	It("should give the defaults to the server", func() {
+		deployment := initDeployment(newWorkbench(), config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+
+		server := deployment.Spec.Template.Spec.Containers[0]
+		Expect(server.Resources).To(Equal(config.Server.Resources))
+
+		// The defaults are not shared with the deployments.
+		server.Resources.Requests[corev1.ResourceCPU] = resource.MustParse("1")
+		Expect(config.Server.Resources.Requests.Cpu().String()).To(Equal("100m"))
+
+		socat := deployment.Spec.Template.Spec.InitContainers[0]
+		Expect(socat.Resources.Requests).To(HaveKey(corev1.ResourceMemory))
+		Expect(socat.Resources.Limits).To(HaveKey(corev1.ResourceMemory))
+	})
+
+	It("should let the workbench override the defaults", func() {
+		workbench := newWorkbench()
+		workbench.Spec.Server.Resources = &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+		}
+		workbench.Spec.Server.GPU = &defaultv1alpha1.WorkbenchGPU{Count: 1}
+
+		deployment := initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+
+		server := deployment.Spec.Template.Spec.Containers[0]
+		Expect(server.Resources.Requests).To(BeEmpty())
+		Expect(server.Resources.Limits.Memory().String()).To(Equal("4Gi"))
+		Expect(server.Resources.Limits.Name(defaultGPUResourceName, resource.DecimalSI).Value()).To(Equal(int64(1)))
+
+		// The spec is left alone.
+		Expect(workbench.Spec.Server.Resources.Limits).To(HaveLen(1))
+	})
+
+	It("should roll the server on a resources change", func() {
+		workbench := newWorkbench()
+		deployment := initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+
+		workbench.Spec.Server.Resources = &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+		}
+
+		source := initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+		Expect(updateDeployment(source, &deployment, config.Labels.PropagatedKeys)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Resources).To(Equal(*workbench.Spec.Server.Resources))
+		Expect(updateDeployment(source, &deployment, config.Labels.PropagatedKeys)).To(BeFalse())
+
+		// The sidecars of the older deployments get their resources too.
+		deployment.Spec.Template.Spec.InitContainers[0].Resources = corev1.ResourceRequirements{}
+		Expect(updateDeployment(source, &deployment, config.Labels.PropagatedKeys)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.InitContainers[0].Resources).To(Equal(socatResources))
+	})
+})
+
+var _ = Describe("Server display name", func() {
+	It("should rename the server pod template", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+		workbench.Spec.DisplayName = "My thesis analysis"
+
+		deployment := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+
+		workbench.Spec.DisplayName = "Final analysis"
+		source := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		Expect(updateDeployment(source, &deployment, nil)).To(BeTrue())
+		Expect(deployment.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "Final analysis"))
+		Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "Final analysis"))
+		Expect(updateDeployment(source, &deployment, nil)).To(BeFalse())
+
+		workbench.Spec.DisplayName = ""
+		source = initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		Expect(updateDeployment(source, &deployment, nil)).To(BeTrue())
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey(displayNameAnnotation))
+	})
+})
+
+var _ = Describe("Server containers", func() {
+	It("should restore the containers of a live deployment missing them", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+
+		source := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+
+		deployment := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		deployment.Spec.Template.Spec.InitContainers = nil
+
+		Expect(updateDeployment(source, &deployment, nil)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.InitContainers).To(Equal(source.Spec.Template.Spec.InitContainers))
+		Expect(updateDeployment(source, &deployment, nil)).To(BeFalse())
+
+		deployment.Spec.Template.Spec.Containers = nil
+		Expect(updateDeployment(source, &deployment, nil)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Containers).To(Equal(source.Spec.Template.Spec.Containers))
+	})
+})
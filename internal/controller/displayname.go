@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"strings"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// displayNameAnnotation carries the display name of the workbench, for the support tooling.
+const displayNameAnnotation = "chorus-tre.ch/display-name"
+
+// displayNameAnnotations are the annotations put on the children and their pods.
+//
+// The surrounding spaces are trimmed, an empty display name gives no annotation.
+func displayNameAnnotations(workbench defaultv1alpha1.Workbench) map[string]string {
+	displayName := strings.TrimSpace(workbench.Spec.DisplayName)
+	if displayName == "" {
+		return nil
+	}
+
+	return map[string]string{
+		displayNameAnnotation: displayName,
+	}
+}
+
+// updateDisplayName makes the destination display name annotation like the source one.
+//
+// The job pod templates are immutable, the app pods show the new name once their job is
+// recreated.
+func updateDisplayName(source map[string]string, destination *map[string]string) bool {
+	return updateLabels(source, destination, []string{displayNameAnnotation})
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// maxEffectiveApps bounds the apps reported in the effective configuration, the others are only counted.
+const maxEffectiveApps = 50
+
+// initEffective reads the effective server configuration from the rendered deployment.
+func initEffective(deployment appsv1.Deployment) defaultv1alpha1.WorkbenchStatusEffective {
+	effective := defaultv1alpha1.WorkbenchStatusEffective{}
+
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		effective.ServerImage = containers[0].Image
+		effective.ServerResources = *containers[0].Resources.DeepCopy()
+	}
+
+	if initContainers := deployment.Spec.Template.Spec.InitContainers; len(initContainers) > 0 {
+		effective.SidecarImage = initContainers[0].Image
+	}
+
+	return effective
+}
+
+// addEffectiveApp reads the effective app configuration from the rendered job.
+//
+// The resources are the ones appResources gave to the app container, with the GPUs.
+func addEffectiveApp(effective *defaultv1alpha1.WorkbenchStatusEffective, app defaultv1alpha1.WorkbenchApp, job batchv1.Job) {
+	if len(effective.Apps) >= maxEffectiveApps {
+		effective.OmittedApps++
+		return
+	}
+
+	effectiveApp := defaultv1alpha1.WorkbenchStatusEffectiveApp{
+		Name: app.Name,
+	}
+
+	if containers := job.Spec.Template.Spec.Containers; len(containers) > 0 {
+		effectiveApp.Image = containers[0].Image
+		effectiveApp.Resources = *containers[0].Resources.DeepCopy()
+	}
+
+	effective.Apps = append(effective.Apps, effectiveApp)
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Effective configuration", func() {
+	workbench := defaultv1alpha1.Workbench{}
+	workbench.Name = "workbench"
+	workbench.Namespace = "default"
+
+	apps := AppConfig{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+	}
+
+	It("should report the resources of the rendered containers", func() {
+		server := ServerConfig{
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+		}
+
+		effective := initEffective(initDeployment(workbench, ImagesConfig{}, server, GPUConfig{}, nil))
+		Expect(effective.ServerResources.Limits.Memory().String()).To(Equal("2Gi"))
+
+		app := defaultv1alpha1.WorkbenchApp{Name: "wezterm", GPU: &defaultv1alpha1.WorkbenchGPU{Count: 1}}
+		job, err := initJob(workbench, ImagesConfig{}, apps, GPUConfig{}, nil, 0, app, initService(workbench, nil))
+		Expect(err).NotTo(HaveOccurred())
+
+		addEffectiveApp(&effective, app, *job)
+		Expect(effective.Apps).To(HaveLen(1))
+		Expect(effective.Apps[0].Resources.Requests.Memory().String()).To(Equal("1Gi"))
+		Expect(effective.Apps[0].Resources.Limits.Name(defaultGPUResourceName, resource.DecimalSI).Value()).To(Equal(int64(1)))
+
+		// The status does not share the maps of the job.
+		job.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory] = resource.MustParse("4Gi")
+		Expect(effective.Apps[0].Resources.Requests.Memory().String()).To(Equal("1Gi"))
+	})
+
+	It("should only count the apps past the bound", func() {
+		effective := defaultv1alpha1.WorkbenchStatusEffective{}
+
+		for index := 0; index < maxEffectiveApps+2; index++ {
+			app := defaultv1alpha1.WorkbenchApp{Name: fmt.Sprintf("app%d", index)}
+			job, err := initJob(workbench, ImagesConfig{}, apps, GPUConfig{}, nil, index, app, initService(workbench, nil))
+			Expect(err).NotTo(HaveOccurred())
+
+			addEffectiveApp(&effective, app, *job)
+		}
+
+		Expect(effective.Apps).To(HaveLen(maxEffectiveApps))
+		Expect(effective.OmittedApps).To(Equal(int32(2)))
+	})
+})
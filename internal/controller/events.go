@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// eventReason is the CamelCase, machine readable, cause of an event.
+type eventReason string
+
+// The event reasons, the alerting rules match them: renaming one is a breaking change.
+const (
+	eventReasonImagePullSecretInvalid  eventReason = "ImagePullSecretInvalid"
+	eventReasonCrossNamespaceReference eventReason = "CrossNamespaceReference"
+	eventReasonSelectorChanged         eventReason = "SelectorChanged"
+	eventReasonPodSecurityRestricted   eventReason = "PodSecurityRestricted"
+	eventReasonJobRejected             eventReason = "JobRejected"
+	eventReasonInvalidAppImage         eventReason = "InvalidAppImage"
+	eventReasonGPUUnschedulable        eventReason = "GPUUnschedulable"
+	eventReasonImagePrePulled          eventReason = "ImagePrePulled"
+	eventReasonImagePrePullFailed      eventReason = "ImagePrePullFailed"
+	eventReasonNoRegistryConfigured    eventReason = "NoRegistryConfigured"
+	eventReasonUnknownResourceProfile  eventReason = "UnknownResourceProfile"
+	eventReasonRecreatingDeployment    eventReason = "RecreatingDeployment"
+	eventReasonUpdatingDeployment      eventReason = "UpdatingDeployment"
+	eventReasonDeletingDeployments     eventReason = "DeletingDeployments"
+	eventReasonFinalizationStuck       eventReason = "FinalizationStuck"
+	eventReasonSuspendUnsupported      eventReason = "SuspendUnsupported"
+	eventReasonSuspended               eventReason = "Suspended"
+	eventReasonIdleSuspended           eventReason = "IdleSuspended"
+	eventReasonResumed                 eventReason = "Resumed"
+	eventReasonKilledApp               eventReason = "KilledApp"
+	eventReasonAppRetrying             eventReason = "AppRetrying"
+	eventReasonAppUpdated              eventReason = "AppUpdated"
+	eventReasonAppStarted              eventReason = "AppStarted"
+	eventReasonAppSuspended            eventReason = "AppSuspended"
+	eventReasonAppCompleted            eventReason = "AppCompleted"
+	eventReasonAppFailed               eventReason = "AppFailed"
+	eventReasonAppRemoved              eventReason = "AppRemoved"
+	eventReasonStartupDeadlineExceeded eventReason = "StartupDeadlineExceeded"
+	eventReasonRotatedSessionSecret    eventReason = "RotatedSessionSecret"
+	eventReasonServiceRecreated        eventReason = "ServiceRecreated"
+	eventReasonSyntheticProbeSucceeded eventReason = "SyntheticProbeSucceeded"
+	eventReasonSyntheticProbeFailed    eventReason = "SyntheticProbeFailed"
+)
+
+// emitEvent records an event, it is the only way the controllers emit them.
+//
+// The reason is fixed, the details such as the names go into the message.
+func emitEvent(recorder record.EventRecorder, object runtime.Object, eventType string, reason eventReason, messageFmt string, args ...any) {
+	recorder.Eventf(object, eventType, string(reason), messageFmt, args...)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	It("should all be emitted with a fixed reason", func() {
+		files, err := filepath.Glob("*.go")
+		Expect(err).NotTo(HaveOccurred())
+
+		fset := token.NewFileSet()
+
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") || file == "events.go" {
+				continue
+			}
+
+			node, err := parser.ParseFile(fset, file, nil, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			ast.Inspect(node, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+
+				switch fun := call.Fun.(type) {
+				case *ast.SelectorExpr:
+					// The EventRecorder methods.
+					switch fun.Sel.Name {
+					case "Event", "Eventf", "AnnotatedEventf":
+						Fail(fset.Position(call.Pos()).String() + ": use emitEvent")
+					}
+
+				case *ast.Ident:
+					if fun.Name != "emitEvent" {
+						return true
+					}
+
+					Expect(len(call.Args)).To(BeNumerically(">=", 5))
+					reason, ok := call.Args[3].(*ast.Ident)
+					Expect(ok && strings.HasPrefix(reason.Name, "eventReason")).To(
+						BeTrue(),
+						fset.Position(call.Pos()).String()+": use an eventReason constant",
+					)
+				}
+
+				return true
+			})
+		}
+	})
+})
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// defaultGPUResourceName is the extended resource of the GPUs, when the workbench does not tell.
+const defaultGPUResourceName corev1.ResourceName = "nvidia.com/gpu"
+
+// gpuUnschedulableRequeueAfter is how often the pending GPU pods are looked at again.
+const gpuUnschedulableRequeueAfter = 30 * time.Second
+
+// gpuCard is the DRI device the Xpra server renders with, through the CARD variable.
+const gpuCard = "/dev/dri/card0"
+
+// addGPU requests the GPUs for the container, and places the pod on the GPU nodes.
+//
+// The extended resources cannot be overcommitted, only the limit is set and the
+// scheduler takes it as the request.
+func addGPU(podSpec *corev1.PodSpec, container *corev1.Container, gpu *defaultv1alpha1.WorkbenchGPU, config GPUConfig) {
+	if gpu == nil {
+		return
+	}
+
+	resourceName := gpu.ResourceName
+	if resourceName == "" {
+		resourceName = defaultGPUResourceName
+	}
+
+	if container.Resources.Limits == nil {
+		container.Resources.Limits = corev1.ResourceList{}
+	}
+
+	container.Resources.Limits[resourceName] = *resource.NewQuantity(int64(gpu.Count), resource.DecimalSI)
+
+	for key, value := range config.NodeSelector {
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = map[string]string{}
+		}
+
+		podSpec.NodeSelector[key] = value
+	}
+
+	podSpec.Tolerations = append(podSpec.Tolerations, config.Tolerations...)
+}
+
+// gpuCondition tells whether the pods requesting GPUs could be scheduled.
+//
+// Neither the deployment nor the job report it, their pending pods are looked at.
+func (r *WorkbenchReconciler) gpuCondition(ctx context.Context, workbench defaultv1alpha1.Workbench, gpuJobNames []string) (metav1.Condition, error) {
+	condition := metav1.Condition{
+		Type:               conditionGPUScheduled,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Scheduled",
+		Message:            "All the pods requesting GPUs are scheduled",
+		ObservedGeneration: workbench.Generation,
+	}
+
+	selectors := []client.MatchingLabels{}
+
+	if workbench.Spec.Server.GPU != nil {
+		selectors = append(selectors, childSelector(workbench))
+	}
+
+	for _, name := range gpuJobNames {
+		selectors = append(selectors, client.MatchingLabels{batchv1.JobNameLabel: name})
+	}
+
+	unschedulable := []string{}
+
+	for _, selector := range selectors {
+		pods := corev1.PodList{}
+		if err := r.List(ctx, &pods, client.InNamespace(targetNamespace(workbench)), selector); err != nil {
+			return condition, err
+		}
+
+		for _, pod := range pods.Items {
+			if message, ok := unschedulableMessage(pod); ok {
+				unschedulable = append(unschedulable, fmt.Sprintf("%s: %s", pod.Name, message))
+			}
+		}
+	}
+
+	if len(unschedulable) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = corev1.PodReasonUnschedulable
+		condition.Message = strings.Join(unschedulable, "; ")
+	}
+
+	return condition, nil
+}
+
+// unschedulableMessage returns why the scheduler could not place the pod, if it could not.
+func unschedulableMessage(pod corev1.Pod) (string, bool) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+			return condition.Message, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("GPU", func() {
+	config := Config{
+		GPU: GPUConfig{
+			NodeSelector: map[string]string{"chorus-tre.ch/gpu": "true"},
+			Tolerations: []corev1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists},
+			},
+		},
+	}
+
+	newWorkbench := func() defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+			{Name: "freesurfer", GPU: &defaultv1alpha1.WorkbenchGPU{Count: 1}},
+			{Name: "wezterm"},
+		}
+
+		return workbench
+	}
+
+	It("should request the GPUs of an app", func() {
+		workbench := newWorkbench()
+		service := initService(workbench, config.Labels.PropagatedKeys)
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, workbench.Spec.Apps[0], service)
+		Expect(err).NotTo(HaveOccurred())
+		podSpec := job.Spec.Template.Spec
+
+		Expect(podSpec.Containers[0].Resources.Limits.Name("nvidia.com/gpu", resource.DecimalSI).Value()).To(Equal(int64(1)))
+		Expect(podSpec.NodeSelector).To(Equal(config.GPU.NodeSelector))
+		Expect(podSpec.Tolerations).To(Equal(config.GPU.Tolerations))
+
+		// The apps without GPU stay off the GPU nodes.
+		job, err = initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 1, workbench.Spec.Apps[1], service)
+		Expect(err).NotTo(HaveOccurred())
+		podSpec = job.Spec.Template.Spec
+
+		Expect(podSpec.Containers[0].Resources.Limits).To(BeEmpty())
+		Expect(podSpec.NodeSelector).To(BeEmpty())
+		Expect(podSpec.Tolerations).To(BeEmpty())
+	})
+
+	It("should use the given GPU resource", func() {
+		workbench := newWorkbench()
+		workbench.Spec.Apps[0].GPU.Count = 2
+		workbench.Spec.Apps[0].GPU.ResourceName = "amd.com/gpu"
+
+		job, err := initJob(workbench, ImagesConfig{}, AppConfig{}, GPUConfig{}, nil, 0, workbench.Spec.Apps[0], initService(workbench, nil))
+		Expect(err).NotTo(HaveOccurred())
+
+		limits := job.Spec.Template.Spec.Containers[0].Resources.Limits
+		Expect(limits).To(HaveLen(1))
+		Expect(limits.Name("amd.com/gpu", resource.DecimalSI).Value()).To(Equal(int64(2)))
+	})
+
+	It("should give the GPUs to the server", func() {
+		workbench := newWorkbench()
+
+		deployment := initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+		Expect(deployment.Spec.Template.Spec.Containers[0].Resources.Limits).To(BeEmpty())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "CARD"}))
+
+		workbench.Spec.Server.GPU = &defaultv1alpha1.WorkbenchGPU{Count: 1}
+		withGPU := initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+
+		server := withGPU.Spec.Template.Spec.Containers[0]
+		Expect(server.Resources.Limits).To(HaveKey(defaultGPUResourceName))
+		Expect(server.Env).To(ContainElement(corev1.EnvVar{Name: "CARD", Value: gpuCard}))
+		Expect(withGPU.Spec.Template.Spec.NodeSelector).To(Equal(config.GPU.NodeSelector))
+
+		// The existing server gets, and loses, the GPUs.
+		Expect(updateDeployment(withGPU, &deployment, config.Labels.PropagatedKeys)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec).To(Equal(withGPU.Spec.Template.Spec))
+		Expect(updateDeployment(withGPU, &deployment, config.Labels.PropagatedKeys)).To(BeFalse())
+
+		workbench.Spec.Server.GPU = nil
+		Expect(updateDeployment(initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys), &deployment, config.Labels.PropagatedKeys)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Tolerations).To(BeEmpty())
+	})
+
+	It("should tell the unschedulable pods", func() {
+		pod := corev1.Pod{}
+		_, ok := unschedulableMessage(pod)
+		Expect(ok).To(BeFalse())
+
+		pod.Status.Conditions = []corev1.PodCondition{
+			{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionFalse,
+				Reason:  corev1.PodReasonUnschedulable,
+				Message: "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.",
+			},
+		}
+
+		message, ok := unschedulableMessage(pod)
+		Expect(ok).To(BeTrue())
+		Expect(message).To(ContainSubstring("Insufficient nvidia.com/gpu"))
+	})
+
+	Context("When the GPU pods cannot be scheduled", func() {
+		const resourceName = "gpu-unschedulable"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		var pod *corev1.Pod
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "freesurfer", GPU: &defaultv1alpha1.WorkbenchGPU{Count: 1}},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+
+			// There is no job controller nor scheduler in envtest.
+			pod = &corev1.Pod{}
+			pod.Name = resourceName + "-0-freesurfer-abcde"
+			pod.Namespace = "default"
+			pod.Labels = map[string]string{batchv1.JobNameLabel: resourceName + "-0-freesurfer"}
+			pod.Spec.Containers = []corev1.Container{{Name: "freesurfer", Image: "freesurfer"}}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+			pod.Status.Conditions = []corev1.PodCondition{
+				{
+					Type:    corev1.PodScheduled,
+					Status:  corev1.ConditionFalse,
+					Reason:  corev1.PodReasonUnschedulable,
+					Message: "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.",
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			// There is no garbage collection in envtest.
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should report them in the status", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			var result reconcile.Result
+			for range 2 {
+				result = reconcileOnce(controllerReconciler, typeNamespacedName)
+			}
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: pod.Labels[batchv1.JobNameLabel], Namespace: "default"}, job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.Containers[0].Resources.Limits.Name(defaultGPUResourceName, resource.DecimalSI).Value()).To(Equal(int64(1)))
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+			condition := meta.FindStatusCondition(workbench.Status.Conditions, conditionGPUScheduled)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("Insufficient nvidia.com/gpu"))
+
+			// Checked again, as nothing notifies the scheduling.
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", gpuUnschedulableRequeueAfter))
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// cacheSyncCheckTimeout bounds the wait of a readiness check, the kubelet has its own timeout.
+const cacheSyncCheckTimeout = time.Second
+
+// CacheSyncChecker is ready once the informer caches are synced.
+func CacheSyncChecker(informers cache.Informers) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), cacheSyncCheckTimeout)
+		defer cancel()
+
+		if !informers.WaitForCacheSync(ctx) {
+			return errors.New("informer caches are not synced yet")
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"context"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// syncedInformers fakes the cache sync state.
+type syncedInformers struct {
+	cache.Informers
+
+	synced bool
+}
+
+func (s syncedInformers) WaitForCacheSync(ctx context.Context) bool {
+	if !s.synced {
+		<-ctx.Done()
+	}
+
+	return s.synced
+}
+
+var _ = Describe("Readiness", func() {
+	It("should wait for the informer caches", func() {
+		req := httptest.NewRequest("GET", "/readyz", nil)
+
+		Expect(CacheSyncChecker(syncedInformers{synced: false})(req)).NotTo(Succeed())
+		Expect(CacheSyncChecker(syncedInformers{synced: true})(req)).To(Succeed())
+	})
+})
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// maxAppHistoryRecords bounds the size of the history ConfigMap, the oldest records go first.
+const maxAppHistoryRecords = 100
+
+// appHistoryRecord is the compact completion record of an app job.
+//
+// The exit code is only known while the pod is there, the requests are the ones of the job.
+type appHistoryRecord struct {
+	App             string                                   `json:"app"`
+	Job             string                                   `json:"job"`
+	Status          defaultv1alpha1.WorkbenchStatusAppStatus `json:"status"`
+	Reason          string                                   `json:"reason,omitempty"`
+	ExitCode        *int32                                   `json:"exitCode,omitempty"`
+	StartTime       *metav1.Time                             `json:"startTime,omitempty"`
+	CompletionTime  *metav1.Time                             `json:"completionTime,omitempty"`
+	DurationSeconds int64                                    `json:"durationSeconds,omitempty"`
+	Requests        corev1.ResourceList                      `json:"requests,omitempty"`
+}
+
+// initAppHistory creates the ConfigMap holding the completion records of the apps.
+func initAppHistory(workbench defaultv1alpha1.Workbench, labelKeys []string) corev1.ConfigMap {
+	configMap := corev1.ConfigMap{}
+	configMap.Name = fmt.Sprintf("%s-app-history", workbench.Name)
+	configMap.Namespace = targetNamespace(workbench)
+
+	configMap.Labels = childLabels(workbench, labelKeys)
+
+	configMap.Data = map[string]string{}
+
+	return configMap
+}
+
+// newAppHistoryRecord builds the record of a finished job, or nil if it's still going.
+func newAppHistoryRecord(app defaultv1alpha1.WorkbenchApp, job batchv1.Job, pods []corev1.Pod) *appHistoryRecord {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		record := &appHistoryRecord{
+			App:       app.Name,
+			Job:       job.Name,
+			Reason:    condition.Reason,
+			StartTime: job.Status.StartTime,
+		}
+
+		switch condition.Type {
+		case batchv1.JobComplete:
+			record.Status = defaultv1alpha1.WorkbenchStatusAppStatusComplete
+			record.CompletionTime = job.Status.CompletionTime
+		case batchv1.JobFailed:
+			record.Status = defaultv1alpha1.WorkbenchStatusAppStatusFailed
+		default:
+			continue
+		}
+
+		if record.CompletionTime == nil {
+			completionTime := condition.LastTransitionTime
+			record.CompletionTime = &completionTime
+		}
+
+		if record.StartTime != nil {
+			record.DurationSeconds = int64(record.CompletionTime.Sub(record.StartTime.Time).Seconds())
+		}
+
+		record.ExitCode = appExitCode(app, pods)
+
+		for _, container := range job.Spec.Template.Spec.Containers {
+			if container.Name == app.Name {
+				record.Requests = container.Resources.Requests
+			}
+		}
+
+		return record
+	}
+
+	return nil
+}
+
+// appExitCode is the exit code of the app container of the newest pod, once terminated.
+func appExitCode(app defaultv1alpha1.WorkbenchApp, pods []corev1.Pod) *int32 {
+	newest := newestPod(pods)
+	if newest == nil {
+		return nil
+	}
+
+	for _, status := range newest.Status.ContainerStatuses {
+		if status.Name == app.Name && status.State.Terminated != nil {
+			exitCode := status.State.Terminated.ExitCode
+			return &exitCode
+		}
+	}
+
+	return nil
+}
+
+// recordAppHistory writes the completion record of the job, exactly once per job UID.
+func (r *WorkbenchReconciler) recordAppHistory(ctx context.Context, workbench *defaultv1alpha1.Workbench, app defaultv1alpha1.WorkbenchApp, job batchv1.Job, pods []corev1.Pod) error {
+	log := log.FromContext(ctx)
+
+	record := newAppHistoryRecord(app, job, pods)
+	if record == nil {
+		return nil
+	}
+
+	configMap := initAppHistory(*workbench, r.Config.Labels.PropagatedKeys)
+
+	foundConfigMap := corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: configMap.Name, Namespace: configMap.Namespace}, &foundConfigMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		if err := setOwner(workbench, &configMap, r.Scheme); err != nil {
+			return err
+		}
+
+		if err := r.Create(ctx, &configMap); err != nil {
+			return err
+		}
+
+		foundConfigMap = configMap
+	}
+
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if foundConfigMap.Data == nil {
+		foundConfigMap.Data = map[string]string{}
+	}
+
+	if !addAppHistoryRecord(foundConfigMap.Data, string(job.UID), string(value), maxAppHistoryRecords) {
+		return nil
+	}
+
+	updateLabels(configMap.Labels, &foundConfigMap.Labels, r.Config.Labels.PropagatedKeys)
+
+	log.V(1).Info("Recording the app history", "job", job.Name)
+
+	return r.Update(ctx, &foundConfigMap)
+}
+
+// addAppHistoryRecord adds the record of the job UID and prunes the history, and tells whether
+// the record was kept.
+//
+// The finished jobs stay around for their TTL, a record already pruned, older than all the kept
+// ones, would otherwise come back and go again on every pass.
+func addAppHistoryRecord(data map[string]string, key string, value string, max int) bool {
+	if _, ok := data[key]; ok {
+		return false
+	}
+
+	data[key] = value
+
+	pruneAppHistory(data, max)
+
+	_, ok := data[key]
+
+	return ok
+}
+
+// pruneAppHistory removes the oldest records until at most max are left, the same completion
+// time ordered by key.
+func pruneAppHistory(data map[string]string, max int) {
+	if len(data) <= max {
+		return
+	}
+
+	type entry struct {
+		key  string
+		time metav1.Time
+	}
+
+	entries := make([]entry, 0, len(data))
+	for key, value := range data {
+		record := appHistoryRecord{}
+		// Unreadable records are considered the oldest ones.
+		_ = json.Unmarshal([]byte(value), &record)
+
+		e := entry{key: key}
+		if record.CompletionTime != nil {
+			e.time = *record.CompletionTime
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].time.Equal(&entries[j].time) {
+			return entries[i].key < entries[j].key
+		}
+
+		return entries[i].time.Before(&entries[j].time)
+	})
+
+	for _, e := range entries[:len(entries)-max] {
+		delete(data, e.key)
+	}
+}
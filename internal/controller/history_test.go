@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("App history", func() {
+	app := defaultv1alpha1.WorkbenchApp{Name: "wezterm"}
+
+	newJob := func() batchv1.Job {
+		start := metav1.NewTime(time.Unix(1000, 0))
+
+		job := batchv1.Job{}
+		job.Name = "workbench-0-wezterm"
+		job.Status.StartTime = &start
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", LastTransitionTime: metav1.NewTime(start.Add(time.Minute))},
+		}
+		job.Spec.Template.Spec.Containers = []corev1.Container{
+			{
+				Name: "wezterm",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+				},
+			},
+		}
+
+		return job
+	}
+
+	It("should record the exit code of the app container, and the requests of the job", func() {
+		older := corev1.Pod{}
+		older.CreationTimestamp = metav1.Unix(1000, 0)
+		older.Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: "wezterm", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}},
+		}
+
+		newer := corev1.Pod{}
+		newer.CreationTimestamp = metav1.Unix(1030, 0)
+		newer.Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: "sidecar", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}},
+			{Name: "wezterm", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137}}},
+		}
+
+		record := newAppHistoryRecord(app, newJob(), []corev1.Pod{older, newer})
+		Expect(record).NotTo(BeNil())
+		Expect(record.Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusFailed))
+		Expect(record.Reason).To(Equal("BackoffLimitExceeded"))
+		Expect(record.DurationSeconds).To(Equal(int64(60)))
+		Expect(record.ExitCode).To(HaveValue(Equal(int32(137))))
+		Expect(record.Requests.Memory().String()).To(Equal("2Gi"))
+	})
+
+	It("should leave out the exit code of the pods already gone, or still running", func() {
+		Expect(newAppHistoryRecord(app, newJob(), nil).ExitCode).To(BeNil())
+
+		running := corev1.Pod{}
+		running.Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: "wezterm", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+		}
+		Expect(newAppHistoryRecord(app, newJob(), []corev1.Pod{running}).ExitCode).To(BeNil())
+
+		job := newJob()
+		job.Status.Conditions = nil
+		Expect(newAppHistoryRecord(app, job, nil)).To(BeNil())
+	})
+
+	It("should not bring back the records already pruned", func() {
+		recordAt := func(minutes int) string {
+			completionTime := metav1.NewTime(time.Unix(1000, 0).Add(time.Duration(minutes) * time.Minute))
+
+			value, err := json.Marshal(appHistoryRecord{App: "wezterm", CompletionTime: &completionTime})
+			Expect(err).NotTo(HaveOccurred())
+
+			return string(value)
+		}
+
+		data := map[string]string{}
+		for index := 1; index <= 3; index++ {
+			Expect(addAppHistoryRecord(data, fmt.Sprintf("uid-%d", index), recordAt(index), 3)).To(BeTrue())
+		}
+
+		// Already there.
+		Expect(addAppHistoryRecord(data, "uid-2", recordAt(2), 3)).To(BeFalse())
+
+		// The newer one pushes the oldest out, which is then left out on the next passes.
+		Expect(addAppHistoryRecord(data, "uid-4", recordAt(4), 3)).To(BeTrue())
+		Expect(data).NotTo(HaveKey("uid-1"))
+
+		Expect(addAppHistoryRecord(data, "uid-1", recordAt(1), 3)).To(BeFalse())
+		Expect(data).To(HaveLen(3))
+		Expect(data).NotTo(HaveKey("uid-1"))
+	})
+})
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// lastActivityAnnotation is put on its pod by the Xpra server, when a client was last active,
+// as an RFC 3339 time.
+const lastActivityAnnotation = "chorus-tre.ch/last-activity"
+
+// wakeAnnotation is put on the workbench to resume it after an idle suspension.
+const wakeAnnotation = "chorus-tre.ch/wake"
+
+// idleReason is the reason of the Suspended condition of an idle workbench.
+const idleReason = "IdleTimeout"
+
+// serverActivity is when the server pods were last seen active, either as told by the server
+// or when they became ready.
+func serverActivity(pods []corev1.Pod) metav1.Time {
+	activity := metav1.Time{}
+
+	for _, pod := range pods {
+		if value, ok := pod.Annotations[lastActivityAnnotation]; ok {
+			if last, err := time.Parse(time.RFC3339, value); err == nil && activity.Time.Before(last) {
+				activity = metav1.NewTime(last)
+			}
+		}
+
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue && activity.Before(&condition.LastTransitionTime) {
+				activity = condition.LastTransitionTime
+			}
+		}
+	}
+
+	return activity
+}
+
+// idleRemaining tells how long the server may still stay idle, when it has an idle timeout.
+func idleRemaining(workbench defaultv1alpha1.Workbench, now time.Time) (time.Duration, bool) {
+	timeout := workbench.Spec.Server.IdleTimeout
+	if timeout == nil || timeout.Duration <= 0 || workbench.Status.Server.LastActivity == nil {
+		return 0, false
+	}
+
+	return max(workbench.Status.Server.LastActivity.Add(timeout.Duration).Sub(now), 0), true
+}
+
+// isWorkbenchSuspended tells whether the workbench is scaled to zero, either as requested or
+// after being idle.
+func isWorkbenchSuspended(workbench defaultv1alpha1.Workbench) bool {
+	return workbench.Spec.Suspended || meta.IsStatusConditionTrue(workbench.Status.Conditions, conditionSuspended)
+}
+
+// isResumed tells whether the suspension of the workbench is over.
+//
+// The one requested lasts as long as the spec says, an idle one until the next spec change
+// or the wake annotation.
+func isResumed(workbench defaultv1alpha1.Workbench, condition metav1.Condition) bool {
+	if condition.Reason != idleReason {
+		return !workbench.Spec.Suspended
+	}
+
+	return workbench.Annotations[wakeAnnotation] == "true" || condition.ObservedGeneration < workbench.Generation
+}
+
+// idleCondition tells that the workbench is scaled to zero, as its server was idle.
+func idleCondition(workbench defaultv1alpha1.Workbench) metav1.Condition {
+	return metav1.Condition{
+		Type:   conditionSuspended,
+		Status: metav1.ConditionTrue,
+		Reason: idleReason,
+		Message: fmt.Sprintf(
+			"The server was idle for %s, it's scaled to zero and the apps are suspended until woken up",
+			workbench.Spec.Server.IdleTimeout.Duration,
+		),
+		ObservedGeneration: workbench.Generation,
+	}
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Idle timeout", func() {
+	newWorkbench := func(timeout time.Duration, lastActivity time.Time) defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Generation = 2
+		workbench.Spec.Server.IdleTimeout = &metav1.Duration{Duration: timeout}
+		workbench.Status.Server.LastActivity = &metav1.Time{Time: lastActivity}
+
+		return workbench
+	}
+
+	It("should tell how long the server may still be idle", func() {
+		now := time.Unix(10000, 0)
+
+		remaining, ok := idleRemaining(newWorkbench(time.Hour, now.Add(-20*time.Minute)), now)
+		Expect(ok).To(BeTrue())
+		Expect(remaining).To(Equal(40 * time.Minute))
+
+		remaining, ok = idleRemaining(newWorkbench(time.Hour, now.Add(-2*time.Hour)), now)
+		Expect(ok).To(BeTrue())
+		Expect(remaining).To(BeZero())
+
+		workbench := newWorkbench(time.Hour, now)
+		workbench.Spec.Server.IdleTimeout = nil
+		_, ok = idleRemaining(workbench, now)
+		Expect(ok).To(BeFalse())
+
+		workbench = newWorkbench(time.Hour, now)
+		workbench.Status.Server.LastActivity = nil
+		_, ok = idleRemaining(workbench, now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should read the activity from the annotation and the readiness", func() {
+		Expect(serverActivity(nil).Time.IsZero()).To(BeTrue())
+
+		told := corev1.Pod{}
+		told.Annotations = map[string]string{lastActivityAnnotation: "2026-01-01T10:00:00Z"}
+
+		ready := corev1.Pod{}
+		ready.Status.Conditions = []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)},
+		}
+
+		Expect(serverActivity([]corev1.Pod{told, ready}).Time).To(Equal(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)))
+
+		ready.Status.Conditions[0].LastTransitionTime = metav1.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)
+		Expect(serverActivity([]corev1.Pod{told, ready}).Time).To(Equal(time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)))
+
+		// A garbled annotation or a pod not ready tell nothing.
+		told.Annotations[lastActivityAnnotation] = "yesterday"
+		ready.Status.Conditions[0].Status = corev1.ConditionFalse
+		Expect(serverActivity([]corev1.Pod{told, ready}).Time.IsZero()).To(BeTrue())
+	})
+
+	It("should resume an idle workbench on a spec change or when woken up", func() {
+		workbench := newWorkbench(time.Hour, time.Unix(0, 0))
+
+		condition := idleCondition(workbench)
+		Expect(isWorkbenchSuspended(workbench)).To(BeFalse())
+		workbench.Status.Conditions = []metav1.Condition{condition}
+		Expect(isWorkbenchSuspended(workbench)).To(BeTrue())
+		Expect(isResumed(workbench, condition)).To(BeFalse())
+
+		workbench.Generation = 3
+		Expect(isResumed(workbench, condition)).To(BeTrue())
+
+		workbench.Generation = 2
+		workbench.Annotations = map[string]string{wakeAnnotation: "true"}
+		Expect(isResumed(workbench, condition)).To(BeTrue())
+
+		// The requested suspension lasts as long as the spec says.
+		workbench.Spec.Suspended = true
+		Expect(isResumed(workbench, suspendedCondition(workbench))).To(BeFalse())
+	})
+
+	It("should stop the running apps of an idle workbench", func() {
+		workbench := newWorkbench(time.Hour, time.Unix(0, 0))
+		workbench.Status.Conditions = []metav1.Condition{idleCondition(workbench)}
+
+		app := defaultv1alpha1.WorkbenchApp{Name: "wezterm"}
+		Expect(suspendedApp(workbench, app).State).To(Equal(defaultv1alpha1.WorkbenchAppStateStopped))
+		Expect(*initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil).Spec.Replicas).To(Equal(int32(0)))
+	})
+})
@@ -2,8 +2,13 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"regexp"
+	"slices"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -13,18 +18,144 @@ import (
 	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
 )
 
-func initJob(workbench defaultv1alpha1.Workbench, config Config, index int, app defaultv1alpha1.WorkbenchApp, service corev1.Service) *batchv1.Job {
-	job := &batchv1.Job{}
+// noRegistryConfiguredMessage explains, in the app status, why an app without an image has no job.
+const noRegistryConfiguredMessage = "NoRegistryConfigured: the app has no image and the operator has neither --registry nor --apps-repository"
+
+// lacksRegistry tells whether the job would run an image name only resolvable on the Docker Hub.
+//
+// The stopped apps do not pull anything, they are left alone.
+func lacksRegistry(config ImagesConfig, app defaultv1alpha1.WorkbenchApp, job batchv1.Job) bool {
+	return app.Image == nil && !config.HasAppsRegistry() && !isSuspended(job)
+}
+
+// unknownResourceProfileMessage explains, in the app status, why an app naming a missing resource
+// profile has no job.
+const unknownResourceProfileMessage = "UnknownResourceProfile: the resource profile %q is not one of the operator ones %v"
+
+// unknownResourceProfile tells whether the app names a resource profile the operator lacks.
+//
+// The admission rejects them, but the operator may have been redeployed with fewer profiles.
+func unknownResourceProfile(config AppConfig, app defaultv1alpha1.WorkbenchApp, job batchv1.Job) bool {
+	if app.ResourceProfile == "" || isSuspended(job) {
+		return false
+	}
+
+	_, ok := config.ResourceProfiles[app.ResourceProfile]
 
-	// The name of the app is there for human consumption.
-	job.Name = fmt.Sprintf("%s-%d-%s", workbench.Name, index, app.Name)
-	job.Namespace = workbench.Namespace
+	return !ok
+}
+
+// defaultJobTTLAfterFinished is how long the finished jobs are kept, unless configured.
+const defaultJobTTLAfterFinished = 24 * time.Hour
 
-	labels := map[string]string{
-		matchingLabel: workbench.Name,
+// jobTTLAfterFinished is how long the job of the app is kept once finished, in seconds.
+func jobTTLAfterFinished(config AppConfig, app defaultv1alpha1.WorkbenchApp) *int32 {
+	if app.JobTTLSecondsAfterFinished != nil {
+		seconds := *app.JobTTLSecondsAfterFinished
+		return &seconds
 	}
 
-	job.Labels = labels
+	ttl := config.JobTTLAfterFinished
+	if ttl == 0 {
+		ttl = defaultJobTTLAfterFinished
+	}
+
+	seconds := int32(ttl / time.Second)
+
+	return &seconds
+}
+
+// addExtraMounts mounts the ConfigMaps and Secrets of the app into its container.
+func addExtraMounts(podSpec *corev1.PodSpec, container *corev1.Container, mounts []defaultv1alpha1.AppMount) {
+	for index, mount := range mounts {
+		volume := corev1.Volume{Name: fmt.Sprintf("extra-mount-%d", index)}
+
+		if mount.ConfigMap != "" {
+			volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: mount.ConfigMap},
+			}
+		} else {
+			volume.Secret = &corev1.SecretVolumeSource{SecretName: mount.Secret}
+		}
+
+		podSpec.Volumes = append(podSpec.Volumes, volume)
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: mount.MountPath,
+			SubPath:   mount.SubPath,
+			ReadOnly:  mount.ReadOnly,
+		})
+	}
+}
+
+// appResources are the resources of the app container.
+//
+// The app ones replace the profile, or the defaults, as a whole: a limit alone is not mixed
+// with a default request.
+func appResources(config AppConfig, app defaultv1alpha1.WorkbenchApp) corev1.ResourceRequirements {
+	if app.Resources != nil {
+		return *app.Resources.DeepCopy()
+	}
+
+	if profile, ok := config.ResourceProfiles[app.ResourceProfile]; ok && app.ResourceProfile != "" {
+		return *profile.DeepCopy()
+	}
+
+	return *config.Resources.DeepCopy()
+}
+
+// appImagePattern is the grammar of the image references, an optional registry host, the
+// lowercase path components, and the tag.
+// See https://github.com/distribution/reference/blob/main/reference.go
+var appImagePattern = regexp.MustCompile(
+	`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`:[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`,
+)
+
+// validateAppImage rejects the image references the kubelet would only refuse when pulling.
+//
+// E.g. an app named with capitals, which the CRD allows, without an image override.
+func validateAppImage(image string) error {
+	if !appImagePattern.MatchString(image) {
+		return fmt.Errorf("invalid image reference %q", image)
+	}
+
+	return nil
+}
+
+// jobName is the name of the job of the app, it's there for human consumption.
+func jobName(workbench defaultv1alpha1.Workbench, index int, app defaultv1alpha1.WorkbenchApp) string {
+	return fmt.Sprintf("%s-%d-%s", workbench.Name, index, app.Name)
+}
+
+// sidecarSecurityContext is the security context of the sidecars: unprivileged, non root,
+// without any capability.
+func sidecarSecurityContext() *corev1.SecurityContext {
+	fal := false
+	tru := true
+
+	return &corev1.SecurityContext{
+		Privileged:               &fal,
+		AllowPrivilegeEscalation: &fal,
+		RunAsNonRoot:             &tru,
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}
+}
+
+// initJob creates the job of the app, given the areas of the configuration it uses.
+func initJob(workbench defaultv1alpha1.Workbench, images ImagesConfig, apps AppConfig, gpu GPUConfig, labelKeys []string, index int, app defaultv1alpha1.WorkbenchApp, service corev1.Service) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+
+	job.Name = jobName(workbench, index, app)
+	job.Namespace = targetNamespace(workbench)
+
+	job.Labels = appLabels(workbench, labelKeys, index, app)
+
+	job.Annotations = displayNameAnnotations(workbench)
+	job.Spec.Template.Annotations = displayNameAnnotations(workbench)
 
 	var shmDir *corev1.Volume
 	if app.ShmSize != nil {
@@ -41,10 +172,9 @@ func initJob(workbench defaultv1alpha1.Workbench, config Config, index int, app
 		job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, *shmDir)
 	}
 
-	// The pod will be cleaned up after a day.
+	// The pod will be cleaned up after a while, a day by default.
 	// https://kubernetes.io/docs/concepts/workloads/controllers/job/#ttl-mechanism-for-finished-jobs
-	oneDay := int32(24 * 3600)
-	job.Spec.TTLSecondsAfterFinished = &oneDay
+	job.Spec.TTLSecondsAfterFinished = jobTTLAfterFinished(apps, app)
 
 	// Service account is an alternative to the image Pull Secrets
 	serviceAccountName := workbench.Spec.ServiceAccount
@@ -64,14 +194,14 @@ func initJob(workbench defaultv1alpha1.Workbench, config Config, index int, app
 		}
 
 		// Non-empty registry requires a / to concatenate with the app one.
-		registry := config.Registry
+		registry := images.Registry
 		if registry != "" {
-			registry = strings.TrimRight(registry, "/") + "/"
+			registry += "/"
 		}
 
-		appsRepository := config.AppsRepository
+		appsRepository := images.AppsRepository
 		if appsRepository != "" {
-			appsRepository = strings.Trim(appsRepository, "/") + "/"
+			appsRepository += "/"
 		}
 
 		appImage = fmt.Sprintf("%s%s%s:%s", registry, appsRepository, app.Name, appVersion)
@@ -86,6 +216,10 @@ func initJob(workbench defaultv1alpha1.Workbench, config Config, index int, app
 		appImage = fmt.Sprintf("%s/%s:%s", app.Image.Registry, app.Image.Repository, appVersion)
 	}
 
+	if err := validateAppImage(appImage); err != nil {
+		return nil, err
+	}
+
 	appContainer := corev1.Container{
 		Name:            app.Name,
 		Image:           appImage,
@@ -98,24 +232,60 @@ func initJob(workbench defaultv1alpha1.Workbench, config Config, index int, app
 		},
 	}
 
+	// The sidecars get them too, with the rest of the app environment.
+	appContainer.Env = append(appContainer.Env, provenanceEnv(workbench)...)
+
 	// Mounting the /dev/shm volume.
 	if shmDir != nil {
 		appContainer.VolumeMounts = append(appContainer.VolumeMounts, corev1.VolumeMount{
 			Name:      shmDir.Name,
-			MountPath: "/dev/shm",
+			MountPath: defaultv1alpha1.ShmMountPath,
 		})
 	}
 
+	appContainer.Resources = appResources(apps, app)
+
+	addScheduling(&job.Spec.Template.Spec, workbench, &app)
+	addGPU(&job.Spec.Template.Spec, &appContainer, app.GPU, gpu)
+
 	job.Spec.Template.Spec.Containers = []corev1.Container{
 		appContainer,
 	}
 
-	for _, imagePullSecret := range workbench.Spec.ImagePullSecrets {
-		job.Spec.Template.Spec.ImagePullSecrets = append(job.Spec.Template.Spec.ImagePullSecrets, corev1.LocalObjectReference{
-			Name: imagePullSecret,
-		})
+	// As of Kubernetes 1.29, initContainer + restartPolicy: Always is the right way to
+	// do sidecar containers, the job completion only depends on the app container.
+	// https://kubernetes.io/docs/concepts/workloads/pods/sidecar-containers/
+	always := corev1.ContainerRestartPolicyAlways
+
+	for _, sidecar := range app.SidecarContainers {
+		sidecarContainer := *sidecar.DeepCopy()
+
+		// Same environment and mounts as the app.
+		sidecarContainer.Env = append(sidecarContainer.Env, appContainer.Env...)
+		sidecarContainer.VolumeMounts = append(sidecarContainer.VolumeMounts, appContainer.VolumeMounts...)
+
+		if sidecarContainer.ImagePullPolicy == "" {
+			sidecarContainer.ImagePullPolicy = corev1.PullIfNotPresent
+		}
+
+		// Whatever the workbench tells, the sidecars are not given more than the app.
+		sidecarContainer.SecurityContext = sidecarSecurityContext()
+
+		if apps.DisableNativeSidecars {
+			// Plain containers keep the pod alive, they are only a fallback.
+			sidecarContainer.RestartPolicy = nil
+			job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, sidecarContainer)
+		} else {
+			sidecarContainer.RestartPolicy = &always
+			job.Spec.Template.Spec.InitContainers = append(job.Spec.Template.Spec.InitContainers, sidecarContainer)
+		}
 	}
 
+	// After the sidecars, they are only for the app.
+	addExtraMounts(&job.Spec.Template.Spec, &job.Spec.Template.Spec.Containers[0], app.ExtraMounts)
+
+	job.Spec.Template.Spec.ImagePullSecrets = imagePullSecrets(workbench, images)
+
 	// Hide the pod name in favour of the app name.
 	job.Spec.Template.Spec.Hostname = app.Name
 
@@ -135,14 +305,71 @@ func initJob(workbench defaultv1alpha1.Workbench, config Config, index int, app
 		job.Spec.Suspend = &fal
 	}
 
-	return job
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+
+	job.Annotations[appSpecHashAnnotation] = appSpecHash(job.Spec.Template.Spec)
+
+	return job, nil
+}
+
+// appSpecHashAnnotation holds the hash of what, in the pod template of the job, is the app.
+const appSpecHashAnnotation = "chorus-tre.ch/app-spec-hash"
+
+// appSpecHash sums up the images and resources of the containers.
+//
+// The rest of the pod template may change with the operator, it must not restart the apps.
+func appSpecHash(podSpec corev1.PodSpec) string {
+	type containerSpec struct {
+		Name      string                      `json:"name"`
+		Image     string                      `json:"image"`
+		Resources corev1.ResourceRequirements `json:"resources"`
+	}
+
+	containers := []containerSpec{}
+	for _, container := range append(slices.Clone(podSpec.InitContainers), podSpec.Containers...) {
+		containers = append(containers, containerSpec{
+			Name:      container.Name,
+			Image:     container.Image,
+			Resources: container.Resources,
+		})
+	}
+
+	// Marshalling a slice of plain structs and quantities does not fail.
+	data, _ := json.Marshal(containers)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// jobOutdated tells whether the existing job runs another app than the desired one, e.g.
+// another version. Its pod template is immutable, it has to be recreated.
+//
+// The jobs created before the hash only have their app image compared.
+func jobOutdated(desired batchv1.Job, existing batchv1.Job) bool {
+	if hash, ok := existing.Annotations[appSpecHashAnnotation]; ok {
+		return hash != desired.Annotations[appSpecHashAnnotation]
+	}
+
+	desiredContainers := desired.Spec.Template.Spec.Containers
+	existingContainers := existing.Spec.Template.Spec.Containers
+
+	return len(desiredContainers) > 0 && len(existingContainers) > 0 &&
+		desiredContainers[0].Image != existingContainers[0].Image
 }
 
 // updateJob  makes the destination batch Job (app), like the source one.
 //
-// It's not allowed to modify the Job definition outside of suspending it.
-func updateJob(source batchv1.Job, destination *batchv1.Job) bool {
-	updated := false
+// It's not allowed to modify the Job definition outside of suspending it, its TTL, its labels and
+// annotations. The app label is synced too, for the jobs created before it.
+func updateJob(source batchv1.Job, destination *batchv1.Job, labelKeys []string) bool {
+	keys := append(slices.Clone(labelKeys), appUIDLabel)
+	updated := updateLabels(source.Labels, &destination.Labels, keys)
+
+	if updateDisplayName(source.Annotations, &destination.Annotations) {
+		updated = true
+	}
 
 	suspend := source.Spec.Suspend
 	if suspend != nil && (destination.Spec.Suspend == nil || *destination.Spec.Suspend != *suspend) {
@@ -150,6 +377,13 @@ func updateJob(source batchv1.Job, destination *batchv1.Job) bool {
 		updated = true
 	}
 
+	// The finished jobs too, they are kept as long as told now.
+	ttl := source.Spec.TTLSecondsAfterFinished
+	if ttl != nil && (destination.Spec.TTLSecondsAfterFinished == nil || *destination.Spec.TTLSecondsAfterFinished != *ttl) {
+		destination.Spec.TTLSecondsAfterFinished = ttl
+		updated = true
+	}
+
 	return updated
 }
 
@@ -159,9 +393,8 @@ func (r *WorkbenchReconciler) findJobs(ctx context.Context, workbench defaultv1a
 	err := r.List(
 		ctx,
 		&jobList,
-		client.MatchingLabels{
-			matchingLabel: workbench.Name,
-		},
+		client.InNamespace(targetNamespace(workbench)),
+		childSelector(workbench),
 	)
 
 	return &jobList, err
@@ -180,19 +413,19 @@ func (r *WorkbenchReconciler) deleteJob(ctx context.Context, job *batchv1.Job) e
 	)
 }
 
-// Delete all the jobs of the given workbench.
-func (r *WorkbenchReconciler) deleteJobs(ctx context.Context, workbench defaultv1alpha1.Workbench) (int, error) {
+// Delete all the jobs of the given workbench, returning the names of the ones found.
+func (r *WorkbenchReconciler) deleteJobs(ctx context.Context, workbench defaultv1alpha1.Workbench) ([]string, error) {
 	log := log.FromContext(ctx)
 
 	// Find all the jobs linked with the workbench.
 	jobList, err := r.findJobs(ctx, workbench)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	// Done.
 	if len(jobList.Items) == 0 {
-		return 0, nil
+		return nil, nil
 	}
 
 	log.V(1).Info("Delete all jobs")
@@ -200,12 +433,17 @@ func (r *WorkbenchReconciler) deleteJobs(ctx context.Context, workbench defaultv
 	if err := r.DeleteAllOf(
 		ctx,
 		&batchv1.Job{},
-		client.InNamespace(workbench.Namespace),
+		client.InNamespace(targetNamespace(workbench)),
 		client.PropagationPolicy("Background"),
-		client.MatchingLabels{matchingLabel: workbench.Name},
+		childSelector(workbench),
 	); err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	names := make([]string, 0, len(jobList.Items))
+	for _, job := range jobList.Items {
+		names = append(names, job.Name)
 	}
 
-	return len(jobList.Items), nil
+	return names, nil
 }
@@ -0,0 +1,269 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Job", func() {
+	newApp := func(tag string) defaultv1alpha1.WorkbenchApp {
+		return defaultv1alpha1.WorkbenchApp{
+			Name:  "wezterm",
+			Image: &defaultv1alpha1.Image{Registry: "registry.local", Repository: "apps/wezterm", Tag: tag},
+		}
+	}
+
+	It("should tell the outdated jobs", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+
+		config := Config{}
+		service := initService(workbench, config.Labels.PropagatedKeys)
+
+		existing, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, newApp("1.0"), service)
+		Expect(err).NotTo(HaveOccurred())
+
+		same, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, newApp("1.0"), service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobOutdated(*same, *existing)).To(BeFalse())
+
+		upgraded, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, newApp("2.0"), service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobOutdated(*upgraded, *existing)).To(BeTrue())
+
+		// The resources are the app too.
+		app := newApp("1.0")
+		app.SidecarContainers = []corev1.Container{
+			{
+				Name:      "dicom-listener",
+				Image:     "dicom",
+				Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")}},
+			},
+		}
+
+		resized, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobOutdated(*resized, *existing)).To(BeTrue())
+
+		// The rest of the template is not.
+		placed := *workbench.DeepCopy()
+		placed.Spec.NodeSelector = map[string]string{"pool": "cpu"}
+
+		moved, err := initJob(placed, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, newApp("1.0"), service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobOutdated(*moved, *existing)).To(BeFalse())
+
+		// Without a hash, only the image tells.
+		delete(existing.Annotations, appSpecHashAnnotation)
+		Expect(jobOutdated(*same, *existing)).To(BeFalse())
+		Expect(jobOutdated(*upgraded, *existing)).To(BeTrue())
+	})
+
+	It("should give the configured resources to the apps not telling theirs", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+
+		config := Config{}
+		config.Apps.Resources = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+		}
+		service := initService(workbench, config.Labels.PropagatedKeys)
+
+		app := newApp("1.0")
+		app.GPU = &defaultv1alpha1.WorkbenchGPU{Count: 1}
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+
+		container := job.Spec.Template.Spec.Containers[0]
+		Expect(container.Resources.Requests.Cpu().String()).To(Equal("1"))
+		Expect(container.Resources.Limits.Memory().String()).To(Equal("8Gi"))
+		Expect(container.Resources.Limits).To(HaveKey(corev1.ResourceName(defaultGPUResourceName)))
+
+		// The defaults are not shared with the jobs.
+		Expect(config.Apps.Resources.Limits).To(HaveLen(1))
+
+		// The app ones win, as a whole.
+		app.Resources = &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Gi")},
+		}
+
+		job, err = initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+
+		container = job.Spec.Template.Spec.Containers[0]
+		Expect(container.Resources.Requests).To(BeEmpty())
+		Expect(container.Resources.Limits.Memory().String()).To(Equal("32Gi"))
+		Expect(app.Resources.Limits).To(HaveLen(1))
+	})
+
+	It("should give their resource profile to the apps naming one", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+
+		config := Config{}
+		config.Apps.Resources = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		}
+		config.Apps.ResourceProfiles = map[string]corev1.ResourceRequirements{
+			"large": {
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")},
+			},
+		}
+		service := initService(workbench, config.Labels.PropagatedKeys)
+
+		app := newApp("1.0")
+		app.ResourceProfile = "large"
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Resources).To(Equal(config.Apps.ResourceProfiles["large"]))
+		Expect(unknownResourceProfile(config.Apps, app, *job)).To(BeFalse())
+
+		// The app ones win over the profile.
+		app.Resources = &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		}
+
+		job, err = initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Resources).To(Equal(*app.Resources))
+
+		// An unknown one is told, the stopped apps aside.
+		app.Resources = nil
+		app.ResourceProfile = "medium"
+
+		job, err = initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Resources).To(Equal(config.Apps.Resources))
+		Expect(unknownResourceProfile(config.Apps, app, *job)).To(BeTrue())
+
+		job.Spec.Suspend = ptr.To(true)
+		Expect(unknownResourceProfile(config.Apps, app, *job)).To(BeFalse())
+	})
+
+	It("should keep the finished jobs as long as the app, or the operator, tells", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+
+		config := Config{}
+		service := initService(workbench, config.Labels.PropagatedKeys)
+		app := newApp("1.0")
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.TTLSecondsAfterFinished).To(HaveValue(Equal(int32(86400))))
+
+		config.Apps.JobTTLAfterFinished = time.Hour
+		job, err = initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.TTLSecondsAfterFinished).To(HaveValue(Equal(int32(3600))))
+
+		// The app one wins, even zero.
+		app.JobTTLSecondsAfterFinished = ptr.To(int32(0))
+		job, err = initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.TTLSecondsAfterFinished).To(HaveValue(Equal(int32(0))))
+
+		// The existing jobs follow.
+		existing := job.DeepCopy()
+		existing.Spec.TTLSecondsAfterFinished = ptr.To(int32(86400))
+		Expect(updateJob(*job, existing, config.Labels.PropagatedKeys)).To(BeTrue())
+		Expect(existing.Spec.TTLSecondsAfterFinished).To(HaveValue(Equal(int32(0))))
+		Expect(updateJob(*job, existing, config.Labels.PropagatedKeys)).To(BeFalse())
+	})
+
+	It("should mount the extra config maps and secrets into the app container only", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+
+		config := Config{}
+		service := initService(workbench, config.Labels.PropagatedKeys)
+
+		app := newApp("1.0")
+		app.SidecarContainers = []corev1.Container{{Name: "sidecar", Image: "sidecar:1.0"}}
+		app.ExtraMounts = []defaultv1alpha1.AppMount{
+			{ConfigMap: "matplotlib", MountPath: "/etc/matplotlibrc", SubPath: "matplotlibrc"},
+			{Secret: "freesurfer", MountPath: "/opt/freesurfer/license.txt", SubPath: "license.txt", ReadOnly: true},
+		}
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, service)
+		Expect(err).NotTo(HaveOccurred())
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(ContainElements(
+			corev1.Volume{Name: "extra-mount-0", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "matplotlib"}},
+			}},
+			corev1.Volume{Name: "extra-mount-1", VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: "freesurfer"},
+			}},
+		))
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElements(
+			corev1.VolumeMount{Name: "extra-mount-0", MountPath: "/etc/matplotlibrc", SubPath: "matplotlibrc"},
+			corev1.VolumeMount{Name: "extra-mount-1", MountPath: "/opt/freesurfer/license.txt", SubPath: "license.txt", ReadOnly: true},
+		))
+
+		Expect(podSpec.InitContainers).To(HaveLen(1))
+		Expect(podSpec.InitContainers[0].VolumeMounts).To(BeEmpty())
+	})
+
+	It("should run a privileged sidecar unprivileged", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+
+		privileged := true
+		root := int64(0)
+
+		app := newApp("1.0")
+		app.SidecarContainers = []corev1.Container{
+			{
+				Name:  "sidecar",
+				Image: "sidecar:1.0",
+				SecurityContext: &corev1.SecurityContext{
+					Privileged:               &privileged,
+					AllowPrivilegeEscalation: &privileged,
+					RunAsUser:                &root,
+					Capabilities: &corev1.Capabilities{
+						Add: []corev1.Capability{"SYS_ADMIN"},
+					},
+				},
+			},
+		}
+
+		for _, apps := range []AppConfig{{}, {DisableNativeSidecars: true}} {
+			job, err := initJob(workbench, ImagesConfig{}, apps, GPUConfig{}, nil, 0, app, initService(workbench, nil))
+			Expect(err).NotTo(HaveOccurred())
+
+			podSpec := job.Spec.Template.Spec
+			sidecars := append(podSpec.InitContainers, podSpec.Containers[1:]...)
+			Expect(sidecars).To(HaveLen(1))
+
+			securityContext := sidecars[0].SecurityContext
+			Expect(securityContext.Privileged).To(HaveValue(BeFalse()))
+			Expect(securityContext.AllowPrivilegeEscalation).To(HaveValue(BeFalse()))
+			Expect(securityContext.RunAsNonRoot).To(HaveValue(BeTrue()))
+			Expect(securityContext.RunAsUser).To(BeNil())
+			Expect(securityContext.Capabilities.Add).To(BeEmpty())
+			Expect(securityContext.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")))
+		}
+
+		// The spec is left untouched.
+		Expect(app.SidecarContainers[0].SecurityContext.Privileged).To(HaveValue(BeTrue()))
+	})
+})
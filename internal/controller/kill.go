@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// killedMessage explains, in the app status, why a killed app has no job.
+const killedMessage = "Killed: the app was force stopped, it stays down while killed"
+
+// isKilled tells whether the app is to be force stopped.
+func isKilled(app defaultv1alpha1.WorkbenchApp) bool {
+	return app.State == defaultv1alpha1.WorkbenchAppStateKilled
+}
+
+// killJob deletes the job of the app and its pods, without any grace period, and tells
+// whether there was one.
+//
+// Unlike a suspension, the kubelet does not wait for the app to shut down. A job already
+// being deleted is left alone.
+func (r *WorkbenchReconciler) killJob(ctx context.Context, job batchv1.Job) (bool, error) {
+	log := log.FromContext(ctx)
+
+	foundJob := batchv1.Job{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(&job), &foundJob); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	if !foundJob.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+
+	log.V(1).Info("Killing Job", "job", foundJob.Name)
+
+	// The job deletion would give the pods their termination grace period.
+	if err := r.DeleteAllOf(
+		ctx,
+		&corev1.Pod{},
+		client.InNamespace(foundJob.Namespace),
+		client.MatchingLabels{batchv1.JobNameLabel: foundJob.Name},
+		client.GracePeriodSeconds(0),
+	); err != nil {
+		return false, err
+	}
+
+	uid := foundJob.UID
+	err := r.Delete(
+		ctx,
+		&foundJob,
+		client.PropagationPolicy(metav1.DeletePropagationForeground),
+		client.Preconditions{UID: &uid},
+	)
+
+	return err == nil, client.IgnoreNotFound(err)
+}
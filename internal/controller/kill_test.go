@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Kill", func() {
+	Context("When a running app is killed", func() {
+		const resourceName = "kill"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		jobName := types.NamespacedName{
+			Name:      resourceName + "-0-wezterm",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{{Name: "wezterm"}}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			// There is no garbage collection in envtest, the foreground deletion is left pending.
+			job := &batchv1.Job{}
+			if err := k8sClient.Get(ctx, jobName, job); err == nil {
+				job.Finalizers = nil
+				Expect(k8sClient.Update(ctx, job)).To(Succeed())
+			}
+
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should delete its job within one reconcile, and never recreate it", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(k8sClient.Get(ctx, jobName, &batchv1.Job{})).To(Succeed())
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.Apps[0].State = defaultv1alpha1.WorkbenchAppStateKilled
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			job := &batchv1.Job{}
+			err := k8sClient.Get(ctx, jobName, job)
+			Expect(apierrors.IsNotFound(err) || job.DeletionTimestamp != nil).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusFailed))
+			Expect(workbench.Status.Apps[0].DesiredState).To(Equal(defaultv1alpha1.WorkbenchAppStateKilled))
+			Expect(workbench.Status.Apps[0].Message).To(Equal(killedMessage))
+
+			Eventually(recorder.Events).Should(Receive(ContainSubstring("KilledApp")))
+
+			// Gone for good.
+			if job.DeletionTimestamp != nil {
+				job.Finalizers = nil
+				Expect(k8sClient.Update(ctx, job)).To(Succeed())
+			}
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, jobName, &batchv1.Job{}))).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// managedLabelKeys are the labels owned by the operator which cannot be propagated.
+var managedLabelKeys = []string{
+	matchingLabel,
+	sourceNamespaceLabel,
+	appUIDLabel,
+	syntheticProbeLabel,
+	prePullLabel,
+}
+
+// ValidatePropagatedLabelKeys rejects the invalid label keys, and the ones colliding with
+// the operator managed labels.
+func ValidatePropagatedLabelKeys(keys []string) error {
+	for _, key := range keys {
+		if key == "" {
+			return fmt.Errorf("empty propagated label key")
+		}
+
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("propagated label key %q is invalid: %s", key, strings.Join(errs, ", "))
+		}
+
+		for _, managedKey := range managedLabelKeys {
+			if key == managedKey {
+				return fmt.Errorf("propagated label key %q is managed by the operator", key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// childLabels are the labels put on every object managed for the workbench.
+//
+// On top of the matching labels, the propagated label keys are copied, when present, from
+// the workbench.
+func childLabels(workbench defaultv1alpha1.Workbench, labelKeys []string) map[string]string {
+	labels := map[string]string(childSelector(workbench))
+
+	for _, key := range labelKeys {
+		if value, ok := workbench.Labels[key]; ok {
+			labels[key] = value
+		}
+	}
+
+	return labels
+}
+
+// updateLabels makes the destination propagated labels like the source ones.
+//
+// Keys absent from the source are removed from the destination, the other labels are kept as is.
+func updateLabels(source map[string]string, destination *map[string]string, keys []string) bool {
+	updated := false
+
+	for _, key := range keys {
+		value, ok := source[key]
+		current, found := (*destination)[key]
+
+		if ok && (!found || current != value) {
+			if *destination == nil {
+				*destination = map[string]string{}
+			}
+
+			(*destination)[key] = value
+			updated = true
+		}
+
+		if !ok && found {
+			delete(*destination, key)
+			updated = true
+		}
+	}
+
+	return updated
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// logLevelAnnotation raises the log verbosity of a single object.
+	logLevelAnnotation = "chorus-tre.ch/log-level"
+	// logLevelDebug is the only value the annotation accepts, it only affects logging.
+	logLevelDebug = "debug"
+)
+
+// debugLogSink logs every verbosity level as if it was the base one.
+type debugLogSink struct {
+	logr.LogSink
+}
+
+func (s debugLogSink) Enabled(level int) bool {
+	return s.LogSink.Enabled(0)
+}
+
+func (s debugLogSink) Info(level int, msg string, keysAndValues ...any) {
+	s.LogSink.Info(0, msg, append(keysAndValues, "v", level)...)
+}
+
+func (s debugLogSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return debugLogSink{s.LogSink.WithValues(keysAndValues...)}
+}
+
+func (s debugLogSink) WithName(name string) logr.LogSink {
+	return debugLogSink{s.LogSink.WithName(name)}
+}
+
+func (s debugLogSink) WithCallDepth(depth int) logr.LogSink {
+	if sink, ok := s.LogSink.(logr.CallDepthLogSink); ok {
+		return debugLogSink{sink.WithCallDepth(depth)}
+	}
+
+	return s
+}
+
+// withObjectLogger returns the logger of the object, debugging it when annotated so.
+//
+// The returned context carries the logger for the helpers called by the reconciler.
+func withObjectLogger(ctx context.Context, object metav1.Object) (context.Context, logr.Logger) {
+	logger := log.FromContext(ctx)
+
+	if object.GetAnnotations()[logLevelAnnotation] != logLevelDebug || logger.GetSink() == nil {
+		return ctx, logger
+	}
+
+	// One more frame for the wrapper.
+	logger = logr.New(debugLogSink{logger.WithCallDepth(1).GetSink()})
+
+	return log.IntoContext(ctx, logger), logger
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var _ = Describe("Object logger", func() {
+	var lines []string
+
+	// A logger only showing the base level.
+	newContext := func() context.Context {
+		lines = nil
+		logger := funcr.New(func(prefix, args string) {
+			lines = append(lines, args)
+		}, funcr.Options{Verbosity: 0})
+
+		return log.IntoContext(context.Background(), logger)
+	}
+
+	objectWith := func(annotations map[string]string) metav1.Object {
+		return &metav1.ObjectMeta{Name: "workbench", Annotations: annotations}
+	}
+
+	debugOnly := func(logger logr.Logger) {
+		logger.V(1).Info("debug")
+	}
+
+	It("should keep the verbosity by default", func() {
+		_, logger := withObjectLogger(newContext(), objectWith(nil))
+		debugOnly(logger)
+		Expect(lines).To(BeEmpty())
+	})
+
+	It("should show the debug messages when annotated", func() {
+		ctx, logger := withObjectLogger(newContext(), objectWith(map[string]string{
+			logLevelAnnotation: logLevelDebug,
+		}))
+		debugOnly(logger)
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"msg"="debug"`))
+
+		// The helpers get it from the context, with their own values.
+		debugOnly(log.FromContext(ctx).WithName("helper").WithValues("key", "value"))
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[1]).To(ContainSubstring(`"key"="value"`))
+	})
+
+	It("should ignore the other values", func() {
+		_, logger := withObjectLogger(newContext(), objectWith(map[string]string{
+			logLevelAnnotation: "trace",
+		}))
+		debugOnly(logger)
+		Expect(lines).To(BeEmpty())
+	})
+})
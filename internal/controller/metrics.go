@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var (
+	// syntheticProbeSuccess tells whether the last synthetic probe succeeded.
+	syntheticProbeSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "workbench_synthetic_probe_success",
+			Help: "Whether the last synthetic workbench probe succeeded (1) or not (0).",
+		},
+	)
+
+	// syntheticProbeDuration is the time it took the last synthetic probe to get ready.
+	syntheticProbeDuration = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "workbench_synthetic_probe_duration_seconds",
+			Help: "Duration of the last synthetic workbench probe.",
+		},
+	)
+
+	// orphansTotal counts the orphaned children found by the startup sweep.
+	orphansTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workbench_orphans_total",
+			Help: "Number of orphaned workbench children found at startup, by kind and action (deleted or reported).",
+		},
+		[]string{"kind", "action"},
+	)
+
+	// convergenceSeconds is the time from a spec change to the pass leaving it settled.
+	convergenceSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "workbench_convergence_seconds",
+			Help:    "Time from a change of the spec, or the creation, to its convergence, by controller.",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+		},
+		[]string{"controller"},
+	)
+
+	// appsRunning is the number of running apps of each workbench.
+	appsRunning = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "workbench_apps_running",
+			Help: "Number of running apps, by workbench.",
+		},
+		[]string{"namespace", "workbench"},
+	)
+
+	// serverStatus tells the status of the Xpra server of each workbench, only one is 1.
+	serverStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "workbench_server_status",
+			Help: "Whether the Xpra server has the status (1) or not (0), by workbench and status.",
+		},
+		[]string{"namespace", "workbench", "status"},
+	)
+
+	// appJobDuration is the time the jobs of the apps ran, until they completed or failed.
+	appJobDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "workbench_app_job_duration_seconds",
+			Help:    "Time from the start of the job of an app to its end, by final status.",
+			Buckets: prometheus.ExponentialBuckets(10, 4, 10),
+		},
+		[]string{"status"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		syntheticProbeSuccess,
+		syntheticProbeDuration,
+		orphansTotal,
+		convergenceSeconds,
+		appsRunning,
+		serverStatus,
+		appJobDuration,
+	)
+}
+
+// serverStatuses are the values of the status label of serverStatus.
+var serverStatuses = []defaultv1alpha1.WorkbenchStatusServerStatus{
+	defaultv1alpha1.WorkbenchStatusServerStatusRunning,
+	defaultv1alpha1.WorkbenchStatusServerStatusProgressing,
+	defaultv1alpha1.WorkbenchStatusServerStatusFailed,
+}
+
+// reportWorkbenchMetrics publishes the gauges of the workbench, from its status.
+func reportWorkbenchMetrics(workbench defaultv1alpha1.Workbench) {
+	appsRunning.WithLabelValues(workbench.Namespace, workbench.Name).Set(float64(workbench.Status.RunningAppCount))
+
+	for _, status := range serverStatuses {
+		value := 0.0
+		if workbench.Status.Server.Status == status {
+			value = 1
+		}
+
+		serverStatus.WithLabelValues(workbench.Namespace, workbench.Name, string(status)).Set(value)
+	}
+}
+
+// forgetWorkbenchMetrics drops the gauges of a deleted workbench, they would pile up otherwise.
+func forgetWorkbenchMetrics(namespace, name string) {
+	appsRunning.DeleteLabelValues(namespace, name)
+	serverStatus.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "workbench": name})
+}
+
+// observeAppJobDuration measures the job of an app, once, when its status becomes final.
+func observeAppJobDuration(before, after defaultv1alpha1.WorkbenchStatusAppStatus, job batchv1.Job) {
+	if duration, ok := appJobDurationOf(before, after, job); ok {
+		appJobDuration.WithLabelValues(string(after)).Observe(duration.Seconds())
+	}
+}
+
+// appJobDurationOf tells how long the job of an app ran, when its status just became final.
+func appJobDurationOf(before, after defaultv1alpha1.WorkbenchStatusAppStatus, job batchv1.Job) (time.Duration, bool) {
+	if before == after || job.Status.StartTime == nil {
+		return 0, false
+	}
+
+	if after != defaultv1alpha1.WorkbenchStatusAppStatusComplete && after != defaultv1alpha1.WorkbenchStatusAppStatusFailed {
+		return 0, false
+	}
+
+	end := job.Status.CompletionTime
+	for index := range job.Status.Conditions {
+		condition := &job.Status.Conditions[index]
+		if end == nil && condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			end = &condition.LastTransitionTime
+		}
+	}
+
+	if end == nil {
+		return 0, false
+	}
+
+	return max(end.Sub(job.Status.StartTime.Time), 0), true
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Metrics", func() {
+	newWorkbench := func(name string) defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = name
+		workbench.Namespace = "metrics"
+		workbench.Status.RunningAppCount = 2
+		workbench.Status.Server.Status = defaultv1alpha1.WorkbenchStatusServerStatusProgressing
+
+		return workbench
+	}
+
+	It("should report the gauges of a workbench, and forget them", func() {
+		workbench := newWorkbench("gauges")
+		reportWorkbenchMetrics(workbench)
+
+		Expect(testutil.ToFloat64(appsRunning.WithLabelValues("metrics", "gauges"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(serverStatus.WithLabelValues("metrics", "gauges", "Progressing"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(serverStatus.WithLabelValues("metrics", "gauges", "Running"))).To(Equal(0.0))
+
+		workbench.Status.Server.Status = defaultv1alpha1.WorkbenchStatusServerStatusRunning
+		reportWorkbenchMetrics(workbench)
+
+		Expect(testutil.ToFloat64(serverStatus.WithLabelValues("metrics", "gauges", "Progressing"))).To(Equal(0.0))
+		Expect(testutil.ToFloat64(serverStatus.WithLabelValues("metrics", "gauges", "Running"))).To(Equal(1.0))
+
+		before := testutil.CollectAndCount(serverStatus)
+		reportWorkbenchMetrics(newWorkbench("other"))
+		Expect(testutil.CollectAndCount(serverStatus)).To(Equal(before + len(serverStatuses)))
+
+		forgetWorkbenchMetrics("metrics", "other")
+		forgetWorkbenchMetrics("metrics", "gauges")
+		Expect(testutil.CollectAndCount(serverStatus)).To(Equal(before - len(serverStatuses)))
+		Expect(appsRunning.DeleteLabelValues("metrics", "gauges")).To(BeFalse())
+	})
+
+	It("should measure the jobs of the apps once they end", func() {
+		start := metav1.NewTime(time.Unix(1000, 0))
+
+		job := batchv1.Job{}
+		job.Status.StartTime = &start
+
+		completion := metav1.NewTime(start.Add(30 * time.Second))
+		job.Status.CompletionTime = &completion
+
+		// Still running, or already final before.
+		_, ok := appJobDurationOf(defaultv1alpha1.WorkbenchStatusAppStatusUnknown, defaultv1alpha1.WorkbenchStatusAppStatusRunning, job)
+		Expect(ok).To(BeFalse())
+		_, ok = appJobDurationOf(defaultv1alpha1.WorkbenchStatusAppStatusComplete, defaultv1alpha1.WorkbenchStatusAppStatusComplete, job)
+		Expect(ok).To(BeFalse())
+
+		duration, ok := appJobDurationOf(defaultv1alpha1.WorkbenchStatusAppStatusRunning, defaultv1alpha1.WorkbenchStatusAppStatusComplete, job)
+		Expect(ok).To(BeTrue())
+		Expect(duration).To(Equal(30 * time.Second))
+
+		failed := batchv1.Job{}
+		failed.Status.StartTime = &start
+		failed.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(start.Add(time.Minute))},
+		}
+
+		duration, ok = appJobDurationOf(defaultv1alpha1.WorkbenchStatusAppStatusRunning, defaultv1alpha1.WorkbenchStatusAppStatusFailed, failed)
+		Expect(ok).To(BeTrue())
+		Expect(duration).To(Equal(time.Minute))
+
+		observeAppJobDuration(defaultv1alpha1.WorkbenchStatusAppStatusRunning, defaultv1alpha1.WorkbenchStatusAppStatusFailed, failed)
+		Expect(testutil.CollectAndCount(appJobDuration, "workbench_app_job_duration_seconds")).To(BeNumerically(">=", 1))
+	})
+})
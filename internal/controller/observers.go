@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// observerBasePort is the port after which the observers listen, observer-1 on 8081.
+const observerBasePort = 8080
+
+// observerName is the container, and the port, of the n-th observer, from one.
+func observerName(n int32) string {
+	return fmt.Sprintf("observer-%d", n)
+}
+
+// addObservers renders the read-only observers next to the Xpra server.
+//
+// They run the server image, shadowing the display of the shared X11 socket. The image
+// reads XPRA_MODE, XPRA_READONLY and XPRA_PORT, the session token applies to them too.
+func addObservers(deployment *appsv1.Deployment, workbench defaultv1alpha1.Workbench) {
+	server := deployment.Spec.Template.Spec.Containers[0]
+
+	for n := int32(1); n <= workbench.Spec.Server.Observers; n++ {
+		port := observerBasePort + n
+
+		env := []corev1.EnvVar{
+			{Name: "XPRA_MODE", Value: "shadow"},
+			{Name: "XPRA_READONLY", Value: "true"},
+			{Name: "XPRA_PORT", Value: strconv.Itoa(int(port))},
+		}
+
+		for _, variable := range server.Env {
+			if variable.Name == "XPRA_PASSWORD" {
+				env = append(env, variable)
+			}
+		}
+
+		observer := corev1.Container{
+			Name:            fmt.Sprintf("xpra-%s", observerName(n)),
+			Image:           server.Image,
+			ImagePullPolicy: server.ImagePullPolicy,
+			Ports: []corev1.ContainerPort{
+				{
+					Name:          observerName(n),
+					ContainerPort: port,
+				},
+			},
+			Env: env,
+			// Only the X11 socket, the session state belongs to the server.
+			VolumeMounts: []corev1.VolumeMount{server.VolumeMounts[0]},
+			Resources:    *server.Resources.DeepCopy(),
+		}
+
+		// The extended resources, e.g. the GPUs, are the server's.
+		for _, resources := range []corev1.ResourceList{observer.Resources.Requests, observer.Resources.Limits} {
+			for name := range resources {
+				if strings.Contains(string(name), "/") {
+					delete(resources, name)
+				}
+			}
+		}
+
+		deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, observer)
+	}
+}
+
+// addObserverPorts exposes the observers on the service.
+func addObserverPorts(service *corev1.Service, workbench defaultv1alpha1.Workbench) {
+	for n := int32(1); n <= workbench.Spec.Server.Observers; n++ {
+		port := observerBasePort + n
+
+		service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{
+			Port:       port,
+			TargetPort: intstr.FromInt32(port),
+			Protocol:   "TCP",
+			Name:       observerName(n),
+		})
+	}
+}
+
+// observerStatuses tells the health of the observers, from the newest server pod.
+func observerStatuses(pods []corev1.Pod, count int32) []defaultv1alpha1.WorkbenchStatusObserver {
+	if count == 0 {
+		return nil
+	}
+
+	newest := newestPod(pods)
+
+	observers := make([]defaultv1alpha1.WorkbenchStatusObserver, 0, count)
+
+	for n := int32(1); n <= count; n++ {
+		name := fmt.Sprintf("xpra-%s", observerName(n))
+		restarts, _ := containerRestarts(pods, name)
+
+		observer := defaultv1alpha1.WorkbenchStatusObserver{
+			Name:     observerName(n),
+			Restarts: restarts,
+		}
+
+		if newest != nil {
+			for _, status := range newest.Status.ContainerStatuses {
+				if status.Name == name {
+					observer.Ready = status.Ready
+				}
+			}
+		}
+
+		observers = append(observers, observer)
+	}
+
+	return observers
+}
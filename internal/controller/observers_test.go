@@ -0,0 +1,118 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Observers", func() {
+	newWorkbench := func(observers int32) defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "teaching"
+		workbench.Spec.Server.Observers = observers
+
+		return workbench
+	}
+
+	It("should render the observers after the server, read-only", func() {
+		workbench := newWorkbench(2)
+		workbench.Spec.Server.GPU = &defaultv1alpha1.WorkbenchGPU{Count: 1}
+
+		deployment := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		addSessionAuth(&deployment, initSessionSecret(workbench, nil))
+		addObservers(&deployment, workbench)
+
+		containers := deployment.Spec.Template.Spec.Containers
+		Expect(containers).To(HaveLen(3))
+		Expect(containers[0].Name).To(Equal(serverContainerName))
+
+		observer := containers[2]
+		Expect(observer.Name).To(Equal("xpra-observer-2"))
+		Expect(observer.Image).To(Equal(containers[0].Image))
+		Expect(observer.Ports).To(ConsistOf(corev1.ContainerPort{Name: "observer-2", ContainerPort: 8082}))
+		Expect(observer.Env).To(ContainElements(
+			corev1.EnvVar{Name: "XPRA_READONLY", Value: "true"},
+			corev1.EnvVar{Name: "XPRA_PORT", Value: "8082"},
+		))
+		Expect(observer.Env).To(ContainElement(HaveField("Name", "XPRA_PASSWORD")))
+		Expect(observer.VolumeMounts).To(HaveLen(1))
+		Expect(observer.Resources.Limits).NotTo(HaveKey(corev1.ResourceName(defaultGPUResourceName)))
+		Expect(containers[0].Resources.Limits).To(HaveKey(corev1.ResourceName(defaultGPUResourceName)))
+	})
+
+	It("should update the observers when their count changes", func() {
+		existing := initDeployment(newWorkbench(0), ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+
+		deployment := initDeployment(newWorkbench(1), ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		addObservers(&deployment, newWorkbench(1))
+
+		Expect(updateDeployment(deployment, &existing, nil)).To(BeTrue())
+		Expect(existing.Spec.Template.Spec.Containers).To(HaveLen(2))
+		Expect(updateDeployment(deployment, &existing, nil)).To(BeFalse())
+
+		next := deployment.DeepCopy()
+		next.Spec.Template.Spec.Containers[1].Image = "xpra-server:next"
+		Expect(updateDeployment(*next, &existing, nil)).To(BeTrue())
+		Expect(existing.Spec.Template.Spec.Containers[1].Image).To(Equal("xpra-server:next"))
+	})
+
+	It("should expose the observers on distinct service ports", func() {
+		service := initService(newWorkbench(2), nil)
+
+		Expect(service.Spec.Ports).To(ContainElements(
+			corev1.ServicePort{Name: "observer-1", Port: 8081, TargetPort: intstr.FromInt32(8081), Protocol: "TCP"},
+			corev1.ServicePort{Name: "observer-2", Port: 8082, TargetPort: intstr.FromInt32(8082), Protocol: "TCP"},
+		))
+
+		existing := initService(newWorkbench(0), nil)
+		Expect(updateService(service, &existing, nil)).To(BeTrue())
+		Expect(existing.Spec.Ports).To(HaveLen(4))
+		Expect(updateService(service, &existing, nil)).To(BeFalse())
+	})
+
+	It("should report the health of each observer, from the newest pod", func() {
+		Expect(observerStatuses(nil, 0)).To(BeNil())
+
+		older := corev1.Pod{}
+		older.CreationTimestamp = metav1.Unix(1000, 0)
+		older.Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: "xpra-observer-1", Ready: true, RestartCount: 2},
+		}
+
+		newer := corev1.Pod{}
+		newer.CreationTimestamp = metav1.Unix(2000, 0)
+		newer.Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: "xpra-observer-1", Ready: false, RestartCount: 1},
+			{Name: "xpra-observer-2", Ready: true},
+		}
+
+		Expect(observerStatuses([]corev1.Pod{older, newer}, 2)).To(Equal([]defaultv1alpha1.WorkbenchStatusObserver{
+			{Name: "observer-1", Ready: false, Restarts: 3},
+			{Name: "observer-2", Ready: true},
+		}))
+	})
+
+	It("should not give the extended resources to the observers", func() {
+		workbench := newWorkbench(1)
+		workbench.Spec.Server.Resources = &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+				"amd.com/gpu":         resource.MustParse("1"),
+			},
+		}
+
+		deployment := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		addObservers(&deployment, workbench)
+
+		Expect(deployment.Spec.Template.Spec.Containers[1].Resources.Limits).To(Equal(corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+		}))
+	})
+})
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// orphanSweepPageSize is the number of objects listed at once by the orphan sweep.
+const orphanSweepPageSize = 100
+
+// orphanSweepPageDelay is the pause between two pages, to spare the API server at startup.
+const orphanSweepPageDelay = 200 * time.Millisecond
+
+// OrphanSweeper deletes, once at startup, the children whose workbench is gone.
+//
+// They are left behind when a workbench is deleted while the operator is down and its
+// finalizer is removed by hand; no reconcile will ever see them again.
+type OrphanSweeper struct {
+	Client client.Client
+	// Reader lists the objects, it has to support the pagination (i.e. not the cache).
+	Reader client.Reader
+	// DryRun only reports the orphans.
+	DryRun bool
+	// PageDelay is the pause between two pages, orphanSweepPageDelay if zero.
+	PageDelay time.Duration
+	// Clock tells when the sweep starts, the real one unless set.
+	Clock clock.PassiveClock
+}
+
+// orphanSweepKinds are the kinds of children labelled with their workbench.
+var orphanSweepKinds = []childKind{
+	{"Deployment", func() client.ObjectList { return &appsv1.DeploymentList{} }},
+	{"Job", func() client.ObjectList { return &batchv1.JobList{} }},
+	{"Service", func() client.ObjectList { return &corev1.ServiceList{} }},
+	{"Secret", func() client.ObjectList { return &corev1.SecretList{} }},
+	{"PersistentVolumeClaim", func() client.ObjectList { return &corev1.PersistentVolumeClaimList{} }},
+	{"ConfigMap", func() client.ObjectList { return &corev1.ConfigMapList{} }},
+}
+
+// NeedLeaderElection makes sure only the active operator deletes anything.
+func (s *OrphanSweeper) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements the manager.Runnable interface, the sweep runs only once.
+func (s *OrphanSweeper) Start(ctx context.Context) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithName("orphan-sweep"))
+	log := log.FromContext(ctx)
+
+	found, err := s.sweep(ctx)
+	if err != nil {
+		// Never fail the manager, the next start sweeps again.
+		log.Error(err, "Unable to sweep the orphaned children")
+	}
+
+	if found.total() == 0 {
+		log.Info("No orphaned children found")
+		return nil
+	}
+
+	log.Info("Orphaned children found", "orphans", found.String(), "total", found.total(), "dryRun", s.DryRun)
+
+	return nil
+}
+
+// sweep goes through all the labelled children, page by page, and deletes the orphans.
+//
+// It may take minutes: the children created since it started belong to workbenches it may
+// have seen missing, they are left alone.
+func (s *OrphanSweeper) sweep(ctx context.Context) (deletionSummary, error) {
+	found := deletionSummary{}
+
+	started := time.Now()
+	if s.Clock != nil {
+		started = s.Clock.Now()
+	}
+
+	// Only the existing workbenches are remembered, a missing one may be created meanwhile.
+	exists := map[types.NamespacedName]bool{}
+
+	pageDelay := s.PageDelay
+	if pageDelay == 0 {
+		pageDelay = orphanSweepPageDelay
+	}
+
+	for _, kind := range orphanSweepKinds {
+		continueToken := ""
+
+		for {
+			list := kind.newList()
+
+			if err := s.Reader.List(
+				ctx,
+				list,
+				client.HasLabels{matchingLabel},
+				client.Limit(orphanSweepPageSize),
+				client.Continue(continueToken),
+			); err != nil {
+				return found, err
+			}
+
+			objects, err := meta.ExtractList(list)
+			if err != nil {
+				return found, err
+			}
+
+			for _, object := range objects {
+				obj, ok := object.(client.Object)
+				if !ok || !isWorkbenchChild(obj) {
+					continue
+				}
+
+				// The creation time has a one second precision.
+				if !obj.GetCreationTimestamp().Add(time.Second).Before(started) {
+					continue
+				}
+
+				// Right before the deletion, so the workbench is seen as it is now.
+				orphan, err := s.isOrphan(ctx, obj, exists)
+				if err != nil {
+					return found, err
+				}
+
+				if !orphan {
+					continue
+				}
+
+				if err := s.handleOrphan(ctx, kind.kind, obj); err != nil {
+					return found, err
+				}
+
+				found.add(kind.kind, obj.GetName())
+			}
+
+			continueToken = list.GetContinue()
+			if continueToken == "" {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return found, ctx.Err()
+			case <-time.After(pageDelay):
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// isOrphan tells whether the workbench named by the child label is gone.
+//
+// The children in a target namespace name the namespace of their workbench too.
+func (s *OrphanSweeper) isOrphan(ctx context.Context, obj client.Object, exists map[types.NamespacedName]bool) (bool, error) {
+	key := types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetLabels()[matchingLabel],
+	}
+
+	if source := obj.GetLabels()[sourceNamespaceLabel]; source != "" {
+		key.Namespace = source
+	}
+
+	if exists[key] {
+		return false, nil
+	}
+
+	err := s.Reader.Get(ctx, key, &defaultv1alpha1.Workbench{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	if err == nil {
+		exists[key] = true
+	}
+
+	return err != nil, nil
+}
+
+// isWorkbenchChild tells whether the object was made by the operator for a workbench, not
+// only labelled with the generic workbench key.
+//
+// The children in the namespace of their workbench are controlled by it, the others name
+// its namespace.
+func isWorkbenchChild(obj client.Object) bool {
+	if obj.GetLabels()[sourceNamespaceLabel] != "" {
+		return true
+	}
+
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil || owner.Kind != "Workbench" {
+		return false
+	}
+
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+
+	return err == nil && gv.Group == defaultv1alpha1.GroupVersion.Group
+}
+
+// handleOrphan deletes the orphan, or only reports it in dry run.
+func (s *OrphanSweeper) handleOrphan(ctx context.Context, kind string, obj client.Object) error {
+	log := log.FromContext(ctx)
+
+	if s.DryRun {
+		log.Info("Found an orphan", "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+
+		orphansTotal.WithLabelValues(kind, "reported").Inc()
+
+		return nil
+	}
+
+	log.V(1).Info("Delete an orphan", "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+
+	// The UID precondition protects an object recreated in the meantime.
+	uid := obj.GetUID()
+	err := s.Client.Delete(
+		ctx,
+		obj,
+		client.PropagationPolicy("Background"),
+		client.Preconditions{UID: &uid},
+	)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	orphansTotal.WithLabelValues(kind, "deleted").Inc()
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Orphan sweep", func() {
+	ctx := context.Background()
+
+	keptKey := types.NamespacedName{Name: "orphan-sweep-kept", Namespace: "default"}
+
+	// newSecret creates a secret labelled with the given workbench.
+	newSecret := func(name, workbench string, owners ...metav1.OwnerReference) *corev1.Secret {
+		secret := &corev1.Secret{}
+		secret.Name = name
+		secret.Namespace = "default"
+		secret.Labels = map[string]string{
+			matchingLabel: workbench,
+		}
+		secret.OwnerReferences = owners
+
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		return secret
+	}
+
+	// newChild creates a secret controlled by the given workbench, which may be gone.
+	newChild := func(name, workbench string) *corev1.Secret {
+		return newSecret(name, workbench, metav1.OwnerReference{
+			APIVersion: defaultv1alpha1.GroupVersion.String(),
+			Kind:       "Workbench",
+			Name:       workbench,
+			UID:        types.UID(workbench),
+			Controller: ptr.To(true),
+		})
+	}
+
+	// later is a clock after the creation of the children.
+	later := func() *clocktesting.FakePassiveClock {
+		return clocktesting.NewFakePassiveClock(time.Now().Add(time.Minute))
+	}
+
+	exists := func(obj client.Object) bool {
+		err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if errors.IsNotFound(err) {
+			return false
+		}
+
+		Expect(err).NotTo(HaveOccurred())
+
+		return true
+	}
+
+	var kept, orphan, foreign *corev1.Secret
+
+	BeforeEach(func() {
+		workbench := &defaultv1alpha1.Workbench{}
+		workbench.Name = keptKey.Name
+		workbench.Namespace = keptKey.Namespace
+		Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+
+		kept = newChild("orphan-sweep-kept-xpra-auth", keptKey.Name)
+		orphan = newChild("orphan-sweep-gone-xpra-auth", "orphan-sweep-gone")
+		foreign = newSecret("orphan-sweep-foreign", "orphan-sweep-gone")
+	})
+
+	AfterEach(func() {
+		// There is no garbage collection in envtest.
+		for _, secret := range []*corev1.Secret{kept, orphan, foreign} {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, secret))).To(Succeed())
+		}
+
+		deleteWorkbench(ctx, keptKey)
+	})
+
+	It("should only report the orphans in dry run", func() {
+		sweeper := &OrphanSweeper{
+			Client:    k8sClient,
+			Reader:    k8sClient,
+			DryRun:    true,
+			PageDelay: time.Millisecond,
+			Clock:     later(),
+		}
+
+		found, err := sweeper.sweep(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.String()).To(ContainSubstring("Secret/" + orphan.Name))
+		Expect(found.String()).NotTo(ContainSubstring(kept.Name))
+
+		Expect(exists(orphan)).To(BeTrue())
+		Expect(exists(kept)).To(BeTrue())
+	})
+
+	It("should delete only the orphans", func() {
+		sweeper := &OrphanSweeper{
+			Client:    k8sClient,
+			Reader:    k8sClient,
+			PageDelay: time.Millisecond,
+			Clock:     later(),
+		}
+
+		found, err := sweeper.sweep(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.String()).To(ContainSubstring("Secret/" + orphan.Name))
+
+		Expect(exists(orphan)).To(BeFalse())
+		Expect(exists(kept)).To(BeTrue())
+	})
+
+	It("should leave alone the objects only carrying a workbench label", func() {
+		sweeper := &OrphanSweeper{
+			Client:    k8sClient,
+			Reader:    k8sClient,
+			PageDelay: time.Millisecond,
+			Clock:     later(),
+		}
+
+		found, err := sweeper.sweep(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.String()).NotTo(ContainSubstring(foreign.Name))
+
+		Expect(exists(foreign)).To(BeTrue())
+	})
+
+	It("should leave alone the children created since the sweep started", func() {
+		sweeper := &OrphanSweeper{
+			Client:    k8sClient,
+			Reader:    k8sClient,
+			PageDelay: time.Millisecond,
+			Clock:     clocktesting.NewFakePassiveClock(time.Now().Add(-time.Minute)),
+		}
+
+		found, err := sweeper.sweep(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.String()).NotTo(ContainSubstring(orphan.Name))
+
+		Expect(exists(orphan)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Orphan children", func() {
+	It("should only take the objects made for a workbench", func() {
+		secret := &corev1.Secret{}
+		secret.Labels = map[string]string{matchingLabel: "workbench"}
+		Expect(isWorkbenchChild(secret)).To(BeFalse())
+
+		owner := metav1.OwnerReference{Kind: "Workbench", Name: "workbench", Controller: ptr.To(true)}
+
+		owner.APIVersion = "other.example.com/v1"
+		secret.OwnerReferences = []metav1.OwnerReference{owner}
+		Expect(isWorkbenchChild(secret)).To(BeFalse())
+
+		owner.APIVersion = defaultv1alpha1.GroupVersion.String()
+		owner.Controller = nil
+		secret.OwnerReferences = []metav1.OwnerReference{owner}
+		Expect(isWorkbenchChild(secret)).To(BeFalse())
+
+		owner.Controller = ptr.To(true)
+		secret.OwnerReferences = []metav1.OwnerReference{owner}
+		Expect(isWorkbenchChild(secret)).To(BeTrue())
+
+		// In a target namespace, there is no owner reference.
+		targeted := &corev1.Secret{}
+		targeted.Labels = map[string]string{matchingLabel: "workbench", sourceNamespaceLabel: "teaching"}
+		Expect(isWorkbenchChild(targeted)).To(BeTrue())
+	})
+})
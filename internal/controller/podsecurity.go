@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// podSecurityEnforceLabel is the Pod Security Standard level the namespace enforces.
+//
+// The audit and warn levels do not refuse any pod, only enforce matters here.
+const podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// podSecurityRestricted is the level refusing the pods not explicitly hardened.
+const podSecurityRestricted = "restricted"
+
+// enforcesRestricted tells whether the namespace refuses the pods violating the restricted level.
+func (r *WorkbenchReconciler) enforcesRestricted(ctx context.Context, namespace string) (bool, error) {
+	foundNamespace := corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, &foundNamespace); err != nil {
+		return false, err
+	}
+
+	return foundNamespace.Labels[podSecurityEnforceLabel] == podSecurityRestricted, nil
+}
+
+// restrictedViolations lists why the pod would be refused by the restricted level.
+//
+// It only covers the settings the restricted level requires to be explicit, the
+// ones the baseline level already forbids are left to the admission.
+func restrictedViolations(spec corev1.PodSpec) []string {
+	podContext := spec.SecurityContext
+	if podContext == nil {
+		podContext = &corev1.PodSecurityContext{}
+	}
+
+	privilegeEscalation := []string{}
+	capabilities := []string{}
+	runAsNonRoot := []string{}
+	seccompProfile := []string{}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		securityContext := container.SecurityContext
+		if securityContext == nil {
+			securityContext = &corev1.SecurityContext{}
+		}
+
+		if securityContext.AllowPrivilegeEscalation == nil || *securityContext.AllowPrivilegeEscalation {
+			privilegeEscalation = append(privilegeEscalation, container.Name)
+		}
+
+		if !dropsAllCapabilities(securityContext.Capabilities) {
+			capabilities = append(capabilities, container.Name)
+		}
+
+		nonRoot := securityContext.RunAsNonRoot
+		if nonRoot == nil {
+			nonRoot = podContext.RunAsNonRoot
+		}
+
+		if nonRoot == nil || !*nonRoot {
+			runAsNonRoot = append(runAsNonRoot, container.Name)
+		}
+
+		profile := securityContext.SeccompProfile
+		if profile == nil {
+			profile = podContext.SeccompProfile
+		}
+
+		if profile == nil || (profile.Type != corev1.SeccompProfileTypeRuntimeDefault && profile.Type != corev1.SeccompProfileTypeLocalhost) {
+			seccompProfile = append(seccompProfile, container.Name)
+		}
+	}
+
+	violations := []string{}
+
+	for _, check := range []struct {
+		name       string
+		containers []string
+	}{
+		{"allowPrivilegeEscalation != false", privilegeEscalation},
+		{"unrestricted capabilities", capabilities},
+		{"runAsNonRoot != true", runAsNonRoot},
+		{"seccompProfile", seccompProfile},
+	} {
+		if len(check.containers) > 0 {
+			violations = append(violations, fmt.Sprintf("%s (%s)", check.name, strings.Join(check.containers, ", ")))
+		}
+	}
+
+	return violations
+}
+
+// dropsAllCapabilities tells whether all the capabilities are dropped, none but NET_BIND_SERVICE added back.
+func dropsAllCapabilities(capabilities *corev1.Capabilities) bool {
+	if capabilities == nil {
+		return false
+	}
+
+	dropsAll := false
+	for _, capability := range capabilities.Drop {
+		if capability == "ALL" {
+			dropsAll = true
+		}
+	}
+
+	for _, capability := range capabilities.Add {
+		if capability != "NET_BIND_SERVICE" {
+			return false
+		}
+	}
+
+	return dropsAll
+}
+
+// podSecurityCondition reports the parts of the workbench refused by the restricted level.
+//
+// The violations are the ones of the first blocked part, the others are likely the same.
+func podSecurityCondition(workbench defaultv1alpha1.Workbench, blocked []string, violations []string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               conditionBlocked,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NotBlocked",
+		Message:            "Nothing is refused by the Pod Security admission",
+		ObservedGeneration: workbench.Generation,
+	}
+
+	if len(blocked) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = string(eventReasonPodSecurityRestricted)
+		condition.Message = fmt.Sprintf(
+			"The namespace %q enforces the restricted Pod Security Standard, %s would be refused: %s. "+
+				"Label the namespace with %s=baseline to run them",
+			targetNamespace(workbench),
+			strings.Join(blocked, ", "),
+			strings.Join(violations, "; "),
+			podSecurityEnforceLabel,
+		)
+	}
+
+	return condition
+}
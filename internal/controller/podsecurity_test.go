@@ -0,0 +1,76 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Pod Security", func() {
+	hardened := func() *corev1.SecurityContext {
+		return &corev1.SecurityContext{
+			AllowPrivilegeEscalation: ptr.To(false),
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+		}
+	}
+
+	It("should accept a hardened pod", func() {
+		spec := corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot: ptr.To(true),
+				SeccompProfile: &corev1.SeccompProfile{
+					Type: corev1.SeccompProfileTypeRuntimeDefault,
+				},
+			},
+			Containers: []corev1.Container{
+				{Name: "app", SecurityContext: hardened()},
+			},
+		}
+
+		Expect(restrictedViolations(spec)).To(BeEmpty())
+	})
+
+	It("should list the containers per violation", func() {
+		sidecar := hardened()
+		sidecar.Capabilities.Add = []corev1.Capability{"SYS_ADMIN"}
+		sidecar.RunAsNonRoot = ptr.To(true)
+		sidecar.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost}
+
+		spec := corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "sidecar", SecurityContext: sidecar},
+			},
+			Containers: []corev1.Container{
+				{Name: "app"},
+			},
+		}
+
+		Expect(restrictedViolations(spec)).To(Equal([]string{
+			"allowPrivilegeEscalation != false (app)",
+			"unrestricted capabilities (sidecar, app)",
+			"runAsNonRoot != true (app)",
+			"seccompProfile (app)",
+		}))
+	})
+
+	It("should refuse the rendered pods", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{{Name: "wezterm"}}
+
+		service := initService(workbench, nil)
+
+		deployment := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		Expect(restrictedViolations(deployment.Spec.Template.Spec)).NotTo(BeEmpty())
+
+		job, err := initJob(workbench, ImagesConfig{}, AppConfig{}, GPUConfig{}, nil, 0, workbench.Spec.Apps[0], service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restrictedViolations(job.Spec.Template.Spec)).NotTo(BeEmpty())
+	})
+})
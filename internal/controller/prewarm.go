@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// prePullLabel marks the jobs only pulling the image of an app, it holds the app name.
+const prePullLabel = "chorus-tre.ch/pre-pull"
+
+// prePullReportedAnnotation marks the pre-pull jobs whose outcome was already reported.
+const prePullReportedAnnotation = "chorus-tre.ch/pre-pull-reported"
+
+// prePullDeadline bounds a pre-pull stuck on an image that cannot be pulled, in seconds.
+const prePullDeadline = int64(3600)
+
+// prewarmed tells whether the image of the app is pulled before it is started.
+func prewarmed(workbench defaultv1alpha1.Workbench, app defaultv1alpha1.WorkbenchApp) bool {
+	return workbench.Spec.Prewarm || app.Prewarm
+}
+
+// isPrePull tells whether the job only pulls an image, it is not an app.
+func isPrePull(job batchv1.Job) bool {
+	_, ok := job.Labels[prePullLabel]
+	return ok
+}
+
+// prePullJobName is the name of the pre-pull job of the app, next to its job.
+func prePullJobName(workbench defaultv1alpha1.Workbench, index int, app defaultv1alpha1.WorkbenchApp) string {
+	return jobName(workbench, index, app) + "-pre-pull"
+}
+
+// initPrePullJob creates the job pulling the image of the app job, on the same nodes.
+//
+// The no-op command may not exist in the image, the pull is done by then anyway. Nothing
+// else is requested, the GPUs are left to the apps.
+func initPrePullJob(workbench defaultv1alpha1.Workbench, index int, app defaultv1alpha1.WorkbenchApp, job batchv1.Job) batchv1.Job {
+	appContainer := job.Spec.Template.Spec.Containers[0]
+
+	prePull := batchv1.Job{}
+
+	prePull.Name = prePullJobName(workbench, index, app)
+	prePull.Namespace = job.Namespace
+
+	prePull.Labels = map[string]string{}
+	for key, value := range job.Labels {
+		prePull.Labels[key] = value
+	}
+
+	prePull.Labels[prePullLabel] = app.Name
+
+	// Reaped like the app jobs, then pulled again if still needed as the nodes collect
+	// the unused images.
+	oneDay := int32(24 * 3600)
+	prePull.Spec.TTLSecondsAfterFinished = &oneDay
+
+	deadline := prePullDeadline
+	prePull.Spec.ActiveDeadlineSeconds = &deadline
+
+	backoffLimit := int32(0)
+	prePull.Spec.BackoffLimit = &backoffLimit
+
+	podSpec := &prePull.Spec.Template.Spec
+
+	podSpec.ServiceAccountName = job.Spec.Template.Spec.ServiceAccountName
+	podSpec.ImagePullSecrets = job.Spec.Template.Spec.ImagePullSecrets
+	podSpec.NodeSelector = job.Spec.Template.Spec.NodeSelector
+	podSpec.Tolerations = job.Spec.Template.Spec.Tolerations
+	podSpec.Affinity = job.Spec.Template.Spec.Affinity
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+
+	// Allowed by the restricted pod security level, whatever the image.
+	nobody := int64(65534)
+	fal := false
+	tru := true
+
+	podSpec.SecurityContext = &corev1.PodSecurityContext{
+		RunAsNonRoot: &tru,
+		RunAsUser:    &nobody,
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+
+	podSpec.Containers = []corev1.Container{
+		{
+			Name:            "pre-pull",
+			Image:           appContainer.Image,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Command:         []string{"true"},
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: &fal,
+				Capabilities: &corev1.Capabilities{
+					Drop: []corev1.Capability{"ALL"},
+				},
+			},
+		},
+	}
+
+	return prePull
+}
+
+// prePullOutcome tells whether the pre-pull is over, whether the image could be pulled, and
+// how long it took.
+//
+// A failed no-op command still pulled the image, only the deadline tells it could not.
+func prePullOutcome(job batchv1.Job) (bool, bool, time.Duration) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type {
+		case batchv1.JobComplete, batchv1.JobFailed:
+			pulled := condition.Reason != batchv1.JobReasonDeadlineExceeded
+
+			duration := time.Duration(0)
+			if job.Status.StartTime != nil {
+				duration = condition.LastTransitionTime.Sub(job.Status.StartTime.Time).Round(time.Second)
+			}
+
+			return true, pulled, duration
+		}
+	}
+
+	return false, false, 0
+}
+
+// prewarmApp pulls the image of the app until it is started, and reports how it went.
+//
+// The pre-pulls are a best effort, their errors are only logged. The app job is the one
+// of the spec, it is looked up to know whether the app already runs.
+func (r *WorkbenchReconciler) prewarmApp(ctx context.Context, workbench *defaultv1alpha1.Workbench, index int, app defaultv1alpha1.WorkbenchApp, job batchv1.Job) {
+	log := log.FromContext(ctx)
+
+	prePull := initPrePullJob(*workbench, index, app, job)
+
+	foundPrePull := batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: prePull.Name, Namespace: prePull.Namespace}, &foundPrePull)
+	if err == nil {
+		r.reportPrePull(ctx, workbench, app, &foundPrePull)
+		return
+	}
+
+	if !apierrors.IsNotFound(err) {
+		log.V(1).Error(err, "Unable to get the pre-pull job", "job", prePull.Name)
+		return
+	}
+
+	// A running app pulled its image already.
+	foundJob := batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, &foundJob)
+	if err == nil && !isSuspended(foundJob) {
+		return
+	}
+
+	if client.IgnoreNotFound(err) != nil {
+		log.V(1).Error(err, "Unable to get the job", "job", job.Name)
+		return
+	}
+
+	if err := setOwner(workbench, &prePull, r.Scheme); err != nil {
+		log.V(1).Error(err, "Error setting the reference", "job", prePull.Name)
+		return
+	}
+
+	log.V(1).Info("New pre-pull job", "job", prePull.Name)
+
+	if err := r.Create(ctx, &prePull); client.IgnoreAlreadyExists(err) != nil {
+		log.V(1).Error(err, "Error creating the pre-pull job", "job", prePull.Name)
+	}
+}
+
+// reportPrePull emits, once, how long pulling the image took.
+func (r *WorkbenchReconciler) reportPrePull(ctx context.Context, workbench *defaultv1alpha1.Workbench, app defaultv1alpha1.WorkbenchApp, prePull *batchv1.Job) {
+	log := log.FromContext(ctx)
+
+	finished, pulled, duration := prePullOutcome(*prePull)
+	if !finished || prePull.Annotations[prePullReportedAnnotation] != "" {
+		return
+	}
+
+	image := prePull.Spec.Template.Spec.Containers[0].Image
+
+	if pulled {
+		emitEvent(
+			r.Recorder,
+			workbench,
+			corev1.EventTypeNormal,
+			eventReasonImagePrePulled,
+			"The image %q of the app %q was pre-pulled in %s",
+			image,
+			app.Name,
+			duration,
+		)
+	} else {
+		emitEvent(
+			r.Recorder,
+			workbench,
+			corev1.EventTypeWarning,
+			eventReasonImagePrePullFailed,
+			"The image %q of the app %q could not be pre-pulled in %s",
+			image,
+			app.Name,
+			duration,
+		)
+	}
+
+	if prePull.Annotations == nil {
+		prePull.Annotations = map[string]string{}
+	}
+
+	prePull.Annotations[prePullReportedAnnotation] = "true"
+
+	if err := r.Update(ctx, prePull); err != nil {
+		log.V(1).Error(err, "Unable to update the pre-pull job", "job", prePull.Name)
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Prewarm", func() {
+	config := Config{
+		Images: ImagesConfig{
+			AppsRepository: "apps",
+		},
+		GPU: GPUConfig{
+			NodeSelector: map[string]string{"chorus-tre.ch/gpu": "true"},
+		},
+	}
+
+	newWorkbench := func() defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+			{Name: "freesurfer", Version: "7.4", GPU: &defaultv1alpha1.WorkbenchGPU{Count: 1}, Prewarm: true},
+			{Name: "wezterm"},
+		}
+
+		return workbench
+	}
+
+	It("should tell the prewarmed apps", func() {
+		workbench := newWorkbench()
+
+		Expect(prewarmed(workbench, workbench.Spec.Apps[0])).To(BeTrue())
+		Expect(prewarmed(workbench, workbench.Spec.Apps[1])).To(BeFalse())
+
+		workbench.Spec.Prewarm = true
+		Expect(prewarmed(workbench, workbench.Spec.Apps[1])).To(BeTrue())
+	})
+
+	It("should pull the app image on the app nodes", func() {
+		workbench := newWorkbench()
+		app := workbench.Spec.Apps[0]
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, app, initService(workbench, config.Labels.PropagatedKeys))
+		Expect(err).NotTo(HaveOccurred())
+
+		prePull := initPrePullJob(workbench, 0, app, *job)
+
+		Expect(prePull.Name).To(Equal("workbench-0-freesurfer-pre-pull"))
+		Expect(isPrePull(prePull)).To(BeTrue())
+		Expect(isPrePull(*job)).To(BeFalse())
+
+		// Still an object of the app, for the cleanups.
+		Expect(prePull.Labels).To(HaveKeyWithValue(matchingLabel, "workbench"))
+		Expect(prePull.Labels).To(HaveKeyWithValue(appUIDLabel, "0-freesurfer"))
+		Expect(prePull.Spec.TTLSecondsAfterFinished).NotTo(BeNil())
+
+		podSpec := prePull.Spec.Template.Spec
+		Expect(podSpec.NodeSelector).To(Equal(config.GPU.NodeSelector))
+		Expect(podSpec.Containers).To(HaveLen(1))
+		Expect(podSpec.Containers[0].Image).To(Equal("apps/freesurfer:7.4"))
+		Expect(podSpec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		Expect(podSpec.Containers[0].Resources.Limits).To(BeEmpty())
+		Expect(restrictedViolations(podSpec)).To(BeEmpty())
+	})
+
+	It("should tell how the pre-pull went", func() {
+		job := batchv1.Job{}
+
+		finished, _, _ := prePullOutcome(job)
+		Expect(finished).To(BeFalse())
+
+		start := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		job.Status.StartTime = &start
+		job.Status.Conditions = []batchv1.JobCondition{
+			{
+				Type:               batchv1.JobFailed,
+				Status:             corev1.ConditionTrue,
+				Reason:             batchv1.JobReasonBackoffLimitExceeded,
+				LastTransitionTime: metav1.NewTime(start.Add(3 * time.Minute)),
+			},
+		}
+
+		// The no-op did not run, but the image is there.
+		finished, pulled, duration := prePullOutcome(job)
+		Expect(finished).To(BeTrue())
+		Expect(pulled).To(BeTrue())
+		Expect(duration).To(Equal(3 * time.Minute))
+
+		job.Status.Conditions[0].Reason = batchv1.JobReasonDeadlineExceeded
+
+		_, pulled, _ = prePullOutcome(job)
+		Expect(pulled).To(BeFalse())
+	})
+
+	Context("When a stopped app is prewarmed", func() {
+		const resourceName = "prewarm"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		prePullName := types.NamespacedName{
+			Name:      resourceName + "-0-freesurfer-pre-pull",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Prewarm = true
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "freesurfer", State: "Stopped"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should pull its image, outside of the apps", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			prePull := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, prePullName, prePull)).To(Succeed())
+
+			// The stopped app has no job, and its status does not follow the pre-pull.
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-0-freesurfer", Namespace: "default"}, &batchv1.Job{}))).To(BeTrue())
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Apps).To(HaveLen(1))
+			Expect(workbench.Status.Apps[0].Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusStopped))
+
+			// There is no job controller in envtest, the status is only set in memory.
+			start := metav1.Now()
+			prePull.Status.StartTime = &start
+			prePull.Status.Conditions = []batchv1.JobCondition{
+				{Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: start},
+			}
+
+			// Only the events of the pre-pull.
+			recorder = record.NewFakeRecorder(100)
+			controllerReconciler.Recorder = recorder
+
+			for range 2 {
+				controllerReconciler.reportPrePull(ctx, workbench, workbench.Spec.Apps[0], prePull)
+			}
+
+			Expect(recorder.Events).To(Receive(ContainSubstring("ImagePrePulled")))
+			Expect(recorder.Events).NotTo(Receive())
+
+			Expect(k8sClient.Get(ctx, prePullName, prePull)).To(Succeed())
+			Expect(prePull.Annotations).To(HaveKeyWithValue(prePullReportedAnnotation, "true"))
+
+			// Not prewarmed anymore, the pre-pull job goes away with the extra jobs.
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.Prewarm = false
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			err := k8sClient.Get(ctx, prePullName, &batchv1.Job{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// syntheticProbeLabel marks the workbenches created by the synthetic probe.
+const syntheticProbeLabel = "chorus-tre.ch/synthetic-probe"
+
+// syntheticProbePollInterval is the time between two checks of the probe workbench.
+const syntheticProbePollInterval = 5 * time.Second
+
+// SyntheticProbe periodically creates a tiny workbench and waits for it to be ready.
+//
+// It gives an assurance that sessions can actually be started, not just that the
+// operator is up and running.
+type SyntheticProbe struct {
+	Client   client.Client
+	Recorder record.EventRecorder
+	Config   SyntheticProbeConfig
+	Clock    clock.WithTicker
+}
+
+// NeedLeaderElection makes sure only one probe runs at once.
+func (p *SyntheticProbe) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements the manager.Runnable interface.
+func (p *SyntheticProbe) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("synthetic-probe")
+
+	// Remove the leftovers from crashed runs.
+	if err := p.sweep(ctx); err != nil {
+		log.Error(err, "Unable to clean up the previous probes")
+	}
+
+	ticker := p.Clock.NewTicker(p.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			success, duration, err := p.runOnce(ctx)
+			if err != nil {
+				log.Error(err, "Synthetic probe failed")
+			}
+
+			log.V(1).Info("Synthetic probe", "success", success, "duration", duration)
+		}
+	}
+}
+
+// runOnce creates a probe workbench, waits for it to be ready, and deletes it.
+func (p *SyntheticProbe) runOnce(ctx context.Context) (bool, time.Duration, error) {
+	start := p.Clock.Now()
+
+	workbench := initProbeWorkbench(p.Config, start)
+
+	if err := p.Client.Create(ctx, &workbench); err != nil {
+		p.report(false, 0, err)
+		return false, 0, err
+	}
+
+	defer func() {
+		if err := p.Client.Delete(ctx, &workbench); client.IgnoreNotFound(err) != nil {
+			log.FromContext(ctx).Error(err, "Unable to delete the probe", "workbench", workbench.Name)
+		}
+	}()
+
+	deadline := start.Add(p.Config.Timeout)
+
+	for {
+		if err := p.Client.Get(ctx, client.ObjectKeyFromObject(&workbench), &workbench); err != nil {
+			p.report(false, 0, err)
+			return false, 0, err
+		}
+
+		if isProbeReady(workbench) {
+			duration := p.Clock.Since(start)
+			p.report(true, duration, nil)
+			return true, duration, nil
+		}
+
+		if !p.Clock.Now().Before(deadline) {
+			err := fmt.Errorf("workbench %q not ready after %s", workbench.Name, p.Config.Timeout)
+			p.report(false, 0, err)
+			return false, 0, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, 0, ctx.Err()
+		case <-p.Clock.After(syntheticProbePollInterval):
+		}
+	}
+}
+
+// sweep deletes all the probe workbenches.
+func (p *SyntheticProbe) sweep(ctx context.Context) error {
+	return p.Client.DeleteAllOf(
+		ctx,
+		&defaultv1alpha1.Workbench{},
+		client.InNamespace(p.Config.Namespace),
+		client.MatchingLabels{syntheticProbeLabel: "true"},
+	)
+}
+
+// report exposes the probe result as metrics and an event on the probe namespace.
+func (p *SyntheticProbe) report(success bool, duration time.Duration, err error) {
+	namespace := &corev1.Namespace{}
+	namespace.Name = p.Config.Namespace
+
+	if success {
+		syntheticProbeSuccess.Set(1)
+		syntheticProbeDuration.Set(duration.Seconds())
+
+		emitEvent(
+			p.Recorder,
+			namespace,
+			corev1.EventTypeNormal,
+			eventReasonSyntheticProbeSucceeded,
+			"Synthetic workbench ready in %s",
+			duration.Round(time.Second),
+		)
+
+		return
+	}
+
+	syntheticProbeSuccess.Set(0)
+
+	emitEvent(
+		p.Recorder,
+		namespace,
+		corev1.EventTypeWarning,
+		eventReasonSyntheticProbeFailed,
+		"Synthetic workbench failed: %s",
+		err,
+	)
+}
+
+// initProbeWorkbench creates the minimal workbench used by the probe.
+func initProbeWorkbench(config SyntheticProbeConfig, now time.Time) defaultv1alpha1.Workbench {
+	workbench := defaultv1alpha1.Workbench{}
+	workbench.Name = fmt.Sprintf("synthetic-probe-%d", now.Unix())
+	workbench.Namespace = config.Namespace
+	workbench.Labels = map[string]string{
+		syntheticProbeLabel: "true",
+	}
+
+	if config.App != "" {
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+			{
+				Name: config.App,
+			},
+		}
+	}
+
+	return workbench
+}
+
+// isProbeReady tells whether the server and all the apps are running.
+func isProbeReady(workbench defaultv1alpha1.Workbench) bool {
+	if workbench.Status.Server.Status != defaultv1alpha1.WorkbenchStatusServerStatusRunning {
+		return false
+	}
+
+	if len(workbench.Status.Apps) < len(workbench.Spec.Apps) {
+		return false
+	}
+
+	for _, app := range workbench.Status.Apps {
+		if app.Status != defaultv1alpha1.WorkbenchStatusAppStatusRunning {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Synthetic probe", func() {
+	ctx := context.Background()
+
+	config := SyntheticProbeConfig{
+		Enabled:   true,
+		Namespace: "default",
+		Interval:  time.Minute,
+		Timeout:   time.Minute,
+		App:       "wezterm",
+	}
+
+	// runProbe runs one probe in the background and returns its outcome.
+	runProbe := func(probe *SyntheticProbe) chan bool {
+		result := make(chan bool, 1)
+
+		go func() {
+			defer GinkgoRecover()
+
+			success, _, _ := probe.runOnce(ctx)
+			result <- success
+		}()
+
+		return result
+	}
+
+	It("should succeed once the workbench is ready", func() {
+		fakeClock := clocktesting.NewFakeClock(time.Unix(1000, 0))
+
+		probe := &SyntheticProbe{
+			Client:   k8sClient,
+			Recorder: record.NewFakeRecorder(100),
+			Config:   config,
+			Clock:    fakeClock,
+		}
+
+		result := runProbe(probe)
+
+		workbench := &defaultv1alpha1.Workbench{}
+		key := client.ObjectKey{Name: "synthetic-probe-1000", Namespace: "default"}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, workbench)
+		}).Should(Succeed())
+
+		Expect(workbench.Labels).To(HaveKeyWithValue(syntheticProbeLabel, "true"))
+
+		// Fake what the reconciler would report.
+		workbench.Status.Server.Status = defaultv1alpha1.WorkbenchStatusServerStatusRunning
+		workbench.Status.Apps = []defaultv1alpha1.WorkbenchStatusApp{
+			{
+				Revision: -1,
+				Status:   defaultv1alpha1.WorkbenchStatusAppStatusRunning,
+			},
+		}
+		Expect(k8sClient.Status().Update(ctx, workbench)).To(Succeed())
+
+		Eventually(fakeClock.HasWaiters).Should(BeTrue())
+		fakeClock.Step(syntheticProbePollInterval)
+
+		Eventually(result).Should(Receive(BeTrue()))
+
+		// The workbench is gone (or going).
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, key, workbench)
+			return errors.IsNotFound(err) || !workbench.DeletionTimestamp.IsZero()
+		}).Should(BeTrue())
+	})
+
+	It("should fail when the workbench is not ready in time", func() {
+		fakeClock := clocktesting.NewFakeClock(time.Unix(2000, 0))
+
+		probe := &SyntheticProbe{
+			Client:   k8sClient,
+			Recorder: record.NewFakeRecorder(100),
+			Config:   config,
+			Clock:    fakeClock,
+		}
+
+		result := runProbe(probe)
+
+		for i := 0; i < int(config.Timeout/syntheticProbePollInterval); i++ {
+			Eventually(fakeClock.HasWaiters).Should(BeTrue())
+			fakeClock.Step(syntheticProbePollInterval)
+		}
+
+		Eventually(result).Should(Receive(BeFalse()))
+	})
+
+	It("should sweep the leftovers", func() {
+		leftover := &defaultv1alpha1.Workbench{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "synthetic-probe-leftover",
+				Namespace: "default",
+				Labels: map[string]string{
+					syntheticProbeLabel: "true",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, leftover)).To(Succeed())
+
+		probe := &SyntheticProbe{
+			Client:   k8sClient,
+			Recorder: record.NewFakeRecorder(100),
+			Config:   config,
+			Clock:    clocktesting.NewFakeClock(time.Now()),
+		}
+
+		Expect(probe.sweep(ctx)).To(Succeed())
+
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(leftover), leftover)
+			return errors.IsNotFound(err) || !leftover.DeletionTimestamp.IsZero()
+		}).Should(BeTrue())
+	})
+})
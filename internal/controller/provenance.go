@@ -0,0 +1,36 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// provenanceEnv tells the apps where they run, so they can tag their outputs without
+// querying the API.
+//
+// The names are a stable contract with the apps, they are only ever added to:
+//
+//   - CHORUS_WORKSPACE, the workspace of the workbench, i.e. its namespace;
+//   - CHORUS_NAMESPACE, the namespace the app runs in, its target namespace if any;
+//   - CHORUS_WORKBENCH, the name of the workbench;
+//   - CHORUS_POD_NAME and CHORUS_POD_UID, the pod of the app, from the downward API.
+func provenanceEnv(workbench defaultv1alpha1.Workbench) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "CHORUS_WORKSPACE", Value: workbench.Namespace},
+		{Name: "CHORUS_NAMESPACE", Value: targetNamespace(workbench)},
+		{Name: "CHORUS_WORKBENCH", Value: workbench.Name},
+		{
+			Name: "CHORUS_POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		},
+		{
+			Name: "CHORUS_POD_UID",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.uid"},
+			},
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Provenance", func() {
+	It("should tell the apps and their sidecars where they run", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "workspace-1"
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+			{
+				Name:              "wezterm",
+				SidecarContainers: []corev1.Container{{Name: "dicom-listener", Image: "dicom"}},
+			},
+		}
+
+		config := Config{Images: ImagesConfig{AppsRepository: "apps"}}
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, workbench.Spec.Apps[0], initService(workbench, config.Labels.PropagatedKeys))
+		Expect(err).NotTo(HaveOccurred())
+
+		// The names and values are a contract with the apps.
+		expected := []corev1.EnvVar{
+			{Name: "CHORUS_WORKSPACE", Value: "workspace-1"},
+			{Name: "CHORUS_NAMESPACE", Value: "workspace-1"},
+			{Name: "CHORUS_WORKBENCH", Value: "workbench"},
+			{
+				Name: "CHORUS_POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			},
+			{
+				Name: "CHORUS_POD_UID",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.uid"},
+				},
+			},
+		}
+
+		for _, container := range append(job.Spec.Template.Spec.Containers, job.Spec.Template.Spec.InitContainers...) {
+			Expect(container.Env).To(ContainElements(expected), container.Name)
+		}
+	})
+})
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// replicatedPullSecretLabel marks the copies of the shared pull secret, only those are updated and deleted.
+const replicatedPullSecretLabel = "chorus-tre.ch/replicated-pull-secret"
+
+// replicatedFromVersionAnnotation is the resource version of the source secret the copy was made from.
+const replicatedFromVersionAnnotation = "chorus-tre.ch/replicated-from-version"
+
+// replicatesPullSecret tells whether the shared pull secret has to be copied into the namespace.
+func (c ImagesConfig) replicatesPullSecret(namespace string) bool {
+	return c.ReplicatePullSecret.Name != "" && c.ReplicatePullSecret.Namespace != namespace
+}
+
+// replicatePullSecret copies the shared pull secret into the target namespace of the workbench.
+//
+// The copy is shared by all the workbenches of the namespace and follows the source rotations.
+// A secret with the same name, not made by the operator, is left untouched.
+func (r *WorkbenchReconciler) replicatePullSecret(ctx context.Context, workbench defaultv1alpha1.Workbench) error {
+	log := log.FromContext(ctx)
+
+	namespace := targetNamespace(workbench)
+	if !r.Config.Images.replicatesPullSecret(namespace) {
+		return nil
+	}
+
+	source := corev1.Secret{}
+	if err := r.Get(ctx, r.Config.Images.ReplicatePullSecret, &source); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("The pull secret to replicate is missing", "secret", r.Config.Images.ReplicatePullSecret)
+			return nil
+		}
+
+		return err
+	}
+
+	foundSecret := corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: source.Name, Namespace: namespace}, &foundSecret)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		secret := corev1.Secret{}
+		secret.Name = source.Name
+		secret.Namespace = namespace
+		secret.Labels = map[string]string{
+			replicatedPullSecretLabel: "true",
+		}
+		secret.Annotations = map[string]string{
+			replicatedFromVersionAnnotation: source.ResourceVersion,
+		}
+		secret.Type = source.Type
+		secret.Data = maps.Clone(source.Data)
+
+		log.V(1).Info("Replicating the pull secret", "secret", secret.Name)
+
+		return r.Create(ctx, &secret)
+	}
+
+	if foundSecret.Labels[replicatedPullSecretLabel] != "true" {
+		return nil
+	}
+
+	if foundSecret.Annotations[replicatedFromVersionAnnotation] == source.ResourceVersion {
+		return nil
+	}
+
+	if foundSecret.Annotations == nil {
+		foundSecret.Annotations = map[string]string{}
+	}
+
+	foundSecret.Annotations[replicatedFromVersionAnnotation] = source.ResourceVersion
+	foundSecret.Data = maps.Clone(source.Data)
+
+	log.V(1).Info("Updating the replicated pull secret", "secret", foundSecret.Name)
+
+	return r.Update(ctx, &foundSecret)
+}
+
+// cleanupReplicatedPullSecret deletes the copy of the shared pull secret with the last workbench of the namespace.
+//
+// The workbenches of other namespaces may target it too, they are all looked at.
+func (r *WorkbenchReconciler) cleanupReplicatedPullSecret(ctx context.Context, workbench defaultv1alpha1.Workbench) error {
+	log := log.FromContext(ctx)
+
+	namespace := targetNamespace(workbench)
+	if !r.Config.Images.replicatesPullSecret(namespace) {
+		return nil
+	}
+
+	workbenchList := defaultv1alpha1.WorkbenchList{}
+	if err := r.List(ctx, &workbenchList); err != nil {
+		return err
+	}
+
+	for _, item := range workbenchList.Items {
+		if item.UID != workbench.UID && item.DeletionTimestamp.IsZero() && targetNamespace(item) == namespace {
+			return nil
+		}
+	}
+
+	foundSecret := corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: r.Config.Images.ReplicatePullSecret.Name, Namespace: namespace}, &foundSecret)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if foundSecret.Labels[replicatedPullSecretLabel] != "true" {
+		return nil
+	}
+
+	log.V(1).Info("Deleting the replicated pull secret", "secret", foundSecret.Name)
+
+	return client.IgnoreNotFound(r.Delete(ctx, &foundSecret))
+}
+
+// workbenchesForSecret maps a secret to the workbenches it matters to: its owner, or its workbench
+// from a target namespace, or all of them for the shared pull secret.
+//
+// The secrets have a single watch, so each of their events is mapped once, not by three handlers.
+func (r *WorkbenchReconciler) workbenchesForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	if requests := workbenchForOwnedChild(ctx, obj); len(requests) > 0 {
+		return requests
+	}
+
+	if requests := workbenchForTargetedChild(ctx, obj); len(requests) > 0 {
+		return requests
+	}
+
+	return r.workbenchesForPullSecret(ctx, obj)
+}
+
+// workbenchesForPullSecret maps the shared pull secret to all the workbenches, to propagate its rotations.
+func (r *WorkbenchReconciler) workbenchesForPullSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	if obj.GetName() != r.Config.Images.ReplicatePullSecret.Name || obj.GetNamespace() != r.Config.Images.ReplicatePullSecret.Namespace {
+		return nil
+	}
+
+	workbenchList := defaultv1alpha1.WorkbenchList{}
+	if err := r.List(ctx, &workbenchList); err != nil {
+		log.Error(err, "Unable to list the workbenches")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(workbenchList.Items))
+	for _, item := range workbenchList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace},
+		})
+	}
+
+	return requests
+}
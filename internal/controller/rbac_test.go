@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// expectedRules are the permissions of the manager, per "group/resource".
+//
+// Any change to the RBAC markers must be reflected here, so it's a conscious one.
+var expectedRules = map[string][]string{
+	"apps/deployments":                  {"create", "delete", "deletecollection", "get", "list", "patch", "update", "watch"},
+	"apps/deployments/status":           {"get"},
+	"batch/jobs":                        {"create", "delete", "deletecollection", "get", "list", "patch", "update", "watch"},
+	"batch/jobs/status":                 {"get"},
+	"/configmaps":                       {"create", "delete", "get", "list", "patch", "update", "watch"},
+	"/events":                           {"create", "patch"},
+	"/namespaces":                       {"get", "list", "watch"},
+	"/persistentvolumeclaims":           {"create", "delete", "get", "list", "update", "watch"},
+	"/pods":                             {"deletecollection", "get", "list", "watch"},
+	"/secrets":                          {"create", "delete", "get", "list", "update", "watch"},
+	"/services":                         {"create", "delete", "get", "list", "patch", "update", "watch"},
+	"/services/status":                  {"get"},
+	"default.chorus-tre.ch/workbenches": {"create", "delete", "get", "list", "patch", "update", "watch"},
+	"default.chorus-tre.ch/workbenches/finalizers":    {"update"},
+	"default.chorus-tre.ch/workbenches/status":        {"get", "patch", "update"},
+	"default.chorus-tre.ch/workbenchsummaries":        {"create", "delete", "get", "list", "patch", "update", "watch"},
+	"default.chorus-tre.ch/workbenchsummaries/status": {"get", "patch", "update"},
+}
+
+// readRules loads the rules of a ClusterRole file, ignoring the Helm template lines.
+func readRules(path string) map[string][]string {
+	content, err := os.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+
+	lines := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.Contains(line, "{{") || line == "---" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	role := rbacv1.ClusterRole{}
+	Expect(yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &role)).To(Succeed())
+
+	rules := map[string][]string{}
+	for _, rule := range role.Rules {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				key := group + "/" + resource
+				rules[key] = append(rules[key], rule.Verbs...)
+			}
+		}
+	}
+
+	return rules
+}
+
+var _ = Describe("RBAC", func() {
+	for _, path := range []string{
+		filepath.Join("..", "..", "config", "rbac", "role.yaml"),
+		filepath.Join("..", "..", "charts", "workbench-operator", "templates", "manager-rbac.yaml"),
+	} {
+		It("should grant exactly the expected verbs in "+path, func() {
+			Expect(readRules(path)).To(Equal(expectedRules))
+		})
+	}
+})
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// referencesCondition tells whether all the references stay within the workbench namespace.
+//
+// The webhook refuses such workbenches already, this covers the clusters running without it.
+func referencesCondition(workbench defaultv1alpha1.Workbench) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               conditionReferences,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ReferencesLocal",
+		Message:            "All the references are within the namespace",
+		ObservedGeneration: workbench.Generation,
+	}
+
+	errs := workbench.ValidateReferences()
+	if len(errs) == 0 {
+		return condition
+	}
+
+	fields := make([]string, 0, len(errs))
+	for _, err := range errs {
+		fields = append(fields, fmt.Sprintf("%s=%q", err.Field, err.BadValue))
+	}
+
+	condition.Status = metav1.ConditionFalse
+	condition.Reason = string(eventReasonCrossNamespaceReference)
+	condition.Message = fmt.Sprintf("References outside of the namespace, nothing is created: %s", strings.Join(fields, ", "))
+
+	return condition
+}
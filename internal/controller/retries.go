@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// appRestarts counts the restarts of the app container, in the pods of the job, and tells
+// why it last terminated.
+//
+// Only a running job has pods to look at, the failures of a finished one are in its status.
+func appRestarts(pods []corev1.Pod, job batchv1.Job) (int32, string) {
+	if job.Status.Active == 0 || len(job.Spec.Template.Spec.Containers) == 0 {
+		return 0, ""
+	}
+
+	return containerRestarts(pods, job.Spec.Template.Spec.Containers[0].Name)
+}
+
+// containerRestarts sums the restarts of the container, and tells its latest termination.
+func containerRestarts(pods []corev1.Pod, name string) (int32, string) {
+	restarts := int32(0)
+	var last *corev1.ContainerStateTerminated
+
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name != name {
+				continue
+			}
+
+			restarts += status.RestartCount
+
+			for _, terminated := range []*corev1.ContainerStateTerminated{status.LastTerminationState.Terminated, status.State.Terminated} {
+				if terminated != nil && (last == nil || last.FinishedAt.Before(&terminated.FinishedAt)) {
+					last = terminated
+				}
+			}
+		}
+	}
+
+	if last == nil {
+		return restarts, ""
+	}
+
+	reason := last.Reason
+	if reason == "" {
+		reason = "Terminated"
+	}
+
+	return restarts, fmt.Sprintf("%s, exit code %d", reason, last.ExitCode)
+}
+
+// reportRetry emits that the app failed, and how many times it will be restarted still.
+func (r *WorkbenchReconciler) reportRetry(workbench *defaultv1alpha1.Workbench, app defaultv1alpha1.WorkbenchApp, status defaultv1alpha1.WorkbenchStatusApp, reason string) {
+	if reason == "" {
+		reason = "unknown reason"
+	}
+
+	remaining := int32(0)
+	if status.RemainingRetries != nil {
+		remaining = *status.RemainingRetries
+	}
+
+	emitEvent(
+		r.Recorder,
+		workbench,
+		corev1.EventTypeWarning,
+		eventReasonAppRetrying,
+		"The app %q failed (%s), attempt %d with %d retries left",
+		app.Name,
+		reason,
+		status.Attempts,
+		remaining,
+	)
+}
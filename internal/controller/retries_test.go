@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Retries", func() {
+	at := func(minutes int) metav1.Time {
+		return metav1.NewTime(time.Date(2024, 1, 1, 0, minutes, 0, 0, time.UTC))
+	}
+
+	It("should count the restarts of the app container only", func() {
+		pods := []corev1.Pod{{}, {}}
+		pods[0].Status.ContainerStatuses = []corev1.ContainerStatus{
+			{
+				Name:         "wezterm",
+				RestartCount: 2,
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1, FinishedAt: at(1)},
+				},
+			},
+			{
+				Name:         "sidecar",
+				RestartCount: 5,
+			},
+		}
+		pods[1].Status.ContainerStatuses = []corev1.ContainerStatus{
+			{
+				Name:         "wezterm",
+				RestartCount: 1,
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137, FinishedAt: at(2)},
+				},
+			},
+		}
+
+		restarts, reason := containerRestarts(pods, "wezterm")
+		Expect(restarts).To(Equal(int32(3)))
+		Expect(reason).To(Equal("OOMKilled, exit code 137"))
+
+		restarts, reason = containerRestarts(nil, "wezterm")
+		Expect(restarts).To(BeZero())
+		Expect(reason).To(BeEmpty())
+	})
+
+	It("should emit each failed attempt", func() {
+		recorder := record.NewFakeRecorder(10)
+		r := &WorkbenchReconciler{Recorder: recorder}
+
+		workbench := &defaultv1alpha1.Workbench{}
+		app := defaultv1alpha1.WorkbenchApp{Name: "wezterm"}
+
+		two := int32(2)
+		r.reportRetry(workbench, app, defaultv1alpha1.WorkbenchStatusApp{Attempts: 4, RemainingRetries: &two}, "Error, exit code 1")
+
+		Expect(recorder.Events).To(Receive(Equal(`Warning AppRetrying The app "wezterm" failed (Error, exit code 1), attempt 4 with 2 retries left`)))
+	})
+})
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// addScheduling places the pod on the nodes of the workbench, or of the app when it tells.
+//
+// Each field of the app, when set, replaces the workbench one. They are copied, the GPU
+// constraints are added on top of them.
+func addScheduling(podSpec *corev1.PodSpec, workbench defaultv1alpha1.Workbench, app *defaultv1alpha1.WorkbenchApp) {
+	nodeSelector := workbench.Spec.NodeSelector
+	tolerations := workbench.Spec.Tolerations
+	affinity := workbench.Spec.Affinity
+
+	if app != nil {
+		if app.NodeSelector != nil {
+			nodeSelector = app.NodeSelector
+		}
+
+		if app.Tolerations != nil {
+			tolerations = app.Tolerations
+		}
+
+		if app.Affinity != nil {
+			affinity = app.Affinity
+		}
+	}
+
+	for key, value := range nodeSelector {
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = map[string]string{}
+		}
+
+		podSpec.NodeSelector[key] = value
+	}
+
+	for _, toleration := range tolerations {
+		podSpec.Tolerations = append(podSpec.Tolerations, *toleration.DeepCopy())
+	}
+
+	if affinity != nil {
+		podSpec.Affinity = affinity.DeepCopy()
+	}
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Scheduling", func() {
+	config := Config{
+		GPU: GPUConfig{
+			NodeSelector: map[string]string{"chorus-tre.ch/gpu": "true"},
+		},
+	}
+
+	poolAffinity := func(pool string) *corev1.Affinity {
+		return &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{pool}},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	newWorkbench := func() defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+		workbench.Spec.NodeSelector = map[string]string{"pool": "cpu"}
+		workbench.Spec.Tolerations = []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "workbench"},
+		}
+		workbench.Spec.Affinity = poolAffinity("cpu")
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+			{Name: "wezterm"},
+			{
+				Name:         "freesurfer",
+				NodeSelector: map[string]string{"pool": "highmem"},
+				Affinity:     poolAffinity("highmem"),
+			},
+		}
+
+		return workbench
+	}
+
+	It("should place the server and the apps on the workbench nodes", func() {
+		workbench := newWorkbench()
+
+		deployment := initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+		podSpec := deployment.Spec.Template.Spec
+
+		Expect(podSpec.NodeSelector).To(Equal(workbench.Spec.NodeSelector))
+		Expect(podSpec.Tolerations).To(Equal(workbench.Spec.Tolerations))
+		Expect(podSpec.Affinity).To(Equal(workbench.Spec.Affinity))
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, workbench.Spec.Apps[0], initService(workbench, config.Labels.PropagatedKeys))
+		Expect(err).NotTo(HaveOccurred())
+		podSpec = job.Spec.Template.Spec
+
+		Expect(podSpec.NodeSelector).To(Equal(workbench.Spec.NodeSelector))
+		Expect(podSpec.Tolerations).To(Equal(workbench.Spec.Tolerations))
+		Expect(podSpec.Affinity).To(Equal(workbench.Spec.Affinity))
+	})
+
+	It("should let the app fields win over the workbench ones", func() {
+		workbench := newWorkbench()
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 1, workbench.Spec.Apps[1], initService(workbench, config.Labels.PropagatedKeys))
+		Expect(err).NotTo(HaveOccurred())
+		podSpec := job.Spec.Template.Spec
+
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"pool": "highmem"}))
+		Expect(podSpec.Affinity).To(Equal(poolAffinity("highmem")))
+
+		// Not overridden.
+		Expect(podSpec.Tolerations).To(Equal(workbench.Spec.Tolerations))
+	})
+
+	It("should add the GPU nodes without touching the spec", func() {
+		workbench := newWorkbench()
+		workbench.Spec.Apps[0].GPU = &defaultv1alpha1.WorkbenchGPU{Count: 1}
+
+		job, err := initJob(workbench, config.Images, config.Apps, config.GPU, config.Labels.PropagatedKeys, 0, workbench.Spec.Apps[0], initService(workbench, config.Labels.PropagatedKeys))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(job.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"pool": "cpu", "chorus-tre.ch/gpu": "true"}))
+		Expect(workbench.Spec.NodeSelector).To(Equal(map[string]string{"pool": "cpu"}))
+	})
+
+	It("should reschedule the server on a new affinity", func() {
+		workbench := newWorkbench()
+		deployment := initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+
+		workbench.Spec.Affinity = poolAffinity("gpu")
+		workbench.Spec.NodeSelector = nil
+
+		source := initDeployment(workbench, config.Images, config.Server, config.GPU, config.Labels.PropagatedKeys)
+		Expect(updateDeployment(source, &deployment, config.Labels.PropagatedKeys)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Affinity).To(Equal(poolAffinity("gpu")))
+		Expect(deployment.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+		Expect(updateDeployment(source, &deployment, config.Labels.PropagatedKeys)).To(BeFalse())
+	})
+})
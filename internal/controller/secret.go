@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// imagePullSecrets lists the pull secrets of the pods, the replicated shared one included.
+func imagePullSecrets(workbench defaultv1alpha1.Workbench, config ImagesConfig) []corev1.LocalObjectReference {
+	var references []corev1.LocalObjectReference
+
+	for _, name := range workbench.Spec.ImagePullSecrets {
+		references = append(references, corev1.LocalObjectReference{
+			Name: name,
+		})
+	}
+
+	replicated := config.ReplicatePullSecret.Name
+	if replicated != "" && !slices.Contains(workbench.Spec.ImagePullSecrets, replicated) {
+		references = append(references, corev1.LocalObjectReference{
+			Name: replicated,
+		})
+	}
+
+	return references
+}
+
+// checkImagePullSecrets verifies that the image pull secrets exist and can be used to pull images.
+//
+// It's done once per reconcile, for the server and all the apps.
+func (r *WorkbenchReconciler) checkImagePullSecrets(ctx context.Context, workbench defaultv1alpha1.Workbench) (metav1.Condition, error) {
+	invalid := []string{}
+
+	for _, name := range workbench.Spec.ImagePullSecrets {
+		secret := corev1.Secret{}
+
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: targetNamespace(workbench)}, &secret)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return metav1.Condition{}, err
+			}
+
+			invalid = append(invalid, fmt.Sprintf("%q is missing", name))
+			continue
+		}
+
+		if secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg {
+			invalid = append(invalid, fmt.Sprintf("%q has type %q", name, secret.Type))
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionImagePullSecrets,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ImagePullSecretsFound",
+		Message:            "All the image pull secrets were found",
+		ObservedGeneration: workbench.Generation,
+	}
+
+	if len(invalid) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = string(eventReasonImagePullSecretInvalid)
+		condition.Message = fmt.Sprintf("Invalid image pull secrets: %s", strings.Join(invalid, ", "))
+	}
+
+	return condition, nil
+}
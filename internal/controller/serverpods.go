@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// serverContainerName is the Xpra server container, in the pods of the deployment.
+const serverContainerName = "xpra-server"
+
+// serverPods lists the pods of the Xpra server, to count the restarts of its containers and
+// to tell its activity.
+func (r *WorkbenchReconciler) serverPods(ctx context.Context, workbench defaultv1alpha1.Workbench) ([]corev1.Pod, error) {
+	pods := corev1.PodList{}
+	if err := r.List(
+		ctx,
+		&pods,
+		client.InNamespace(targetNamespace(workbench)),
+		childSelector(workbench),
+	); err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+// workbenchForServerPod maps a server pod to its workbench.
+//
+// The pods are owned by a replica set, not by the workbench, only their labels tell.
+func workbenchForServerPod(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+
+	name := labels[matchingLabel]
+	if name == "" {
+		return nil
+	}
+
+	namespace := obj.GetNamespace()
+	if source := labels[sourceNamespaceLabel]; source != "" {
+		namespace = source
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}},
+	}
+}
+
+// serverPodStatusChanged only lets through the server pods whose containers changed, e.g.
+// a restart or a readiness flip, or whose activity was told.
+//
+// The creations and deletions are followed through the deployment already.
+var serverPodStatusChanged = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+
+		newPod, ok := e.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+
+		if oldPod.Annotations[lastActivityAnnotation] != newPod.Annotations[lastActivityAnnotation] {
+			return true
+		}
+
+		return !equality.Semantic.DeepEqual(oldPod.Status.ContainerStatuses, newPod.Status.ContainerStatuses)
+	},
+}
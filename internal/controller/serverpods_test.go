@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Server pods", func() {
+	It("should map the server pods to their workbench", func() {
+		pod := &corev1.Pod{}
+		pod.Namespace = "research"
+		Expect(workbenchForServerPod(context.Background(), pod)).To(BeEmpty())
+
+		pod.Labels = map[string]string{matchingLabel: "workbench"}
+		Expect(workbenchForServerPod(context.Background(), pod)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "workbench", Namespace: "research"}},
+		}))
+
+		pod.Namespace = "compute"
+		pod.Labels[sourceNamespaceLabel] = "research"
+		Expect(workbenchForServerPod(context.Background(), pod)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "workbench", Namespace: "research"}},
+		}))
+	})
+
+	It("should only let the container changes through", func() {
+		oldPod := &corev1.Pod{}
+		oldPod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: serverContainerName, Ready: true}}
+
+		newPod := oldPod.DeepCopy()
+		newPod.Annotations = map[string]string{"touched": "true"}
+		Expect(serverPodStatusChanged.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod})).To(BeFalse())
+
+		newPod.Status.ContainerStatuses[0].Ready = false
+		newPod.Status.ContainerStatuses[0].RestartCount = 1
+		Expect(serverPodStatusChanged.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod})).To(BeTrue())
+
+		Expect(serverPodStatusChanged.Create(event.CreateEvent{Object: newPod})).To(BeFalse())
+	})
+
+	It("should let the told activity through", func() {
+		oldPod := &corev1.Pod{}
+
+		newPod := oldPod.DeepCopy()
+		newPod.Annotations = map[string]string{lastActivityAnnotation: "2026-01-01T10:00:00Z"}
+		Expect(serverPodStatusChanged.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod})).To(BeTrue())
+	})
+})
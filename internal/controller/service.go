@@ -1,23 +1,27 @@
 package controller
 
 import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
 )
 
-func initService(workbench defaultv1alpha1.Workbench) corev1.Service {
+func initService(workbench defaultv1alpha1.Workbench, labelKeys []string) corev1.Service {
 	service := corev1.Service{}
 	service.Name = workbench.Name
-	service.Namespace = workbench.Namespace
+	service.Namespace = targetNamespace(workbench)
 
 	// Labels
-	labels := map[string]string{
-		matchingLabel: workbench.Name,
-	}
+	labels := map[string]string(childSelector(workbench))
 
-	service.Labels = labels
+	service.Labels = childLabels(workbench, labelKeys)
 	service.Spec.Selector = labels
 
 	service.Spec.Ports = []corev1.ServicePort{
@@ -37,8 +41,62 @@ func initService(workbench defaultv1alpha1.Workbench) corev1.Service {
 		},
 	}
 
+	addObserverPorts(&service, workbench)
+
 	// Default type for internal usage.
 	service.Spec.Type = "ClusterIP"
 
 	return service
 }
+
+// updateService makes the destination service like the source one.
+//
+// Only the labels and the ports, which follow the observers, are reconciled.
+func updateService(source corev1.Service, destination *corev1.Service, labelKeys []string) bool {
+	updated := updateLabels(source.Labels, &destination.Labels, labelKeys)
+
+	if !equality.Semantic.DeepEqual(destination.Spec.Ports, source.Spec.Ports) {
+		destination.Spec.Ports = source.Spec.Ports
+		updated = true
+	}
+
+	return updated
+}
+
+// serviceRecreated tells whether the service is not the one recorded in the status.
+//
+// The first service seen is not a recreation.
+func serviceRecreated(workbench defaultv1alpha1.Workbench, service corev1.Service) bool {
+	return workbench.Status.ServiceUID != "" && workbench.Status.ServiceUID != service.UID
+}
+
+// restartAppPods deletes the pods of the running apps, their jobs start new ones.
+//
+// The new pods resolve the service afresh, instead of hanging on a stale X connection.
+func (r *WorkbenchReconciler) restartAppPods(ctx context.Context, workbench defaultv1alpha1.Workbench) error {
+	log := log.FromContext(ctx)
+
+	jobList, err := r.findJobs(ctx, workbench)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobList.Items {
+		if job.Spec.Suspend != nil && *job.Spec.Suspend || isPrePull(job) {
+			continue
+		}
+
+		log.V(1).Info("Restart the app pods", "job", job.Name)
+
+		if err := r.DeleteAllOf(
+			ctx,
+			&corev1.Pod{},
+			client.InNamespace(job.Namespace),
+			client.MatchingLabels{batchv1.JobNameLabel: job.Name},
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
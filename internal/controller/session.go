@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// rotateSessionSecretAnnotation is put on the workbench to request a new session secret.
+const rotateSessionSecretAnnotation = "chorus-tre.ch/rotate-session-secret"
+
+// sessionSecretRevisionAnnotation counts the rotations, the server restarts when it changes.
+const sessionSecretRevisionAnnotation = "chorus-tre.ch/session-secret-revision"
+
+// sessionSecretKey is the key of the token within the secret.
+const sessionSecretKey = "token"
+
+// initSessionSecret creates the secret holding the token protecting the Xpra session.
+func initSessionSecret(workbench defaultv1alpha1.Workbench, labelKeys []string) corev1.Secret {
+	secret := corev1.Secret{}
+	secret.Name = fmt.Sprintf("%s-xpra-auth", workbench.Name)
+	secret.Namespace = targetNamespace(workbench)
+
+	secret.Labels = childLabels(workbench, labelKeys)
+	secret.Annotations = map[string]string{
+		sessionSecretRevisionAnnotation: "1",
+	}
+
+	secret.Type = corev1.SecretTypeOpaque
+
+	return secret
+}
+
+// newSessionToken generates a random token.
+func newSessionToken() ([]byte, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	return []byte(hex.EncodeToString(token)), nil
+}
+
+// addSessionAuth makes the Xpra server require the session token, read from the secret.
+//
+// The revision is put on the pod template so a rotation restarts the server.
+func addSessionAuth(deployment *appsv1.Deployment, secret corev1.Secret) {
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
+
+	deployment.Spec.Template.Annotations[sessionSecretRevisionAnnotation] = secret.Annotations[sessionSecretRevisionAnnotation]
+
+	server := &deployment.Spec.Template.Spec.Containers[0]
+	server.Env = append(server.Env, corev1.EnvVar{
+		Name: "XPRA_PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secret.Name,
+				},
+				Key: sessionSecretKey,
+			},
+		},
+	})
+}
+
+// reconcileSessionSecret creates the session secret when missing, and rotates it on demand.
+func (r *WorkbenchReconciler) reconcileSessionSecret(ctx context.Context, workbench *defaultv1alpha1.Workbench) (*corev1.Secret, error) {
+	log := log.FromContext(ctx)
+
+	secret := initSessionSecret(*workbench, r.Config.Labels.PropagatedKeys)
+
+	foundSecret := corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, &foundSecret)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		token, err := newSessionToken()
+		if err != nil {
+			return nil, err
+		}
+
+		secret.Data = map[string][]byte{
+			sessionSecretKey: token,
+		}
+
+		if err := setOwner(workbench, &secret, r.Scheme); err != nil {
+			return nil, err
+		}
+
+		log.V(1).Info("Creating the session secret", "secret", secret.Name)
+
+		if err := r.Create(ctx, &secret); err != nil {
+			return nil, err
+		}
+
+		return &secret, nil
+	}
+
+	updated := updateLabels(secret.Labels, &foundSecret.Labels, r.Config.Labels.PropagatedKeys)
+
+	_, rotate := workbench.Annotations[rotateSessionSecretAnnotation]
+	if rotate {
+		token, err := newSessionToken()
+		if err != nil {
+			return nil, err
+		}
+
+		revision, _ := strconv.Atoi(foundSecret.Annotations[sessionSecretRevisionAnnotation])
+
+		if foundSecret.Annotations == nil {
+			foundSecret.Annotations = map[string]string{}
+		}
+
+		foundSecret.Annotations[sessionSecretRevisionAnnotation] = strconv.Itoa(revision + 1)
+		foundSecret.Data = map[string][]byte{
+			sessionSecretKey: token,
+		}
+
+		updated = true
+	}
+
+	if updated {
+		log.V(1).Info("Updating the session secret", "secret", foundSecret.Name, "rotate", rotate)
+
+		if err := r.Update(ctx, &foundSecret); err != nil {
+			return nil, err
+		}
+	}
+
+	if rotate {
+		emitEvent(
+			r.Recorder,
+			workbench,
+			corev1.EventTypeNormal,
+			eventReasonRotatedSessionSecret,
+			"Rotated the session secret %q, the server restarts",
+			foundSecret.Name,
+		)
+
+		// The request is fulfilled, the annotation goes with the metadata writes of the pass.
+		delete(workbench.Annotations, rotateSessionSecretAnnotation)
+	}
+
+	return &foundSecret, nil
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// sessionStateVolumeName is the volume holding the Xpra session state.
+const sessionStateVolumeName = "xpra-session"
+
+// sessionStateMountPath is where the xpra-server image keeps the state of its sessions.
+const sessionStateMountPath = "/home/xpra/.xpra"
+
+// sessionStateStorage is the size of the claim, the session state is small.
+var sessionStateStorage = resource.MustParse("100Mi")
+
+// initSessionClaim creates the claim persisting the Xpra session across server restarts.
+func initSessionClaim(workbench defaultv1alpha1.Workbench, labelKeys []string) corev1.PersistentVolumeClaim {
+	claim := corev1.PersistentVolumeClaim{}
+	claim.Name = fmt.Sprintf("%s-xpra-session", workbench.Name)
+	claim.Namespace = targetNamespace(workbench)
+
+	claim.Labels = childLabels(workbench, labelKeys)
+
+	claim.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{
+		corev1.ReadWriteOnce,
+	}
+	claim.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: sessionStateStorage,
+	}
+
+	return claim
+}
+
+// addSessionState mounts the session state claim into the Xpra server.
+func addSessionState(deployment *appsv1.Deployment, claim corev1.PersistentVolumeClaim) {
+	deployment.Spec.Template.Spec.Volumes = append(deployment.Spec.Template.Spec.Volumes, corev1.Volume{
+		Name: sessionStateVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claim.Name,
+			},
+		},
+	})
+
+	server := &deployment.Spec.Template.Spec.Containers[0]
+	server.VolumeMounts = append(server.VolumeMounts, corev1.VolumeMount{
+		Name:      sessionStateVolumeName,
+		MountPath: sessionStateMountPath,
+	})
+}
+
+// reconcileSessionClaim creates the session state claim when missing.
+//
+// The claim is kept when the persistent session is disabled, only its mount goes.
+func (r *WorkbenchReconciler) reconcileSessionClaim(ctx context.Context, workbench *defaultv1alpha1.Workbench) (*corev1.PersistentVolumeClaim, error) {
+	log := log.FromContext(ctx)
+
+	claim := initSessionClaim(*workbench, r.Config.Labels.PropagatedKeys)
+
+	foundClaim := corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: claim.Name, Namespace: claim.Namespace}, &foundClaim)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		if err := setOwner(workbench, &claim, r.Scheme); err != nil {
+			return nil, err
+		}
+
+		log.V(1).Info("Creating the session state claim", "claim", claim.Name)
+
+		if err := r.Create(ctx, &claim); err != nil {
+			return nil, err
+		}
+
+		return &claim, nil
+	}
+
+	// The claim spec is immutable, only the labels follow.
+	if updateLabels(claim.Labels, &foundClaim.Labels, r.Config.Labels.PropagatedKeys) {
+		log.V(1).Info("Updating the session state claim", "claim", foundClaim.Name)
+
+		if err := r.Update(ctx, &foundClaim); err != nil {
+			return nil, err
+		}
+	}
+
+	return &foundClaim, nil
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// nativeSidecarsMinVersion is the first Kubernetes version with native sidecars on by default.
+var nativeSidecarsMinVersion = version.MustParseGeneric("1.29.0")
+
+// SupportsNativeSidecars tells whether the cluster runs init containers with restartPolicy Always.
+func SupportsNativeSidecars(config *rest.Config) (bool, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := client.ServerVersion()
+	if err != nil {
+		return false, err
+	}
+
+	return supportsNativeSidecars(info.GitVersion)
+}
+
+func supportsNativeSidecars(gitVersion string) (bool, error) {
+	serverVersion, err := version.ParseGeneric(gitVersion)
+	if err != nil {
+		return false, err
+	}
+
+	return serverVersion.AtLeast(nativeSidecarsMinVersion), nil
+}
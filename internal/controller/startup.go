@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// containerState tells how the container was last seen, in the newest pod of the job.
+func containerState(pods []corev1.Pod, name string) string {
+	newest := newestPod(pods)
+	if newest == nil {
+		return "no pod"
+	}
+
+	for _, status := range newest.Status.ContainerStatuses {
+		if status.Name != name {
+			continue
+		}
+
+		if state := describeContainer(status); state != "" {
+			return state
+		}
+	}
+
+	return fmt.Sprintf("pod %s", newest.Status.Phase)
+}
+
+// newestPod is the last created of the pods, nil without any.
+func newestPod(pods []corev1.Pod) *corev1.Pod {
+	var newest *corev1.Pod
+	for index := range pods {
+		if newest == nil || newest.CreationTimestamp.Before(&pods[index].CreationTimestamp) {
+			newest = &pods[index]
+		}
+	}
+
+	return newest
+}
+
+// describeContainer tells how the container is, e.g. "waiting, ImagePullBackOff", empty before
+// it has a state.
+func describeContainer(status corev1.ContainerStatus) string {
+	switch {
+	case status.State.Waiting != nil:
+		return fmt.Sprintf("waiting, %s", status.State.Waiting.Reason)
+	case status.State.Terminated != nil:
+		return fmt.Sprintf("terminated, %s", status.State.Terminated.Reason)
+	case status.State.Running != nil && !status.Ready:
+		return "running, not ready"
+	case status.State.Running != nil:
+		return "running"
+	}
+
+	return ""
+}
+
+// reportStartupDeadline emits that the app was not ready in time, with how its container was.
+func (r *WorkbenchReconciler) reportStartupDeadline(ctx context.Context, workbench *defaultv1alpha1.Workbench, app defaultv1alpha1.WorkbenchApp, job batchv1.Job) {
+	log := log.FromContext(ctx)
+
+	state := "unknown"
+
+	pods, err := r.appPods(ctx, job)
+	if err != nil {
+		log.V(1).Error(err, "Unable to list the pods", "job", job.Name)
+	} else if len(job.Spec.Template.Spec.Containers) > 0 {
+		state = containerState(pods, job.Spec.Template.Spec.Containers[0].Name)
+	}
+
+	emitEvent(
+		r.Recorder,
+		workbench,
+		corev1.EventTypeWarning,
+		eventReasonStartupDeadlineExceeded,
+		"The app %q was not ready within %ds, its container is %s",
+		app.Name,
+		*app.StartupDeadlineSeconds,
+		state,
+	)
+}
+
+// stopLateJob has the job controller stop the job of an app out of startup time.
+//
+// Its active deadline is set to its age, so the job is failed at once. Unlike a deletion, the
+// failed job stays and is not recreated on the next pass.
+func (r *WorkbenchReconciler) stopLateJob(ctx context.Context, job batchv1.Job, now metav1.Time) error {
+	if job.Status.Active == 0 || job.Status.StartTime == nil || job.Spec.ActiveDeadlineSeconds != nil {
+		return nil
+	}
+
+	log := log.FromContext(ctx)
+
+	patch := client.MergeFrom(job.DeepCopy())
+
+	deadline := max(int64(now.Sub(job.Status.StartTime.Time).Seconds()), 1)
+	job.Spec.ActiveDeadlineSeconds = &deadline
+
+	log.V(1).Info("Stopping the job out of startup time", "job", job.Name)
+
+	return r.Patch(ctx, &job, patch)
+}
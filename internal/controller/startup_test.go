@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Startup deadline", func() {
+	It("should tell how the container was last seen, in the newest pod", func() {
+		Expect(containerState(nil, "wezterm")).To(Equal("no pod"))
+
+		pods := []corev1.Pod{{}, {}}
+		pods[0].CreationTimestamp = metav1.NewTime(time.Unix(1000, 0))
+		pods[0].Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: "wezterm", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error"}}},
+		}
+		pods[1].CreationTimestamp = metav1.NewTime(time.Unix(2000, 0))
+		pods[1].Status.Phase = corev1.PodPending
+		Expect(containerState(pods, "wezterm")).To(Equal("pod Pending"))
+
+		pods[1].Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: "sidecar", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			{Name: "wezterm", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+		}
+		Expect(containerState(pods, "wezterm")).To(Equal("waiting, ContainerCreating"))
+
+		pods[1].Status.ContainerStatuses[1].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+		Expect(containerState(pods, "wezterm")).To(Equal("running, not ready"))
+	})
+})
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"runtime"
@@ -9,12 +10,15 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
 	// +kubebuilder:scaffold:imports
@@ -72,3 +76,21 @@ var _ = AfterSuite(func() {
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
+
+// newTestReconciler creates a workbench reconciler on the envtest client, with the configuration.
+func newTestReconciler(config Config) *WorkbenchReconciler {
+	return &WorkbenchReconciler{
+		Client:   k8sClient,
+		Scheme:   k8sClient.Scheme(),
+		Recorder: record.NewFakeRecorder(100),
+		Config:   config,
+	}
+}
+
+// reconcileOnce runs a single pass of the reconciler on the workbench, which must succeed.
+func reconcileOnce(r *WorkbenchReconciler, key types.NamespacedName) reconcile.Result {
+	result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+	return result
+}
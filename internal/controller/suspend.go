@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// suspendAttempts is how many times a suspension is tried before giving up on it.
+const suspendAttempts = 2
+
+// suspendProbeInterval is how long a namespace is known to not keep the jobs suspended, before trying again.
+//
+// The admission policies are per namespace, and may be fixed, the verdict is not kept forever.
+const suspendProbeInterval = 15 * time.Minute
+
+// suspendUnsupportedMessage explains, in the app status, why a stopped app has no job.
+const suspendUnsupportedMessage = "SuspendUnsupported: the cluster does not keep the jobs suspended, the job was deleted instead"
+
+// isSuspended tells whether the job is suspended.
+func isSuspended(job batchv1.Job) bool {
+	return job.Spec.Suspend != nil && *job.Spec.Suspend
+}
+
+// suspendJob writes the updated job meant to be suspended, and tells whether the suspension stuck.
+//
+// Some admission policies strip spec.suspend, the job would then be updated forever in vain. The job
+// returned by the update is the stored one, the cache may not have it yet.
+func (r *WorkbenchReconciler) suspendJob(ctx context.Context, job *batchv1.Job) (bool, error) {
+	log := log.FromContext(ctx)
+
+	suspend := job.Spec.Suspend
+
+	for attempt := 0; attempt < suspendAttempts; attempt++ {
+		job.Spec.Suspend = suspend
+
+		if err := r.Update(ctx, job); err != nil {
+			return false, err
+		}
+
+		if isSuspended(*job) {
+			return true, nil
+		}
+
+		log.V(1).Info("The job suspension did not stick", "job", job.Name, "attempt", attempt+1)
+	}
+
+	return false, nil
+}
+
+// suspendProbes remembers the namespaces where the jobs suspensions did not stick, and since when.
+//
+// The zero value is ready to use.
+type suspendProbes struct {
+	mu          sync.Mutex
+	unsupported map[string]time.Time
+}
+
+// isUnsupported tells whether the namespace is known to not keep the jobs suspended, the verdict expires
+// after suspendProbeInterval.
+func (p *suspendProbes) isUnsupported(namespace string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	since, ok := p.unsupported[namespace]
+	if !ok {
+		return false
+	}
+
+	if now.Sub(since) >= suspendProbeInterval {
+		delete(p.unsupported, namespace)
+		return false
+	}
+
+	return true
+}
+
+// setUnsupported records that the suspensions did not stick in the namespace, and tells whether it was
+// not known already.
+func (p *suspendProbes) setUnsupported(namespace string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	since, ok := p.unsupported[namespace]
+	known := ok && now.Sub(since) < suspendProbeInterval
+
+	if p.unsupported == nil {
+		p.unsupported = map[string]time.Time{}
+	}
+
+	p.unsupported[namespace] = now
+
+	return !known
+}
+
+// suspendedApp is the app as the workbench wants it, a running app of a suspended workbench,
+// or of an idle one, is stopped.
+//
+// The spec is left as is, so the same apps start again once the workbench is resumed.
+func suspendedApp(workbench defaultv1alpha1.Workbench, app defaultv1alpha1.WorkbenchApp) defaultv1alpha1.WorkbenchApp {
+	if !isWorkbenchSuspended(workbench) || (app.State != "" && app.State != defaultv1alpha1.WorkbenchAppStateRunning) {
+		return app
+	}
+
+	app.State = defaultv1alpha1.WorkbenchAppStateStopped
+
+	return app
+}
+
+// suspendedCondition tells that the workbench is scaled to zero, as requested.
+func suspendedCondition(workbench defaultv1alpha1.Workbench) metav1.Condition {
+	return metav1.Condition{
+		Type:               conditionSuspended,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Suspended",
+		Message:            "The server is scaled to zero and the apps are suspended, the storage and the service are kept",
+		ObservedGeneration: workbench.Generation,
+	}
+}
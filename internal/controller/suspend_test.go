@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Suspension", func() {
+	It("should stop the running apps of a suspended workbench only", func() {
+		workbench := defaultv1alpha1.Workbench{}
+
+		running := defaultv1alpha1.WorkbenchApp{Name: "wezterm"}
+		killed := defaultv1alpha1.WorkbenchApp{Name: "kitty", State: defaultv1alpha1.WorkbenchAppStateKilled}
+
+		Expect(suspendedApp(workbench, running)).To(Equal(running))
+
+		workbench.Spec.Suspended = true
+		Expect(suspendedApp(workbench, running).State).To(Equal(defaultv1alpha1.WorkbenchAppStateStopped))
+		Expect(suspendedApp(workbench, killed)).To(Equal(killed))
+	})
+
+	It("should scale the server deployment to zero", func() {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+
+		deployment := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+
+		workbench.Spec.Suspended = true
+		suspended := initDeployment(workbench, ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		Expect(*suspended.Spec.Replicas).To(Equal(int32(0)))
+
+		Expect(updateDeployment(suspended, &deployment, nil)).To(BeTrue())
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(0)))
+		Expect(updateDeployment(suspended, &deployment, nil)).To(BeFalse())
+	})
+
+	It("should remember the namespaces not keeping the suspension, for a while", func() {
+		probes := suspendProbes{}
+		now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+		Expect(probes.isUnsupported("default", now)).To(BeFalse())
+
+		Expect(probes.setUnsupported("default", now)).To(BeTrue())
+		Expect(probes.setUnsupported("default", now.Add(time.Minute))).To(BeFalse())
+		Expect(probes.isUnsupported("default", now.Add(time.Minute))).To(BeTrue())
+
+		// The other namespaces are still tried.
+		Expect(probes.isUnsupported("research", now)).To(BeFalse())
+
+		// Tried again once expired.
+		later := now.Add(time.Minute + suspendProbeInterval)
+		Expect(probes.isUnsupported("default", later)).To(BeFalse())
+		Expect(probes.setUnsupported("default", later)).To(BeTrue())
+	})
+})
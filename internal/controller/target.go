@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// sourceNamespaceLabel holds the namespace of the workbench, on the children created in
+// another namespace.
+const sourceNamespaceLabel = "chorus-tre.ch/workbench-namespace"
+
+// allowedSourceNamespacesAnnotation lists, on a target namespace, the comma separated
+// namespaces whose workbenches may run there.
+const allowedSourceNamespacesAnnotation = "chorus-tre.ch/allowed-workbench-namespaces"
+
+// targetNamespace is where the children of the workbench are created.
+func targetNamespace(workbench defaultv1alpha1.Workbench) string {
+	if workbench.Spec.TargetNamespace != "" {
+		return workbench.Spec.TargetNamespace
+	}
+
+	return workbench.Namespace
+}
+
+// isTargeted tells whether the children of the workbench are in another namespace.
+func isTargeted(workbench defaultv1alpha1.Workbench) bool {
+	return targetNamespace(workbench) != workbench.Namespace
+}
+
+// childSelector matches the children of the workbench, in its target namespace.
+//
+// The workbenches of many namespaces may share a target namespace, their names may not.
+func childSelector(workbench defaultv1alpha1.Workbench) client.MatchingLabels {
+	selector := client.MatchingLabels{matchingLabel: workbench.Name}
+
+	if isTargeted(workbench) {
+		selector[sourceNamespaceLabel] = workbench.Namespace
+	}
+
+	return selector
+}
+
+// setOwner makes the workbench the controller of the child, when in the same namespace.
+//
+// The owner references cannot cross the namespaces, the children of a targeted workbench
+// are only found by their labels: they are watched as such and deleted by the finalizer.
+func setOwner(workbench *defaultv1alpha1.Workbench, child client.Object, scheme *runtime.Scheme) error {
+	if isTargeted(*workbench) {
+		return nil
+	}
+
+	return controllerutil.SetControllerReference(workbench, child, scheme)
+}
+
+// targetAllows tells whether the target namespace lets the workbenches of the source one in.
+func targetAllows(namespace corev1.Namespace, source string) bool {
+	for _, allowed := range strings.Split(namespace.Annotations[allowedSourceNamespacesAnnotation], ",") {
+		if strings.TrimSpace(allowed) == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+// targetCondition tells whether the workbench may create its children in its target namespace.
+//
+// Without the consent of the target namespace, anyone allowed to create a workbench could
+// run pods, with their service account, in any namespace.
+func (r *WorkbenchReconciler) targetCondition(ctx context.Context, workbench defaultv1alpha1.Workbench) (metav1.Condition, error) {
+	target := targetNamespace(workbench)
+
+	condition := metav1.Condition{
+		Type:               conditionReferences,
+		Status:             metav1.ConditionTrue,
+		Reason:             "TargetNamespaceAllowed",
+		Message:            fmt.Sprintf("The references are within the namespace, the children are in %q", target),
+		ObservedGeneration: workbench.Generation,
+	}
+
+	namespace := corev1.Namespace{}
+	err := r.Get(ctx, types.NamespacedName{Name: target}, &namespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return condition, err
+	}
+
+	if apierrors.IsNotFound(err) {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "TargetNamespaceNotFound"
+		condition.Message = fmt.Sprintf("The target namespace %q does not exist, nothing is created", target)
+	} else if !targetAllows(namespace, workbench.Namespace) {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "TargetNamespaceNotAllowed"
+		condition.Message = fmt.Sprintf(
+			"The target namespace %q does not allow the workbenches of %q in its %s annotation, nothing is created",
+			target,
+			workbench.Namespace,
+			allowedSourceNamespacesAnnotation,
+		)
+	}
+
+	return condition, nil
+}
+
+// workbenchForTargetedChild maps a child in a target namespace to its workbench.
+//
+// The children in the namespace of their workbench are mapped by their owner reference.
+func workbenchForTargetedChild(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+
+	source, name := labels[sourceNamespaceLabel], labels[matchingLabel]
+	if source == "" || name == "" || source == obj.GetNamespace() {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: name, Namespace: source}},
+	}
+}
+
+// workbenchForOwnedChild maps a child controlled by a workbench to it, like Owns.
+func workbenchForOwnedChild(_ context.Context, obj client.Object) []reconcile.Request {
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil || owner.Kind != "Workbench" {
+		return nil
+	}
+
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil || gv.Group != defaultv1alpha1.GroupVersion.Group {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: owner.Name, Namespace: obj.GetNamespace()}},
+	}
+}
+
+// workbenchesForTarget maps a namespace to the workbenches targeting it, so they follow the
+// changes of its annotation.
+func (r *WorkbenchReconciler) workbenchesForTarget(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	workbenchList := defaultv1alpha1.WorkbenchList{}
+	if err := r.List(ctx, &workbenchList); err != nil {
+		log.Error(err, "Unable to list the workbenches")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, item := range workbenchList.Items {
+		if isTargeted(item) && targetNamespace(item) == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace},
+			})
+		}
+	}
+
+	return requests
+}
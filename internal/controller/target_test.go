@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Target namespace", func() {
+	newWorkbench := func(target string) defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "research"
+		workbench.Spec.TargetNamespace = target
+
+		return workbench
+	}
+
+	It("should select the children by their workbench namespace too, when targeted", func() {
+		Expect(childSelector(newWorkbench(""))).To(Equal(client.MatchingLabels{matchingLabel: "workbench"}))
+		Expect(childSelector(newWorkbench("research"))).To(Equal(client.MatchingLabels{matchingLabel: "workbench"}))
+		Expect(childSelector(newWorkbench("compute"))).To(Equal(client.MatchingLabels{
+			matchingLabel:        "workbench",
+			sourceNamespaceLabel: "research",
+		}))
+
+		deployment := initDeployment(newWorkbench("compute"), ImagesConfig{}, ServerConfig{}, GPUConfig{}, nil)
+		Expect(deployment.Namespace).To(Equal("compute"))
+		Expect(deployment.Spec.Selector.MatchLabels).To(HaveKeyWithValue(sourceNamespaceLabel, "research"))
+	})
+
+	It("should only let in the listed namespaces", func() {
+		namespace := corev1.Namespace{}
+		Expect(targetAllows(namespace, "research")).To(BeFalse())
+
+		namespace.Annotations = map[string]string{allowedSourceNamespacesAnnotation: "imaging, research"}
+		Expect(targetAllows(namespace, "research")).To(BeTrue())
+		Expect(targetAllows(namespace, "genomics")).To(BeFalse())
+		Expect(targetAllows(namespace, "")).To(BeFalse())
+	})
+
+	It("should map the targeted children only", func() {
+		deployment := &appsv1.Deployment{}
+		deployment.Namespace = "compute"
+		deployment.Labels = map[string]string{matchingLabel: "workbench", sourceNamespaceLabel: "research"}
+
+		Expect(workbenchForTargetedChild(context.Background(), deployment)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "workbench", Namespace: "research"}},
+		}))
+
+		// Those are mapped by their owner reference.
+		deployment.Namespace = "research"
+		Expect(workbenchForTargetedChild(context.Background(), deployment)).To(BeEmpty())
+
+		delete(deployment.Labels, sourceNamespaceLabel)
+		Expect(workbenchForTargetedChild(context.Background(), deployment)).To(BeEmpty())
+	})
+
+	It("should map the secrets of the workbenches with a single handler", func() {
+		reconciler := &WorkbenchReconciler{}
+		tru := true
+
+		owned := &corev1.Secret{}
+		owned.Namespace = "research"
+		owned.OwnerReferences = []metav1.OwnerReference{
+			{APIVersion: defaultv1alpha1.GroupVersion.String(), Kind: "Workbench", Name: "workbench", Controller: &tru},
+		}
+
+		Expect(reconciler.workbenchesForSecret(context.Background(), owned)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "workbench", Namespace: "research"}},
+		}))
+
+		targeted := &corev1.Secret{}
+		targeted.Namespace = "compute"
+		targeted.Labels = map[string]string{matchingLabel: "workbench", sourceNamespaceLabel: "research"}
+
+		Expect(reconciler.workbenchesForSecret(context.Background(), targeted)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "workbench", Namespace: "research"}},
+		}))
+
+		// Only the controller of another kind.
+		other := owned.DeepCopy()
+		other.OwnerReferences[0].Kind = "Deployment"
+		other.OwnerReferences[0].APIVersion = "apps/v1"
+		Expect(reconciler.workbenchesForSecret(context.Background(), other)).To(BeEmpty())
+	})
+})
@@ -11,12 +11,17 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
@@ -28,6 +33,17 @@ type WorkbenchReconciler struct {
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
 	Config   Config
+	// Clock tells the time of the status changes, the real one unless set.
+	Clock clock.PassiveClock
+
+	// cursors are where the passes out of budget stopped in the apps loop.
+	cursors appCursors
+
+	// convergence measures the time the spec changes take to converge.
+	convergence convergenceTracker
+
+	// suspendProbes remembers the namespaces that do not keep the jobs suspended.
+	suspendProbes suspendProbes
 }
 
 // finalizer used to control the clean up the deployments.
@@ -36,8 +52,20 @@ const finalizer = "default.k8s.chorus-tre.ch/finalizer"
 // matchingLabel is used to catch all the apps of a workbench.
 const matchingLabel = "workbench"
 
+// childCreatedRequeueAfter gives the newly created children the time to get a status.
+const childCreatedRequeueAfter = 2 * time.Second
+
 var ErrSuspendedJob = errors.New("suspended job")
 
+// ErrBlockedJob is returned instead of creating a job the Pod Security admission would refuse.
+var ErrBlockedJob = errors.New("blocked job")
+
+// ErrCompletedJob is returned instead of recreating the reaped job of a completed app.
+var ErrCompletedJob = errors.New("completed job")
+
+// ErrRejectedJob is returned when the dry run of a job creation was refused by the API server.
+var ErrRejectedJob = errors.New("rejected job")
+
 // +kubebuilder:rbac:groups=default.chorus-tre.ch,resources=workbenches,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=default.chorus-tre.ch,resources=workbenches/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=default.chorus-tre.ch,resources=workbenches/finalizers,verbs=update
@@ -47,11 +75,16 @@ var ErrSuspendedJob = errors.New("suspended job")
 // +kubebuilder:rbac:groups=core,resources=services/status,verbs=get
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete;deletecollection
 // +kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;deletecollection
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *WorkbenchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *WorkbenchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	log := log.FromContext(ctx)
 
 	log.V(1).Info("Reconcile", "what", req.NamespacedName)
@@ -62,11 +95,20 @@ func (r *WorkbenchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		// Not found means it's been deleted.
 		if !apierrors.IsNotFound(err) {
 			log.Error(err, "unable to fetch the workbench")
+		} else {
+			r.cursors.forget(req.NamespacedName)
+			forgetWorkbenchMetrics(req.Namespace, req.Name)
 		}
 
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// A single workbench may be debugged without raising the verbosity of the whole operator.
+	ctx, log = withObjectLogger(ctx, &workbench)
+
+	// The writes of the workbench, never its spec.
+	writes := newWorkbenchWrites(workbench)
+
 	// Manage deletion and finalizers.
 	containsFinalizer := controllerutil.ContainsFinalizer(&workbench, finalizer)
 
@@ -74,85 +116,372 @@ func (r *WorkbenchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		// Object has been deleted
 		if containsFinalizer {
 			// It first removes the sub-resources, then the finalizer.
-			count, err := r.deleteExternalResources(ctx, &workbench)
+			summary, err := r.deleteExternalResources(ctx, &workbench)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
 
 			// We will get a resource name may not be empty error otherwise.
-			if count > 0 {
+			if summary.total() > 0 {
+				if err := r.reportDeletion(ctx, writes, &workbench, summary); err != nil {
+					log.V(1).Error(err, "Unable to update the WorkbenchStatus")
+				}
+
 				return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 			}
 
-			finalizersUpdated := controllerutil.RemoveFinalizer(&workbench, finalizer)
-			if finalizersUpdated {
-				if err := r.Update(ctx, &workbench); err != nil {
-					return ctrl.Result{}, err
-				}
+			if err := r.cleanupReplicatedPullSecret(ctx, workbench); err != nil {
+				log.V(1).Error(err, "Error deleting the replicated pull secret")
+				return ctrl.Result{}, err
+			}
+
+			controllerutil.RemoveFinalizer(&workbench, finalizer)
+			if err := writes.patchMetadata(ctx, r.Client, workbench); err != nil {
+				return ctrl.Result{}, err
 			}
 		}
 
+		r.convergence.forget(workbench.UID)
+		r.cursors.forget(req.NamespacedName)
+		forgetWorkbenchMetrics(workbench.Namespace, workbench.Name)
+
 		// Stop reconciliation as the object is being deleted.
 		return ctrl.Result{}, nil
 	}
 
+	if r.Config.Metrics.Convergence {
+		r.convergence.observe(workbench, r.now().Time)
+	}
+
 	// verify that the finalizer exists.
+	// It's written right away, before any child is created.
 	if !containsFinalizer {
-		finalizersUpdated := controllerutil.AddFinalizer(&workbench, finalizer)
-		if finalizersUpdated {
-			if err := r.Update(ctx, &workbench); err != nil {
+		controllerutil.AddFinalizer(&workbench, finalizer)
+		if err := writes.patchMetadata(ctx, r.Client, workbench); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// The other metadata changes are written once, whatever the outcome of the pass.
+	defer func() {
+		if err := writes.patchMetadata(ctx, r.Client, workbench); err != nil {
+			log.V(1).Error(err, "Unable to patch the workbench metadata")
+
+			if reterr == nil {
+				reterr = err
+			}
+		}
+	}()
+
+	// The status changes are accumulated and written once at the end.
+	statusUpdated := false
+
+	// A freshly created child has no status yet, it's read on the next pass.
+	childCreated := false
+
+	// The earliest time a held app status may be published.
+	var statusRetryAfter time.Duration
+
+	// -------- PRE-FLIGHT -----------
+
+	// Nothing is created for a workbench reaching out of its namespace, unless let in.
+	referencesCondition := referencesCondition(workbench)
+	if referencesCondition.Status == metav1.ConditionTrue && isTargeted(workbench) {
+		condition, err := r.targetCondition(ctx, workbench)
+		if err != nil {
+			log.V(1).Error(err, "Error reading the target namespace")
+			return ctrl.Result{}, err
+		}
+
+		referencesCondition = condition
+	}
+
+	if meta.SetStatusCondition(&workbench.Status.Conditions, referencesCondition) {
+		statusUpdated = true
+
+		if referencesCondition.Status == metav1.ConditionFalse {
+			emitEvent(
+				r.Recorder,
+				&workbench,
+				corev1.EventTypeWarning,
+				eventReasonCrossNamespaceReference,
+				"%s",
+				referencesCondition.Message,
+			)
+		}
+	}
+
+	if referencesCondition.Status == metav1.ConditionFalse {
+		if statusUpdated {
+			if err := r.updateStatus(ctx, writes, &workbench); err != nil {
 				return ctrl.Result{}, err
 			}
 		}
+
+		// A fix of the spec, or of the target namespace, triggers a new reconcile.
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.replicatePullSecret(ctx, workbench); err != nil {
+		log.V(1).Error(err, "Error replicating the pull secret")
+		return ctrl.Result{}, err
+	}
+
+	// A wrong secret is reported, but the workloads are created anyway as it may arrive later.
+	pullSecretsCondition, err := r.checkImagePullSecrets(ctx, workbench)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if meta.SetStatusCondition(&workbench.Status.Conditions, pullSecretsCondition) {
+		statusUpdated = true
+
+		// Only reporting the changes avoids repeating the same event on every requeue.
+		if pullSecretsCondition.Status == metav1.ConditionFalse {
+			emitEvent(
+				r.Recorder,
+				&workbench,
+				corev1.EventTypeWarning,
+				eventReasonImagePullSecretInvalid,
+				"%s",
+				pullSecretsCondition.Message,
+			)
+		}
+	}
+
+	// The pods refused by the namespace would silently be missing.
+	restricted, err := r.enforcesRestricted(ctx, targetNamespace(workbench))
+	if err != nil {
+		log.V(1).Error(err, "Error reading the namespace")
+		return ctrl.Result{}, err
+	}
+
+	// The parts refused by the Pod Security admission, and why.
+	blocked := []string{}
+	var blockedViolations []string
+
+	// -------- SESSION SECRET -------
+
+	sessionSecretName := ""
+
+	var sessionSecret *corev1.Secret
+	if !r.Config.Server.DisableSessionAuth {
+		sessionSecret, err = r.reconcileSessionSecret(ctx, &workbench)
+		if err != nil {
+			log.V(1).Error(err, "Error reconciling the session secret")
+			return ctrl.Result{}, err
+		}
+
+		sessionSecretName = sessionSecret.Name
+	}
+
+	if workbench.Status.SessionSecretName != sessionSecretName {
+		workbench.Status.SessionSecretName = sessionSecretName
+		statusUpdated = true
+	}
+
+	// -------- SESSION STATE --------
+
+	var sessionClaim *corev1.PersistentVolumeClaim
+	if workbench.Spec.Server.PersistentSession {
+		sessionClaim, err = r.reconcileSessionClaim(ctx, &workbench)
+		if err != nil {
+			log.V(1).Error(err, "Error reconciling the session state claim")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// -------- SUSPENSION -----------
+
+	now := r.now()
+
+	// The server pods tell its activity, and the health of its containers.
+	serverPods, err := r.serverPods(ctx, workbench)
+	if err != nil {
+		log.V(1).Error(err, "Error listing the server pods")
+		return ctrl.Result{}, err
+	}
+
+	if (&workbench).UpdateStatusFromActivity(serverActivity(serverPods)) {
+		statusUpdated = true
+	}
+
+	if condition := meta.FindStatusCondition(workbench.Status.Conditions, conditionSuspended); condition != nil && isResumed(workbench, *condition) {
+		meta.RemoveStatusCondition(&workbench.Status.Conditions, conditionSuspended)
+		statusUpdated = true
+
+		// The idle time starts over.
+		(&workbench).UpdateStatusFromActivity(now)
+
+		emitEvent(
+			r.Recorder,
+			&workbench,
+			corev1.EventTypeNormal,
+			eventReasonResumed,
+			"The workbench is resumed",
+		)
+	}
+
+	// The request is fulfilled, the annotation goes with the metadata writes of the pass.
+	delete(workbench.Annotations, wakeAnnotation)
+
+	// The idle time of a server never seen active starts now.
+	if workbench.Spec.Server.IdleTimeout != nil && workbench.Status.Server.LastActivity == nil {
+		(&workbench).UpdateStatusFromActivity(now)
+		statusUpdated = true
+	}
+
+	// The server is scaled to zero by its deployment, the apps are stopped below.
+	idleLeft, idles := idleRemaining(workbench, now.Time)
+
+	switch {
+	case workbench.Spec.Suspended:
+		if meta.SetStatusCondition(&workbench.Status.Conditions, suspendedCondition(workbench)) {
+			statusUpdated = true
+
+			emitEvent(
+				r.Recorder,
+				&workbench,
+				corev1.EventTypeNormal,
+				eventReasonSuspended,
+				"The workbench is suspended, its server and apps are stopped",
+			)
+		}
+	case idles && idleLeft == 0:
+		if meta.SetStatusCondition(&workbench.Status.Conditions, idleCondition(workbench)) {
+			statusUpdated = true
+
+			emitEvent(
+				r.Recorder,
+				&workbench,
+				corev1.EventTypeNormal,
+				eventReasonIdleSuspended,
+				"The workbench was idle for %s, its server and apps are stopped",
+				workbench.Spec.Server.IdleTimeout.Duration,
+			)
+		}
+	case idles && (statusRetryAfter == 0 || idleLeft < statusRetryAfter):
+		statusRetryAfter = idleLeft
 	}
 
 	// -------- SERVER ---------------
 
 	// The deployment of Xpra server
-	deployment := initDeployment(workbench, r.Config)
+	deployment := initDeployment(workbench, r.Config.Images, r.Config.Server, r.Config.GPU, r.Config.Labels.PropagatedKeys)
+
+	if sessionSecret != nil {
+		addSessionAuth(&deployment, *sessionSecret)
+	}
+
+	if sessionClaim != nil {
+		addSessionState(&deployment, *sessionClaim)
+	}
+
+	// After the session token, the observers require it too.
+	addObservers(&deployment, workbench)
+
+	// The deployment is created anyway, its pods come once the namespace is relabelled.
+	if restricted {
+		if violations := restrictedViolations(deployment.Spec.Template.Spec); len(violations) > 0 {
+			blocked = append(blocked, "the server")
+			blockedViolations = violations
+		}
+	}
 
 	// Link the deployment with the Workbench resource such that we can reconcile it
 	// when it's being changed.
-	if err := controllerutil.SetControllerReference(&workbench, &deployment, r.Scheme); err != nil {
+	if err := setOwner(&workbench, &deployment, r.Scheme); err != nil {
 		log.V(1).Error(err, "Error setting the reference", "deployment", deployment.Name)
 
 		return ctrl.Result{}, err
 	}
 
-	foundDeployment, err := r.createDeployment(ctx, deployment)
+	foundDeployment, created, err := r.createDeployment(ctx, deployment)
 	if err != nil {
 		log.V(1).Error(err, "Error creating the deployment")
 	}
 
+	if created {
+		childCreated = true
+	}
+
 	// TODO: to properly follow the deployment we have to dig into the replicaset
 	// via metadata.annotations."deployment.kubernetes.io/revision"
 	// which is also present on the replica. Then the pods, which can be found via
 	// the labels, has said replicas as its owner.
-	if foundDeployment != nil {
-		statusUpdated := (&workbench).UpdateStatusFromDeployment(*foundDeployment)
-		if statusUpdated {
-			if err := r.Status().Update(ctx, &workbench); err != nil {
-				log.V(1).Error(err, "Unable to update the WorkbenchStatus")
+	if foundDeployment != nil && !created {
+		if (&workbench).UpdateStatusFromDeployment(*foundDeployment) {
+			statusUpdated = true
+		}
+
+		// A crash looping server may still be counted as available.
+		restarts, reason := containerRestarts(serverPods, serverContainerName)
+		if (&workbench).UpdateStatusFromServerPods(restarts, reason) {
+			statusUpdated = true
+		}
+
+		if (&workbench).UpdateStatusFromObservers(observerStatuses(serverPods, workbench.Spec.Server.Observers)) {
+			statusUpdated = true
+		}
+
+		// -------- SERVER SELECTOR -----
+
+		selectorCondition := checkDeploymentSelector(deployment, *foundDeployment, workbench.Generation)
+		if meta.SetStatusCondition(&workbench.Status.Conditions, selectorCondition) {
+			statusUpdated = true
+
+			if selectorCondition.Status == metav1.ConditionTrue {
+				emitEvent(
+					r.Recorder,
+					&workbench,
+					corev1.EventTypeWarning,
+					eventReasonSelectorChanged,
+					"%s",
+					selectorCondition.Message,
+				)
+			}
+		}
+
+		selectorChanged := selectorCondition.Status == metav1.ConditionTrue
+
+		// The deployment is recreated on the next pass, the server is briefly down.
+		if selectorChanged && r.Config.Server.RecreateOnSelectorChange {
+			log.V(1).Info("Recreating Deployment", "deployment", foundDeployment.Name)
+
+			emitEvent(
+				r.Recorder,
+				&workbench,
+				corev1.EventTypeNormal,
+				eventReasonRecreatingDeployment,
+				"Recreating deployment %q into the namespace %q",
+				deployment.Name,
+				deployment.Namespace,
+			)
+
+			if err := r.Delete(ctx, foundDeployment, client.PropagationPolicy("Background")); err != nil {
+				log.V(1).Error(err, "Unable to delete the deployment")
+				return ctrl.Result{}, err
 			}
+
+			childCreated = true
 		}
 
 		// -------- SERVER UPDATES ------
 
-		// Update the existing deployment with the model one.
-		updated := updateDeployment(deployment, foundDeployment)
+		// Update the existing deployment with the model one, the API rejects a selector change.
+		updated := !selectorChanged && updateDeployment(deployment, foundDeployment, r.Config.Labels.PropagatedKeys)
 
 		if updated {
 			log.V(1).Info("Updating Deployment", "deployment", foundDeployment.Name)
 
-			r.Recorder.Event(
+			emitEvent(
+				r.Recorder,
 				&workbench,
-				"Normal",
-				"UpdatingDeployment",
-				fmt.Sprintf(
-					"Updating deployment %q into the namespace %q",
-					deployment.Name,
-					deployment.Namespace,
-				),
+				corev1.EventTypeNormal,
+				eventReasonUpdatingDeployment,
+				"Updating deployment %q into the namespace %q",
+				deployment.Name,
+				deployment.Namespace,
 			)
 
 			err2 := r.Update(ctx, foundDeployment)
@@ -166,41 +495,274 @@ func (r *WorkbenchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// ------- SERVICE ---------------
 
 	// The service of the Xpra server
-	service := initService(workbench)
+	service := initService(workbench, r.Config.Labels.PropagatedKeys)
 
 	// Link the service with the Workbench resource such that we can reconcile it
 	// when it's being changed.
-	if err := controllerutil.SetControllerReference(&workbench, &service, r.Scheme); err != nil {
+	if err := setOwner(&workbench, &service, r.Scheme); err != nil {
 		log.V(1).Error(err, "Error setting the reference", "service", service.Name)
 		return ctrl.Result{}, err
 	}
 
-	if err := r.createService(ctx, service); err != nil {
+	foundService, created, err := r.createService(ctx, service)
+	if err != nil {
 		log.V(1).Error(err, "Error creating the service", "service", service.Name)
 		return ctrl.Result{}, err
 	}
 
-	// The service definition is not affected by the CRD, and the status does have any information from it.
+	if created {
+		childCreated = true
+	}
+
+	if serviceRecreated(workbench, *foundService) {
+		emitEvent(
+			r.Recorder,
+			&workbench,
+			corev1.EventTypeWarning,
+			eventReasonServiceRecreated,
+			"Service %q was recreated, the apps may hold a stale connection",
+			foundService.Name,
+		)
+
+		if r.Config.Apps.RestartOnServiceRecreation {
+			if err := r.restartAppPods(ctx, workbench); err != nil {
+				log.V(1).Error(err, "Unable to restart the app pods")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	if workbench.Status.ServiceUID != foundService.UID {
+		workbench.Status.ServiceUID = foundService.UID
+		statusUpdated = true
+	}
+
+	// Only the labels and the observer ports of the service follow the workbench.
+	if !created && updateService(service, foundService, r.Config.Labels.PropagatedKeys) {
+		log.V(1).Info("Updating Service", "service", foundService.Name)
+
+		if err := r.Update(ctx, foundService); err != nil {
+			log.V(1).Error(err, "Unable to update the service")
+			return ctrl.Result{}, err
+		}
+	}
 
 	// ---------- APPS ---------------
 
 	// List of jobs that were either found or created, the others will be deleted.
 	foundJobNames := []string{}
 
+	// The jobs requesting GPUs, their pods tell whether they could be scheduled.
+	gpuJobNames := []string{}
+
+	// The configuration actually used, read from the rendered objects.
+	effective := initEffective(deployment)
+
+	// The apps before the cursor were handled by the previous passes, out of budget.
+	cursor := r.cursors.get(req.NamespacedName, workbench.UID)
+	budget := newAppsBudget(r.Config.Apps, r.now().Time)
+
+	// Whether this pass went through all the remaining apps.
+	appsCompleted := true
+
 	for index, app := range workbench.Spec.Apps {
-		job := initJob(workbench, r.Config, index, app, service)
+		app = suspendedApp(workbench, app)
+
+		job, err := initJob(workbench, r.Config.Images, r.Config.Apps, r.Config.GPU, r.Config.Labels.PropagatedKeys, index, app, service)
+		if err != nil {
+			// Only this app fails, its job, if any, is kept.
+			foundJobNames = append(foundJobNames, jobName(workbench, index, app))
+			addEffectiveApp(&effective, app, batchv1.Job{})
+
+			if (&workbench).UpdateStatusFromRejected(index, err.Error(), now) {
+				statusUpdated = true
+
+				emitEvent(
+					r.Recorder,
+					&workbench,
+					corev1.EventTypeWarning,
+					eventReasonInvalidAppImage,
+					"The app %q cannot be started: %s",
+					app.Name,
+					err.Error(),
+				)
+			}
+
+			continue
+		}
+
+		addEffectiveApp(&effective, app, *job)
 
 		// Link the service with the Workbench resource such that we can reconcile it
 		// when it's being changed.
-		if err := controllerutil.SetControllerReference(&workbench, job, r.Scheme); err != nil {
+		if err := setOwner(&workbench, job, r.Scheme); err != nil {
 			log.V(1).Error(err, "Error setting the reference", "job", job.Name)
 			return ctrl.Result{}, err
 		}
 
-		foundJob, err := r.createJob(ctx, *job)
+		// Only the running apps get pods.
+		blockedJob := false
+		if restricted && (job.Spec.Suspend == nil || !*job.Spec.Suspend) {
+			if violations := restrictedViolations(job.Spec.Template.Spec); len(violations) > 0 {
+				blocked = append(blocked, fmt.Sprintf("the app %q", app.Name))
+				if blockedViolations == nil {
+					blockedViolations = violations
+				}
+
+				blockedJob = true
+			}
+		}
+
+		// Every app of the spec keeps its job, whether it exists, is created, or is skipped.
+		// Otherwise, the cleanup below would delete it and the next pass recreate it.
+		foundJobNames = append(foundJobNames, job.Name)
+
+		if prewarmed(workbench, app) {
+			foundJobNames = append(foundJobNames, prePullJobName(workbench, index, app))
+		}
+
+		if app.GPU != nil && !isSuspended(*job) {
+			gpuJobNames = append(gpuJobNames, job.Name)
+		}
+
+		// The status of the apps not handled is kept as is.
+		if index < cursor || !appsCompleted {
+			continue
+		}
+
+		if !budget.take(r.now().Time) {
+			log.V(1).Info("Out of budget, continuing later", "app", index)
+
+			r.cursors.set(req.NamespacedName, workbench.UID, index)
+			appsCompleted = false
+
+			continue
+		}
+
+		// Force stopped, the job is deleted and never recreated while the app is killed.
+		if isKilled(app) {
+			killed, err := r.killJob(ctx, *job)
+			if err != nil {
+				log.V(1).Error(err, "Unable to kill the job", "job", job.Name)
+				return ctrl.Result{}, err
+			}
+
+			if killed {
+				emitEvent(
+					r.Recorder,
+					&workbench,
+					corev1.EventTypeNormal,
+					eventReasonKilledApp,
+					"Killed the app %q, its job %q is deleted",
+					app.Name,
+					job.Name,
+				)
+			}
+
+			if (&workbench).UpdateStatusFromRejected(index, killedMessage, now) {
+				statusUpdated = true
+			}
+
+			continue
+		}
+
+		// Not created, as a doomed job would only fail obscurely on the image pull.
+		if lacksRegistry(r.Config.Images, app, *job) {
+			if (&workbench).UpdateStatusFromRejected(index, noRegistryConfiguredMessage, now) {
+				statusUpdated = true
+
+				emitEvent(
+					r.Recorder,
+					&workbench,
+					corev1.EventTypeWarning,
+					eventReasonNoRegistryConfigured,
+					"The app %q has no image, set the --registry or --apps-repository flag of the operator",
+					app.Name,
+				)
+			}
+
+			continue
+		}
+
+		// Not created either, the defaults would not be the size asked for.
+		if unknownResourceProfile(r.Config.Apps, app, *job) {
+			message := fmt.Sprintf(unknownResourceProfileMessage, app.ResourceProfile, r.Config.Apps.ResourceProfileNames())
+			if (&workbench).UpdateStatusFromRejected(index, message, now) {
+				statusUpdated = true
+
+				emitEvent(
+					r.Recorder,
+					&workbench,
+					corev1.EventTypeWarning,
+					eventReasonUnknownResourceProfile,
+					"The app %q asks for the resource profile %q, which the operator does not have",
+					app.Name,
+					app.ResourceProfile,
+				)
+			}
+
+			continue
+		}
+
+		completedJob := workbench.IsAppCompleted(index, *job)
+
+		if prewarmed(workbench, app) && !completedJob {
+			r.prewarmApp(ctx, &workbench, index, app, *job)
+		}
+
+		foundJob, created, err := r.createJob(ctx, *job, blockedJob, completedJob)
 		if err != nil {
-			// Break the loop as nothing shall be created.
+			// Not created, the Blocked condition tells why.
+			if errors.Is(err, ErrBlockedJob) {
+				continue
+			}
+
+			// Not recreated, the user closed the app and its status stays complete.
+			if errors.Is(err, ErrCompletedJob) {
+				continue
+			}
+
+			// Not created, the app is failed with the reason.
+			if errors.Is(err, ErrRejectedJob) {
+				message := rejectionMessage(err)
+
+				if (&workbench).UpdateStatusFromRejected(index, message, now) {
+					statusUpdated = true
+
+					emitEvent(
+						r.Recorder,
+						&workbench,
+						corev1.EventTypeWarning,
+						eventReasonJobRejected,
+						"The job of the app %q was rejected: %s",
+						app.Name,
+						message,
+					)
+				}
+
+				continue
+			}
+
+			// Nothing is created, but the app is reported as stopped.
 			if errors.Is(err, ErrSuspendedJob) {
+				message := ""
+				if r.suspendProbes.isUnsupported(workbench.Namespace, r.now().Time) {
+					message = suspendUnsupportedMessage
+				}
+
+				if (&workbench).UpdateStatusFromSuspended(index, message) {
+					statusUpdated = true
+
+					emitEvent(
+						r.Recorder,
+						&workbench,
+						corev1.EventTypeNormal,
+						eventReasonAppSuspended,
+						"The app %q is stopped, its job is not created",
+						app.Name,
+					)
+				}
+
 				continue
 			}
 
@@ -209,34 +771,164 @@ func (r *WorkbenchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return ctrl.Result{}, err
 		}
 
-		foundJobNames = append(foundJobNames, job.Name)
-
 		// Break the loop as the job was created.
-		if foundJob == nil {
+		if created {
+			childCreated = true
+
+			// The suspended jobs are never created, it's always a start.
+			emitEvent(
+				r.Recorder,
+				&workbench,
+				corev1.EventTypeNormal,
+				eventReasonAppStarted,
+				"The app %q is started by the job %q",
+				app.Name,
+				foundJob.Name,
+			)
+
 			continue
 		}
 
-		// TODO: we could follow the pod as well by following the batch.kubernetes.io/job-name
-		statusUpdated := (&workbench).UpdateStatusFromJob(index, *foundJob)
-		if statusUpdated {
-			if err := r.Status().Update(ctx, &workbench); err != nil {
-				log.V(1).Error(err, "Unable to update the WorkbenchStatus")
+		// The pods are followed for the restarts the job does not count, and for their health.
+		appPods, err := r.appPods(ctx, *foundJob)
+		if err != nil {
+			log.V(1).Error(err, "Unable to list the pods", "job", foundJob.Name)
+		}
+
+		restarts, terminationReason := appRestarts(appPods, *foundJob)
+
+		previousAttempts := int32(0)
+		if index < len(workbench.Status.Apps) {
+			previousAttempts = workbench.Status.Apps[index].Attempts
+		}
+
+		if (&workbench).UpdateStatusFromRetries(index, *foundJob, restarts) {
+			statusUpdated = true
+
+			if workbench.Status.Apps[index].Attempts > previousAttempts {
+				r.reportRetry(&workbench, app, workbench.Status.Apps[index], terminationReason)
+			}
+		}
+
+		lateBefore := workbench.IsStartupDeadlineExceeded(index)
+
+		statusBefore := defaultv1alpha1.WorkbenchStatusAppStatusUnknown
+		if index < len(workbench.Status.Apps) {
+			statusBefore = workbench.Status.Apps[index].Status
+		}
+
+		statusChanged, retryAfter := (&workbench).UpdateStatusFromJob(index, *foundJob, now, r.Config.Apps.StatusDamping)
+		if statusChanged {
+			statusUpdated = true
+
+			observeAppJobDuration(statusBefore, workbench.Status.Apps[index].Status, *foundJob)
+			r.reportAppTransition(&workbench, app, statusBefore, workbench.Status.Apps[index], *foundJob)
+		}
+
+		// The app is failed once, and its job optionally stopped.
+		if workbench.IsStartupDeadlineExceeded(index) {
+			if !lateBefore {
+				r.reportStartupDeadline(ctx, &workbench, app, *foundJob)
+			}
+
+			if r.Config.Apps.KillOnStartupDeadline {
+				if err := r.stopLateJob(ctx, *foundJob, now); err != nil {
+					log.V(1).Error(err, "Unable to stop the job", "job", foundJob.Name)
+					return ctrl.Result{}, err
+				}
 			}
 		}
 
+		if retryAfter > 0 && (statusRetryAfter == 0 || retryAfter < statusRetryAfter) {
+			statusRetryAfter = retryAfter
+		}
+
+		// Unlike the job, the pod tells e.g. an image pull back-off or an out of memory kill.
+		if err == nil && (&workbench).UpdateStatusFromAppPod(index, appPodHealth(appPods)) {
+			statusUpdated = true
+		}
+
+		// Keep track of the finished jobs before the TTL reaps them.
+		if err := r.recordAppHistory(ctx, &workbench, app, *foundJob, appPods); err != nil {
+			log.V(1).Error(err, "Unable to record the app history", "job", foundJob.Name)
+		}
+
 		// TODO: move that check to an admission webhook.
 		if job.Name != foundJob.Name {
 			err := fmt.Errorf("One simply cannot change the application name: %s != %s", job.Name, foundJob.Name)
 			return ctrl.Result{}, err
 		}
 
-		updated := updateJob(*job, foundJob)
+		// Another image, or other resources, need another job. It's created on the next pass.
+		if jobOutdated(*job, *foundJob) {
+			if foundJob.DeletionTimestamp.IsZero() {
+				if err := r.deleteJob(ctx, foundJob); client.IgnoreNotFound(err) != nil {
+					log.V(1).Error(err, "Unable to delete the outdated job", "job", job.Name)
+					return ctrl.Result{}, err
+				}
+
+				emitEvent(
+					r.Recorder,
+					&workbench,
+					corev1.EventTypeNormal,
+					eventReasonAppUpdated,
+					"The app %q changed, its job %q is recreated",
+					app.Name,
+					job.Name,
+				)
+			}
+
+			continue
+		}
+
+		// Stopping the app, unless the namespace is known to not suspend jobs.
+		suspending := isSuspended(*job) && !isSuspended(*foundJob)
+
+		updated := updateJob(*job, foundJob, r.Config.Labels.PropagatedKeys)
+
+		if suspending {
+			suspended := false
+
+			if !r.suspendProbes.isUnsupported(workbench.Namespace, r.now().Time) {
+				log.V(1).Info("Suspending Job", "job", job.Name)
+
+				// FIXME:when the job is suspended from the outside world , it will likely take a while to shutdown as
+				// nobody is listening to the killing signal.
+				suspended, err = r.suspendJob(ctx, foundJob)
+				if err != nil {
+					log.V(1).Error(err, "Unable to suspend the job", "job", job.Name)
+					return ctrl.Result{}, err
+				}
+
+				if !suspended && r.suspendProbes.setUnsupported(workbench.Namespace, r.now().Time) {
+					emitEvent(
+						r.Recorder,
+						&workbench,
+						corev1.EventTypeWarning,
+						eventReasonSuspendUnsupported,
+						"The suspension of the job %q did not stick, the stopped apps of the namespace are deleted instead",
+						job.Name,
+					)
+				}
+			}
+
+			// A stopped app without a job is restarted like a never started one.
+			if !suspended {
+				if err := r.deleteJob(ctx, foundJob); client.IgnoreNotFound(err) != nil {
+					return ctrl.Result{}, err
+				}
+
+				if (&workbench).UpdateStatusFromSuspended(index, suspendUnsupportedMessage) {
+					statusUpdated = true
+				}
+			}
+
+			continue
+		}
 
 		if updated {
 			log.V(1).Info("Updating Job", "job", job.Name)
 
-			// FIXME:when the job is suspended from the outside world , it will likely take a while to shutdown as
-			// nobody is listening to the killing signal.
 			err2 := r.Update(ctx, foundJob)
 			if err2 != nil {
 				log.V(1).Error(err2, "Unable to update the job", "job", job.Name)
@@ -245,6 +937,77 @@ func (r *WorkbenchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
+	if (&workbench).UpdateStatusEffective(effective) {
+		statusUpdated = true
+	}
+
+	blockedCondition := podSecurityCondition(workbench, blocked, blockedViolations)
+	if meta.SetStatusCondition(&workbench.Status.Conditions, blockedCondition) {
+		statusUpdated = true
+
+		if blockedCondition.Status == metav1.ConditionTrue {
+			emitEvent(
+				r.Recorder,
+				&workbench,
+				corev1.EventTypeWarning,
+				eventReasonPodSecurityRestricted,
+				"%s",
+				blockedCondition.Message,
+			)
+		}
+	}
+
+	// Only the workbenches requesting GPUs report their scheduling.
+	if workbench.Spec.Server.GPU != nil || len(gpuJobNames) > 0 {
+		gpuCondition, err := r.gpuCondition(ctx, workbench, gpuJobNames)
+		if err != nil {
+			log.V(1).Error(err, "Unable to check the scheduling of the GPU pods")
+			return ctrl.Result{}, err
+		}
+
+		if meta.SetStatusCondition(&workbench.Status.Conditions, gpuCondition) {
+			statusUpdated = true
+
+			if gpuCondition.Status == metav1.ConditionFalse {
+				emitEvent(
+					r.Recorder,
+					&workbench,
+					corev1.EventTypeWarning,
+					eventReasonGPUUnschedulable,
+					"%s",
+					gpuCondition.Message,
+				)
+			}
+		}
+
+		// Nothing is notified when the pods get scheduled, it is checked again later.
+		if gpuCondition.Status == metav1.ConditionFalse && (statusRetryAfter == 0 || gpuUnschedulableRequeueAfter < statusRetryAfter) {
+			statusRetryAfter = gpuUnschedulableRequeueAfter
+		}
+	} else if meta.RemoveStatusCondition(&workbench.Status.Conditions, conditionGPUScheduled) {
+		statusUpdated = true
+	}
+
+	if (&workbench).UpdateStatusAppCounts() {
+		statusUpdated = true
+	}
+
+	reportWorkbenchMetrics(workbench)
+
+	// A single write for all the status changes of this pass.
+	if statusUpdated {
+		if err := r.updateStatus(ctx, writes, &workbench); err != nil {
+			log.V(1).Error(err, "Unable to update the WorkbenchStatus")
+		}
+	}
+
+	// Only a pass reaching the last app looks for the extra objects.
+	if !appsCompleted {
+		return ctrl.Result{RequeueAfter: appsBudgetRequeueAfter}, nil
+	}
+
+	r.cursors.set(req.NamespacedName, workbench.UID, 0)
+
 	allJobs, err := r.findJobs(ctx, workbench)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -263,39 +1026,122 @@ func (r *WorkbenchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if err := r.deleteJob(ctx, &job); err != nil {
 			return ctrl.Result{}, err
 		}
+
+		emitEvent(
+			r.Recorder,
+			&workbench,
+			corev1.EventTypeNormal,
+			eventReasonAppRemoved,
+			"The job %q of a removed app was deleted",
+			job.Name,
+		)
 	}
 
-	return ctrl.Result{}, nil
+	// The jobs are matched by name above, as the older ones have no app label.
+	if err := r.deleteStaleAppObjects(ctx, workbench); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if r.Config.Metrics.Convergence && isSettled(workbench, appsCompleted, childCreated) {
+		if elapsed, ok := r.convergence.converged(workbench, r.now().Time); ok {
+			convergenceSeconds.WithLabelValues("workbench").Observe(elapsed.Seconds())
+		}
+	}
+
+	if childCreated {
+		return ctrl.Result{RequeueAfter: childCreatedRequeueAfter}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: statusRetryAfter}, nil
 }
 
-// deleteExternalResources removes the underlying Deployment(s).
-func (r *WorkbenchReconciler) deleteExternalResources(ctx context.Context, workbench *defaultv1alpha1.Workbench) (int, error) {
+// updateStatus writes the status of the workbench, once per pass.
+//
+// It is patched, a concurrent update of the workbench does not conflict with it.
+func (r *WorkbenchReconciler) updateStatus(ctx context.Context, writes *workbenchWrites, workbench *defaultv1alpha1.Workbench) error {
+	// The phase follows every write of the status.
+	workbench.UpdateStatusPhase()
+
+	return writes.patchStatus(ctx, r.Client, *workbench)
+}
+
+// deleteExternalResources removes the underlying Deployment(s), returning what is still present.
+func (r *WorkbenchReconciler) deleteExternalResources(ctx context.Context, workbench *defaultv1alpha1.Workbench) (deletionSummary, error) {
 	// The service is delete automatically due to the owner reference it holds.
 
-	r.Recorder.Event(
+	emitEvent(
+		r.Recorder,
 		workbench,
-		"Normal",
-		"DeletingDeployments",
-		fmt.Sprintf(
-			`Deleting deployment "%s=%s" from the namespace %q`,
-			matchingLabel,
-			workbench.Name,
-			workbench.Namespace,
-		),
+		corev1.EventTypeNormal,
+		eventReasonDeletingDeployments,
+		`Deleting deployment "%s=%s" from the namespace %q`,
+		matchingLabel,
+		workbench.Name,
+		targetNamespace(*workbench),
 	)
 
 	// First delete the applications, then the server.
 
+	summary := deletionSummary{}
+
 	// Deref so it's not *modifiable*.
-	count, err := r.deleteJobs(ctx, *workbench)
-	if count > 0 || err != nil {
-		return count, err
+	jobNames, err := r.deleteJobs(ctx, *workbench)
+	summary.add("Job", jobNames...)
+	if len(jobNames) > 0 || err != nil {
+		return summary, err
+	}
+
+	deploymentNames, err := r.deleteDeployments(ctx, *workbench)
+	summary.add("Deployment", deploymentNames...)
+	if len(deploymentNames) > 0 || err != nil {
+		return summary, err
+	}
+
+	// Then all the other labelled children, so they are counted too.
+	for _, kind := range finalizedKinds {
+		names, err := r.deleteChildren(ctx, *workbench, kind)
+		summary.add(kind.kind, names...)
+		if err != nil {
+			return summary, err
+		}
 	}
 
-	return r.deleteDeployments(ctx, *workbench)
+	return summary, nil
+}
+
+// reportDeletion writes what is blocking the deletion into the Deleting condition.
+//
+// A warning is emitted once when it takes longer than expected.
+func (r *WorkbenchReconciler) reportDeletion(ctx context.Context, writes *workbenchWrites, workbench *defaultv1alpha1.Workbench, summary deletionSummary) error {
+	now := r.now()
+
+	if workbench.Status.FirstDeletionAttempt == nil {
+		workbench.Status.FirstDeletionAttempt = &now
+	}
+
+	condition := deletingCondition(*workbench, summary, now.Time, r.Config.Finalization.WarningAfter)
+
+	previous := meta.FindStatusCondition(workbench.Status.Conditions, conditionDeleting)
+	if condition.Reason == string(eventReasonFinalizationStuck) && (previous == nil || previous.Reason != condition.Reason) {
+		emitEvent(
+			r.Recorder,
+			workbench,
+			corev1.EventTypeWarning,
+			eventReasonFinalizationStuck,
+			"%s",
+			condition.Message,
+		)
+	}
+
+	meta.SetStatusCondition(&workbench.Status.Conditions, condition)
+
+	return r.updateStatus(ctx, writes, workbench)
 }
 
-func (r *WorkbenchReconciler) createDeployment(ctx context.Context, deployment appsv1.Deployment) (*appsv1.Deployment, error) {
+// createDeployment creates the deployment when missing, or returns the existing one.
+//
+// The boolean tells whether it was just created, and so has no status yet.
+func (r *WorkbenchReconciler) createDeployment(ctx context.Context, deployment appsv1.Deployment) (*appsv1.Deployment, bool, error) {
 	log := log.FromContext(ctx)
 
 	deploymentNamespacedName := types.NamespacedName{
@@ -308,7 +1154,7 @@ func (r *WorkbenchReconciler) createDeployment(ctx context.Context, deployment a
 	if err != nil {
 		if !apierrors.IsNotFound(err) {
 			log.V(1).Error(err, "Deployment is not (not) found.")
-			return nil, err
+			return nil, false, err
 		}
 
 		log.V(1).Info("Creating the deployment", "deployment", deployment.Name)
@@ -317,17 +1163,19 @@ func (r *WorkbenchReconciler) createDeployment(ctx context.Context, deployment a
 			log.V(1).Error(err, "Error creating the deployment")
 			// It's probably has already been created.
 			// FIXME: check that it's indeed the case.
-			return nil, err
+			return nil, false, err
 		}
 
-		return nil, nil
+		return &deployment, true, nil
 	}
 
-	return &foundDeployment, nil
+	return &foundDeployment, false, nil
 }
 
-// reconcileService creates the service when missing.
-func (r *WorkbenchReconciler) createService(ctx context.Context, service corev1.Service) error {
+// createService creates the service when missing, or returns the existing one.
+//
+// The boolean tells whether it was just created.
+func (r *WorkbenchReconciler) createService(ctx context.Context, service corev1.Service) (*corev1.Service, bool, error) {
 	log := log.FromContext(ctx)
 
 	serviceNamespacedName := types.NamespacedName{
@@ -342,19 +1190,27 @@ func (r *WorkbenchReconciler) createService(ctx context.Context, service corev1.
 		if !apierrors.IsNotFound(err) {
 			log.V(1).Error(err, "Service is not (not) found.")
 
-			return err
+			return nil, false, err
 		}
 
 		log.V(1).Info("Creating the service", "service", service.Name)
 
-		return r.Create(ctx, &service)
+		if err := r.Create(ctx, &service); err != nil {
+			return nil, false, err
+		}
+
+		return &service, true, nil
 	}
 
-	return nil
+	return &foundService, false, nil
 }
 
 // createJob creates a job if missing, or returns the existing job.
-func (r *WorkbenchReconciler) createJob(ctx context.Context, job batchv1.Job) (*batchv1.Job, error) {
+//
+// A blocked job is not created, nor is one failing its dry run, nor the one of a completed
+// app reaped after its TTL. The boolean tells whether it was just created, and so has no
+// status yet.
+func (r *WorkbenchReconciler) createJob(ctx context.Context, job batchv1.Job, blocked bool, completed bool) (*batchv1.Job, bool, error) {
 	log := log.FromContext(ctx)
 
 	jobNamespacedName := types.NamespacedName{
@@ -368,14 +1224,37 @@ func (r *WorkbenchReconciler) createJob(ctx context.Context, job batchv1.Job) (*
 		if !apierrors.IsNotFound(err) {
 			log.V(1).Error(err, "Job is not (not) found.", "job", job.Name)
 
-			return &foundJob, err
+			return nil, false, err
 		}
 
 		// Do no create a job in the suspended state. It's a feature to have things in the
 		// Workbench definitions that do not exist yet.
 		if job.Spec.Suspend != nil && *job.Spec.Suspend == true {
 			log.V(1).Info("Skip suspended job", "job", job.Name)
-			return nil, fmt.Errorf("skipping job %q: %w", job.Name, ErrSuspendedJob)
+			return nil, false, fmt.Errorf("skipping job %q: %w", job.Name, ErrSuspendedJob)
+		}
+
+		if blocked {
+			log.V(1).Info("Skip blocked job", "job", job.Name)
+			return nil, false, fmt.Errorf("skipping job %q: %w", job.Name, ErrBlockedJob)
+		}
+
+		if completed {
+			log.V(1).Info("Skip completed job", "job", job.Name)
+			return nil, false, fmt.Errorf("skipping job %q: %w", job.Name, ErrCompletedJob)
+		}
+
+		// The refusals of the API server are about the app, e.g. a name too long or a quota,
+		// they must not prevent the other apps from running.
+		if !r.Config.Apps.DisableJobDryRun {
+			if err := r.Create(ctx, job.DeepCopy(), client.DryRunAll); err != nil {
+				if isRejection(err) {
+					log.V(1).Info("Rejected job", "job", job.Name, "reason", err.Error())
+					return nil, false, fmt.Errorf("dry run of job %q: %w: %w", job.Name, ErrRejectedJob, err)
+				}
+
+				return nil, false, err
+			}
 		}
 
 		log.V(1).Info("New job", "job", job.Name)
@@ -383,13 +1262,40 @@ func (r *WorkbenchReconciler) createJob(ctx context.Context, job batchv1.Job) (*
 		if err := r.Create(ctx, &job); err != nil {
 			log.V(1).Error(err, "Error creating the job", "job", job.Name)
 
-			return nil, err
+			return nil, false, err
 		}
 
-		return nil, nil
+		return &job, true, nil
+	}
+
+	return &foundJob, false, nil
+}
+
+// isRejection tells whether the API server refused the object itself, rather than failed.
+func isRejection(err error) bool {
+	return apierrors.IsInvalid(err) ||
+		apierrors.IsForbidden(err) ||
+		apierrors.IsBadRequest(err) ||
+		apierrors.IsRequestEntityTooLargeError(err)
+}
+
+// rejectionMessage is the message of the API server, without the wrapping.
+func rejectionMessage(err error) string {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return status.Status().Message
+	}
+
+	return err.Error()
+}
+
+// now is the time of the status changes.
+func (r *WorkbenchReconciler) now() metav1.Time {
+	if r.Clock == nil {
+		return metav1.Now()
 	}
 
-	return &foundJob, err
+	return metav1.NewTime(r.Clock.Now())
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -399,5 +1305,25 @@ func (r *WorkbenchReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&appsv1.Deployment{}).
 		Owns(&batchv1.Job{}).
 		Owns(&corev1.Service{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.workbenchesForSecret),
+		).
+		// The children in a target namespace have no owner reference.
+		Watches(&appsv1.Deployment{}, handler.EnqueueRequestsFromMapFunc(workbenchForTargetedChild)).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(workbenchForTargetedChild)).
+		Watches(&corev1.Service{}, handler.EnqueueRequestsFromMapFunc(workbenchForTargetedChild)).
+		Watches(&corev1.PersistentVolumeClaim{}, handler.EnqueueRequestsFromMapFunc(workbenchForTargetedChild)).
+		// The deployment does not tell about the restarts of its containers.
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(workbenchForServerPod),
+			builder.WithPredicates(serverPodStatusChanged),
+		).
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.workbenchesForTarget),
+		).
 		Complete(r)
 }
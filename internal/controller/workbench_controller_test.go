@@ -2,7 +2,10 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -10,15 +13,135 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
 )
 
+// countingClient counts the writes made to the status subresource.
+type countingClient struct {
+	client.Client
+
+	statusWrites int
+}
+
+func (c *countingClient) Status() client.SubResourceWriter {
+	return &countingStatusWriter{
+		SubResourceWriter: c.Client.Status(),
+		parent:            c,
+	}
+}
+
+type countingStatusWriter struct {
+	client.SubResourceWriter
+
+	parent *countingClient
+}
+
+func (w *countingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	w.parent.statusWrites++
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+func (w *countingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	w.parent.statusWrites++
+	return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// racingClient runs a concurrent change right before the first patch.
+type racingClient struct {
+	client.Client
+
+	race func()
+}
+
+func (c *racingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if c.race != nil {
+		c.race()
+		c.race = nil
+	}
+
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// slowClient makes every read of a job take a while, like a loaded API server.
+type slowClient struct {
+	client.Client
+
+	delay time.Duration
+}
+
+func (c *slowClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if _, ok := obj.(*batchv1.Job); ok {
+		time.Sleep(c.delay)
+	}
+
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+// suspendDroppingClient strips spec.suspend from the updated jobs, like some admission policies.
+type suspendDroppingClient struct {
+	client.Client
+
+	jobUpdates int
+}
+
+func (c *suspendDroppingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if job, ok := obj.(*batchv1.Job); ok {
+		c.jobUpdates++
+		job.Spec.Suspend = nil
+	}
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// crashingClient fails the job creations past the allowed ones, like an operator dying mid-pass.
+type crashingClient struct {
+	client.Client
+
+	allowedJobs int
+}
+
+func (c *crashingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	createOptions := &client.CreateOptions{}
+	createOptions.ApplyOptions(opts)
+
+	if _, ok := obj.(*batchv1.Job); ok && len(createOptions.DryRun) == 0 {
+		if c.allowedJobs == 0 {
+			return fmt.Errorf("crashed before creating the job %q", obj.GetName())
+		}
+
+		c.allowedJobs--
+	}
+
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// deleteWorkbench removes the workbench, finalizer included as nothing reconciles its deletion.
+func deleteWorkbench(ctx context.Context, key types.NamespacedName) {
+	resource := &defaultv1alpha1.Workbench{}
+	Expect(k8sClient.Get(ctx, key, resource)).To(Succeed())
+
+	if len(resource.Finalizers) > 0 {
+		resource.Finalizers = nil
+		Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+	}
+
+	Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+}
+
 var _ = Describe("Workbench Controller", func() {
 	Context("When reconciling a resource", func() {
 		const resourceName = "test-resource"
@@ -89,17 +212,24 @@ var _ = Describe("Workbench Controller", func() {
 				Scheme:   k8sClient.Scheme(),
 				Recorder: record.NewFakeRecorder(3),
 				Config: Config{
-					Registry:        "my-registry",
-					AppsRepository:  "applications",
-					XpraServerImage: "my-registry/server/xpra-server",
+					Images: ImagesConfig{
+						Registry:       "my-registry",
+						AppsRepository: "applications",
+					},
+					Server: ServerConfig{
+						XpraServerImage: "my-registry/server/xpra-server",
+					},
 				},
 			}
 
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
 
+			// The children were just created, their status is read shortly.
+			Expect(result.RequeueAfter).To(Equal(childCreatedRequeueAfter))
+
 			// Verify that a deployment exists.
 			deploymentNamespacedName := types.NamespacedName{
 				Name:      fmt.Sprintf("%s-server", typeNamespacedName.Name),
@@ -163,4 +293,2982 @@ var _ = Describe("Workbench Controller", func() {
 			Expect(job1.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(1))
 		})
 	})
+
+	Context("When the spec is updated during the first reconcile", func() {
+		const resourceName = "racing-finalizer"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should keep both changes", func() {
+			racing := &racingClient{
+				Client: k8sClient,
+				race: func() {
+					// The backend patching the workbench right after creating it.
+					workbench := &defaultv1alpha1.Workbench{}
+					Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+					workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{{Name: "wezterm"}}
+					Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+				},
+			}
+
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Client = racing
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(racing.race).To(BeNil())
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Finalizers).To(ContainElement(finalizer))
+			Expect(workbench.Spec.Apps).To(HaveLen(1))
+		})
+	})
+
+	Context("When reconciling a resource with many apps", func() {
+		const resourceName = "many-apps"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			for i := 0; i < 15; i++ {
+				workbench.Spec.Apps = append(workbench.Spec.Apps, defaultv1alpha1.WorkbenchApp{
+					Name: fmt.Sprintf("app%d", i),
+				})
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should write the status only once", func() {
+			countingClient := &countingClient{Client: k8sClient}
+
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Client = countingClient
+
+			// The first pass creates the children.
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			// The second pass finds them and reports their status.
+			countingClient.statusWrites = 0
+
+			result := reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(countingClient.statusWrites).To(Equal(1))
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Apps).To(HaveLen(15))
+		})
+	})
+
+	Context("When the apps do not fit in the reconcile budget", func() {
+		const resourceName = "apps-budget"
+		const appCount = 6
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			for i := range appCount {
+				workbench.Spec.Apps = append(workbench.Spec.Apps, defaultv1alpha1.WorkbenchApp{
+					Name: fmt.Sprintf("app%d", i),
+				})
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		countJobs := func() int {
+			jobs := &batchv1.JobList{}
+			Expect(k8sClient.List(ctx, jobs, client.InNamespace("default"), client.MatchingLabels{matchingLabel: resourceName})).To(Succeed())
+
+			return len(jobs.Items)
+		}
+
+		It("should handle them over bounded passes", func() {
+			delay := 50 * time.Millisecond
+
+			controllerReconciler := newTestReconciler(Config{
+				Images: ImagesConfig{
+					AppsRepository: "apps",
+				},
+				Apps: AppConfig{
+					ReconcileBudget: 2 * delay,
+				},
+			})
+			controllerReconciler.Client = &slowClient{Client: k8sClient, delay: delay}
+
+			passes := 0
+			for countJobs() < appCount {
+				Expect(passes).To(BeNumerically("<", appCount), "no progress")
+				passes++
+
+				start := time.Now()
+				result := reconcileOnce(controllerReconciler, typeNamespacedName)
+				Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+				// The apps cost a delay each, at most one over the budget.
+				Expect(time.Since(start)).To(BeNumerically("<", appCount*delay))
+			}
+
+			Expect(passes).To(BeNumerically(">", 1))
+
+			// The statuses follow as the next passes read the jobs.
+			workbench := &defaultv1alpha1.Workbench{}
+			Eventually(func() []defaultv1alpha1.WorkbenchStatusApp {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+
+				Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+				return workbench.Status.Apps
+			}).WithTimeout(5 * time.Second).Should(HaveLen(appCount))
+
+			Expect(workbench.Status.AppCount).To(Equal(appCount))
+		})
+
+		It("should not clean up the jobs of the apps left for later", func() {
+			controllerReconciler := newTestReconciler(Config{
+				Images: ImagesConfig{
+					AppsRepository: "apps",
+				},
+				Apps: AppConfig{
+					MaxPerReconcile: 4,
+				},
+			})
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(countJobs()).To(Equal(4))
+
+			// The second pass starts from the cursor, and keeps the first jobs.
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(countJobs()).To(Equal(appCount))
+
+			// Back from the start.
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(countJobs()).To(Equal(appCount))
+		})
+	})
+
+	Context("When a previous pass crashed", func() {
+		const resourceName = "crashed-pass"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{Name: "kitty"},
+				{Name: "alacritty"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			// There is no garbage collection in envtest.
+			Expect(k8sClient.DeleteAllOf(
+				ctx,
+				&batchv1.Job{},
+				client.InNamespace("default"),
+				client.MatchingLabels{matchingLabel: resourceName},
+				client.PropagationPolicy("Background"),
+			)).To(Succeed())
+
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should converge without recreating anything", func() {
+			reconcileWith := func(c client.Client) error {
+				controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+				controllerReconciler.Client = c
+
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+
+				return err
+			}
+
+			// Dying after the first app.
+			Expect(reconcileWith(&crashingClient{Client: k8sClient, allowedJobs: 1})).NotTo(Succeed())
+
+			uids := func() map[string]types.UID {
+				jobs := &batchv1.JobList{}
+				Expect(k8sClient.List(ctx, jobs, client.InNamespace("default"), client.MatchingLabels{matchingLabel: resourceName})).To(Succeed())
+
+				uids := map[string]types.UID{}
+				for _, job := range jobs.Items {
+					uids[job.Name] = job.UID
+				}
+
+				return uids
+			}
+
+			before := uids()
+			Expect(before).To(HaveLen(1))
+
+			deployment := &appsv1.Deployment{}
+			deploymentNamespacedName := types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			deploymentUID := deployment.UID
+
+			for range 3 {
+				Expect(reconcileWith(k8sClient)).To(Succeed())
+			}
+
+			after := uids()
+			Expect(after).To(HaveLen(3))
+			for name, uid := range before {
+				Expect(after).To(HaveKeyWithValue(name, uid))
+			}
+
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.UID).To(Equal(deploymentUID))
+		})
+	})
+
+	Context("When an app is stopped from the start", func() {
+		const resourceName = "never-started"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{
+					Name: "wezterm",
+				},
+				{
+					Name:  "kitty",
+					State: defaultv1alpha1.WorkbenchAppStateStopped,
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should report it in the status without creating its job", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			job := &batchv1.Job{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-1-kitty", Namespace: "default"}, job)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Apps).To(HaveLen(2))
+			Expect(workbench.Status.Apps[1].Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusStopped))
+			Expect(workbench.Status.Apps[1].DesiredState).To(Equal(defaultv1alpha1.WorkbenchAppStateStopped))
+
+			// The running app is kept.
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-0-wezterm", Namespace: "default"}, job)).To(Succeed())
+		})
+	})
+
+	Context("When a running app is stopped", func() {
+		const resourceName = "stopped-later"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		jobNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-0-wezterm",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{
+					Name: "wezterm",
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should suspend its job instead of recreating it", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, jobNamespacedName, job)).To(Succeed())
+			uid := job.UID
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.Apps[0].State = defaultv1alpha1.WorkbenchAppStateStopped
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			for range 3 {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+
+				Expect(k8sClient.Get(ctx, jobNamespacedName, job)).To(Succeed())
+				Expect(job.UID).To(Equal(uid))
+				Expect(job.DeletionTimestamp).To(BeNil())
+				Expect(job.Spec.Suspend).To(HaveValue(BeTrue()))
+			}
+		})
+	})
+
+	Context("When an app is removed", func() {
+		const resourceName = "removed-app"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{Name: "kitty"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should delete all its objects", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			exists := func(obj client.Object) bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+				if errors.IsNotFound(err) {
+					return false
+				}
+
+				Expect(err).NotTo(HaveOccurred())
+
+				return true
+			}
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			kittyJob := &batchv1.Job{}
+			kittyJob.Name = resourceName + "-1-kitty"
+			kittyJob.Namespace = "default"
+			Expect(exists(kittyJob)).To(BeTrue())
+			Expect(kittyJob.Labels).To(HaveKeyWithValue(appUIDLabel, "1-kitty"))
+
+			// Auxiliary per-app services, as published ports would have.
+			appService := func(uid string) *corev1.Service {
+				service := &corev1.Service{}
+				service.Name = resourceName + "-" + uid
+				service.Namespace = "default"
+				service.Labels = map[string]string{
+					matchingLabel: resourceName,
+					appUIDLabel:   uid,
+				}
+				service.Spec.Ports = []corev1.ServicePort{{Name: "http", Port: 8080}}
+
+				Expect(k8sClient.Create(ctx, service)).To(Succeed())
+
+				return service
+			}
+
+			weztermService := appService("0-wezterm")
+			kittyService := appService("1-kitty")
+
+			DeferCleanup(func() {
+				for _, service := range []*corev1.Service{weztermService, kittyService} {
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, service))).To(Succeed())
+				}
+			})
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.Apps = workbench.Spec.Apps[:1]
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(exists(kittyJob)).To(BeFalse())
+			Expect(exists(kittyService)).To(BeFalse())
+
+			// The remaining app, and the workbench service, are kept.
+			Expect(exists(weztermService)).To(BeTrue())
+			Expect(exists(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-0-wezterm", Namespace: "default"}})).To(BeTrue())
+			Expect(exists(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}})).To(BeTrue())
+		})
+	})
+
+	Context("When the cluster does not suspend the jobs", func() {
+		const resourceName = "suspend-unsupported"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{Name: "kitty"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should delete the stopped apps instead", func() {
+			recorder := record.NewFakeRecorder(100)
+			dropping := &suspendDroppingClient{Client: k8sClient}
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Client = dropping
+			controllerReconciler.Recorder = recorder
+
+			reconcileAndGet := func() *defaultv1alpha1.Workbench {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+
+				workbench := &defaultv1alpha1.Workbench{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+				return workbench
+			}
+
+			stop := func(index int) {
+				workbench := &defaultv1alpha1.Workbench{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+				workbench.Spec.Apps[index].State = defaultv1alpha1.WorkbenchAppStateStopped
+				Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+			}
+
+			jobExists := func(name string) bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-" + name, Namespace: "default"}, &batchv1.Job{})
+				if errors.IsNotFound(err) {
+					return false
+				}
+
+				Expect(err).NotTo(HaveOccurred())
+
+				return true
+			}
+
+			unsupportedEvents := func() int {
+				count := 0
+				for len(recorder.Events) > 0 {
+					if event := <-recorder.Events; strings.Contains(event, "SuspendUnsupported") {
+						count++
+					}
+				}
+
+				return count
+			}
+
+			reconcileAndGet()
+			Expect(jobExists("0-wezterm")).To(BeTrue())
+
+			stop(0)
+			workbench := reconcileAndGet()
+
+			Expect(dropping.jobUpdates).To(Equal(suspendAttempts))
+			Expect(jobExists("0-wezterm")).To(BeFalse())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusStopped))
+			Expect(workbench.Status.Apps[0].Message).To(ContainSubstring("SuspendUnsupported"))
+			Expect(unsupportedEvents()).To(Equal(1))
+
+			// Not recreated.
+			reconcileAndGet()
+			Expect(jobExists("0-wezterm")).To(BeFalse())
+
+			// The next app is deleted right away, without a warning.
+			stop(1)
+			workbench = reconcileAndGet()
+
+			Expect(dropping.jobUpdates).To(Equal(suspendAttempts))
+			Expect(jobExists("1-kitty")).To(BeFalse())
+			Expect(workbench.Status.Apps[1].Message).To(ContainSubstring("SuspendUnsupported"))
+			Expect(unsupportedEvents()).To(Equal(0))
+		})
+	})
+
+	Context("When an app job finishes", func() {
+		const resourceName = "app-history"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should record it exactly once", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+			app := defaultv1alpha1.WorkbenchApp{
+				Name: "wezterm",
+			}
+
+			startTime := metav1.NewTime(time.Now().Add(-time.Hour))
+			completionTime := metav1.NewTime(startTime.Add(30 * time.Minute))
+
+			job := batchv1.Job{}
+			job.Name = resourceName + "-0-wezterm"
+			job.UID = "0000-1111"
+			job.Status.StartTime = &startTime
+			job.Status.CompletionTime = &completionTime
+			job.Status.Conditions = []batchv1.JobCondition{
+				{
+					Type:   batchv1.JobComplete,
+					Status: corev1.ConditionTrue,
+				},
+			}
+
+			for i := 0; i < 3; i++ {
+				Expect(controllerReconciler.recordAppHistory(ctx, workbench, app, job, nil)).To(Succeed())
+			}
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-app-history",
+				Namespace: "default",
+			}, configMap)).To(Succeed())
+
+			Expect(configMap.Data).To(HaveLen(1))
+			Expect(configMap.Data).To(HaveKey("0000-1111"))
+
+			record := appHistoryRecord{}
+			Expect(json.Unmarshal([]byte(configMap.Data["0000-1111"]), &record)).To(Succeed())
+			Expect(record.App).To(Equal("wezterm"))
+			Expect(record.Job).To(Equal(job.Name))
+			Expect(record.Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusComplete))
+			Expect(record.DurationSeconds).To(Equal(int64(1800)))
+		})
+
+		It("should ignore running jobs", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+			job := batchv1.Job{}
+			job.Name = resourceName + "-0-wezterm"
+			job.UID = "2222-3333"
+			job.Status.Active = 1
+
+			Expect(controllerReconciler.recordAppHistory(ctx, workbench, defaultv1alpha1.WorkbenchApp{Name: "wezterm"}, job, nil)).To(Succeed())
+
+			// The ConfigMap may have been left by another spec, as envtest has no garbage collector.
+			configMap := &corev1.ConfigMap{}
+			err := k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-app-history",
+				Namespace: "default",
+			}, configMap)
+			if err == nil {
+				Expect(configMap.Data).NotTo(HaveKey("2222-3333"))
+			} else {
+				Expect(errors.IsNotFound(err)).To(BeTrue())
+			}
+		})
+	})
+
+	Context("When renaming the workbench", func() {
+		const resourceName = "display-name"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		deploymentNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-server",
+			Namespace: "default",
+		}
+
+		jobNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-0-wezterm",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.DisplayName = "  My thesis analysis "
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should rename the children, and only the new pods", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "My thesis analysis"))
+			Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "My thesis analysis"))
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, jobNamespacedName, job)).To(Succeed())
+			Expect(job.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "My thesis analysis"))
+			Expect(job.Spec.Template.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "My thesis analysis"))
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.DisplayName = "Final analysis"
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "Final analysis"))
+			Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "Final analysis"))
+
+			Expect(k8sClient.Get(ctx, jobNamespacedName, job)).To(Succeed())
+			Expect(job.Annotations).To(HaveKeyWithValue(displayNameAnnotation, "Final analysis"))
+
+			// Removing it removes the annotation.
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.DisplayName = ""
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Annotations).NotTo(HaveKey(displayNameAnnotation))
+			Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey(displayNameAnnotation))
+		})
+	})
+
+	Context("When propagating labels", func() {
+		const resourceName = "labels"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+					Labels: map[string]string{
+						"cost-center": "abc",
+						"unrelated":   "value",
+					},
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{
+					Name: "wezterm",
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should copy and update them on the children", func() {
+			controllerReconciler := newTestReconciler(Config{
+				Images: ImagesConfig{
+					AppsRepository: "apps",
+				},
+				Labels: LabelsConfig{
+					PropagatedKeys: []string{"cost-center"},
+				},
+			})
+
+			deploymentKey := types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}
+			jobKey := types.NamespacedName{Name: resourceName + "-0-wezterm", Namespace: "default"}
+
+			expectLabel := func(value string) {
+				deployment := &appsv1.Deployment{}
+				Expect(k8sClient.Get(ctx, deploymentKey, deployment)).To(Succeed())
+				Expect(deployment.Labels).To(HaveKeyWithValue("cost-center", value))
+				Expect(deployment.Labels).NotTo(HaveKey("unrelated"))
+				// The selector is left untouched.
+				Expect(deployment.Spec.Selector.MatchLabels).NotTo(HaveKey("cost-center"))
+
+				service := &corev1.Service{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+				Expect(service.Labels).To(HaveKeyWithValue("cost-center", value))
+				Expect(service.Spec.Selector).NotTo(HaveKey("cost-center"))
+
+				job := &batchv1.Job{}
+				Expect(k8sClient.Get(ctx, jobKey, job)).To(Succeed())
+				Expect(job.Labels).To(HaveKeyWithValue("cost-center", value))
+			}
+
+			for i := 0; i < 2; i++ {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+			}
+
+			expectLabel("abc")
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Labels["cost-center"] = "xyz"
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			expectLabel("xyz")
+		})
+
+		It("should reject the operator managed keys", func() {
+			Expect(ValidatePropagatedLabelKeys([]string{"cost-center"})).To(Succeed())
+			Expect(ValidatePropagatedLabelKeys([]string{matchingLabel})).NotTo(Succeed())
+			Expect(ValidatePropagatedLabelKeys([]string{""})).NotTo(Succeed())
+			Expect(ValidatePropagatedLabelKeys([]string{"example.com/cost-center"})).To(Succeed())
+			Expect(ValidatePropagatedLabelKeys([]string{" cost-center"})).NotTo(Succeed())
+			Expect(ValidatePropagatedLabelKeys([]string{"cost center"})).NotTo(Succeed())
+			Expect(ValidatePropagatedLabelKeys([]string{"a/b/c"})).NotTo(Succeed())
+		})
+	})
+
+	Context("When reporting the effective configuration", func() {
+		const resourceName = "effective"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Server.Version = "6.2.1"
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{
+					Name:    "wezterm",
+					Version: "1.0.0",
+				},
+				{
+					Name: "kitty",
+					Image: &defaultv1alpha1.Image{
+						Registry:   "quay.io",
+						Repository: "kitty/kitty",
+					},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should match the rendered objects", func() {
+			controllerReconciler := newTestReconciler(Config{
+				Images: ImagesConfig{
+					Registry:       "my-registry",
+					AppsRepository: "applications",
+				},
+				Server: ServerConfig{
+					SocatImage: "alpine/socat:1.8.0.0",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+					},
+				},
+				Apps: AppConfig{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					},
+				},
+			})
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Effective).NotTo(BeNil())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}, deployment)).To(Succeed())
+
+			Expect(workbench.Status.Effective.ServerImage).To(Equal(deployment.Spec.Template.Spec.Containers[0].Image))
+			Expect(workbench.Status.Effective.ServerImage).To(Equal("my-registry/applications/xpra-server:6.2.1"))
+			Expect(workbench.Status.Effective.SidecarImage).To(Equal(deployment.Spec.Template.Spec.InitContainers[0].Image))
+			Expect(workbench.Status.Effective.ServerResources).To(Equal(deployment.Spec.Template.Spec.Containers[0].Resources))
+			Expect(workbench.Status.Effective.ServerResources.Requests.Memory().String()).To(Equal("512Mi"))
+
+			Expect(workbench.Status.Effective.Apps).To(HaveLen(2))
+			for index, name := range []string{"wezterm", "kitty"} {
+				job := &batchv1.Job{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%d-%s", resourceName, index, name), Namespace: "default"}, job)).To(Succeed())
+
+				Expect(workbench.Status.Effective.Apps[index].Name).To(Equal(name))
+				Expect(workbench.Status.Effective.Apps[index].Image).To(Equal(job.Spec.Template.Spec.Containers[0].Image))
+				Expect(workbench.Status.Effective.Apps[index].Resources).To(Equal(job.Spec.Template.Spec.Containers[0].Resources))
+				Expect(workbench.Status.Effective.Apps[index].Resources.Requests.Memory().String()).To(Equal("1Gi"))
+			}
+
+			Expect(workbench.Status.Effective.Apps[0].Image).To(Equal("my-registry/applications/wezterm:1.0.0"))
+			Expect(workbench.Status.Effective.Apps[1].Image).To(Equal("quay.io/kitty/kitty:latest"))
+		})
+	})
+
+	Context("When checking the image pull secrets", func() {
+		const resourceName = "pull-secrets"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			valid := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "valid-pull-secret",
+					Namespace: "default",
+				},
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`),
+				},
+			}
+			Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, valid))).To(Succeed())
+
+			opaque := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "opaque-pull-secret",
+					Namespace: "default",
+				},
+				Type: corev1.SecretTypeOpaque,
+			}
+			Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, opaque))).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		createWorkbench := func(secrets ...string) {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+			workbench.Spec.ImagePullSecrets = secrets
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		}
+
+		reconcileAndGetCondition := func(recorder *record.FakeRecorder) *metav1.Condition {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+			return meta.FindStatusCondition(workbench.Status.Conditions, conditionImagePullSecrets)
+		}
+
+		It("should accept valid secrets", func() {
+			createWorkbench("valid-pull-secret")
+
+			recorder := record.NewFakeRecorder(100)
+			condition := reconcileAndGetCondition(recorder)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(recorder.Events).NotTo(Receive(ContainSubstring("ImagePullSecretInvalid")))
+		})
+
+		It("should report missing and wrong-type secrets once", func() {
+			createWorkbench("valid-pull-secret", "missing-pull-secret", "opaque-pull-secret")
+
+			recorder := record.NewFakeRecorder(100)
+			condition := reconcileAndGetCondition(recorder)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("ImagePullSecretInvalid"))
+			Expect(condition.Message).To(ContainSubstring("missing-pull-secret"))
+			Expect(condition.Message).To(ContainSubstring("opaque-pull-secret"))
+			Expect(condition.Message).NotTo(ContainSubstring("valid-pull-secret"))
+
+			Expect(recorder.Events).To(Receive(ContainSubstring("ImagePullSecretInvalid")))
+
+			// The workloads are created anyway.
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}, deployment)).To(Succeed())
+
+			// The event is not repeated on requeues.
+			reconcileAndGetCondition(recorder)
+			Expect(recorder.Events).NotTo(Receive(ContainSubstring("ImagePullSecretInvalid")))
+		})
+	})
+
+	Context("When rendering app sidecars", func() {
+		workbench := defaultv1alpha1.Workbench{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sidecars",
+				Namespace: "default",
+			},
+		}
+
+		shmSize := resource.MustParse("1Gi")
+
+		app := defaultv1alpha1.WorkbenchApp{
+			Name:    "viewer",
+			ShmSize: &shmSize,
+			SidecarContainers: []corev1.Container{
+				{
+					Name:  "dicom-listener",
+					Image: "quay.io/dicom/listener:1.0.0",
+				},
+			},
+		}
+
+		service := corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sidecars",
+				Namespace: "default",
+			},
+		}
+
+		It("should use native sidecars", func() {
+			job, err := initJob(workbench, ImagesConfig{}, AppConfig{}, GPUConfig{}, nil, 0, app, service)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(job.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(job.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+
+			sidecar := job.Spec.Template.Spec.InitContainers[0]
+			Expect(sidecar.Name).To(Equal("dicom-listener"))
+			Expect(sidecar.RestartPolicy).NotTo(BeNil())
+			Expect(*sidecar.RestartPolicy).To(Equal(corev1.ContainerRestartPolicyAlways))
+			Expect(sidecar.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+			Expect(sidecar.Env).To(Equal(job.Spec.Template.Spec.Containers[0].Env))
+			Expect(sidecar.VolumeMounts).To(Equal(job.Spec.Template.Spec.Containers[0].VolumeMounts))
+
+			// The spec is left untouched.
+			Expect(app.SidecarContainers[0].Env).To(BeEmpty())
+		})
+
+		It("should fall back to plain containers", func() {
+			job, err := initJob(workbench, ImagesConfig{}, AppConfig{DisableNativeSidecars: true}, GPUConfig{}, nil, 0, app, service)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(job.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(job.Spec.Template.Spec.Containers).To(HaveLen(2))
+
+			sidecar := job.Spec.Template.Spec.Containers[1]
+			Expect(sidecar.Name).To(Equal("dicom-listener"))
+			Expect(sidecar.RestartPolicy).To(BeNil())
+			Expect(sidecar.Env).To(Equal(job.Spec.Template.Spec.Containers[0].Env))
+		})
+
+		It("should detect the supporting versions", func() {
+			for gitVersion, supported := range map[string]bool{
+				"v1.28.9":          false,
+				"v1.29.0":          true,
+				"v1.31.3+k3s1":     true,
+				"v1.30.2-gke.1587": true,
+			} {
+				Expect(supportsNativeSidecars(gitVersion)).To(Equal(supported), gitVersion)
+			}
+
+			_, err := supportsNativeSidecars("not-a-version")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When protecting the Xpra session", func() {
+		const resourceName = "session-auth"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		secretNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-xpra-auth",
+			Namespace: "default",
+		}
+
+		deploymentNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-server",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+
+			// There is no garbage collection in envtest.
+			secret := &corev1.Secret{}
+			if err := k8sClient.Get(ctx, secretNamespacedName, secret); err == nil {
+				Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+			}
+		})
+
+		reconcileWith := func(config Config, recorder *record.FakeRecorder) {
+			controllerReconciler := newTestReconciler(config)
+			controllerReconciler.Recorder = recorder
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+		}
+
+		It("should generate the secret and give it to the server", func() {
+			reconcileWith(Config{}, record.NewFakeRecorder(100))
+
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, secretNamespacedName, secret)).To(Succeed())
+			Expect(secret.Data[sessionSecretKey]).To(HaveLen(64))
+			Expect(secret.OwnerReferences).To(HaveLen(1))
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name: "XPRA_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+						Key:                  sessionSecretKey,
+					},
+				},
+			}))
+			Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(sessionSecretRevisionAnnotation, "1"))
+
+			// Only the name is exposed.
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.SessionSecretName).To(Equal(secret.Name))
+		})
+
+		It("should rotate the secret on demand", func() {
+			reconcileWith(Config{}, record.NewFakeRecorder(100))
+
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, secretNamespacedName, secret)).To(Succeed())
+			token := secret.Data[sessionSecretKey]
+
+			// Without the annotation, the token is kept.
+			reconcileWith(Config{}, record.NewFakeRecorder(100))
+			Expect(k8sClient.Get(ctx, secretNamespacedName, secret)).To(Succeed())
+			Expect(secret.Data[sessionSecretKey]).To(Equal(token))
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Annotations = map[string]string{rotateSessionSecretAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			reconcileWith(Config{}, recorder)
+			Expect(recorder.Events).To(Receive(ContainSubstring("RotatedSessionSecret")))
+
+			Expect(k8sClient.Get(ctx, secretNamespacedName, secret)).To(Succeed())
+			Expect(secret.Data[sessionSecretKey]).NotTo(Equal(token))
+			Expect(secret.Annotations).To(HaveKeyWithValue(sessionSecretRevisionAnnotation, "2"))
+
+			// The server restarts with the new token.
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(sessionSecretRevisionAnnotation, "2"))
+
+			// The request is consumed.
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Annotations).NotTo(HaveKey(rotateSessionSecretAnnotation))
+		})
+
+		It("should do nothing when disabled", func() {
+			reconcileWith(Config{Server: ServerConfig{DisableSessionAuth: true}}, record.NewFakeRecorder(100))
+
+			secret := &corev1.Secret{}
+			err := k8sClient.Get(ctx, secretNamespacedName, secret)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+				Expect(env.Name).NotTo(Equal("XPRA_PASSWORD"))
+			}
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.SessionSecretName).To(BeEmpty())
+		})
+	})
+
+	Context("When the namespace enforces a Pod Security level", func() {
+		const resourceName = "pod-security"
+
+		ctx := context.Background()
+
+		// One namespace per PodSecurity mode, each with a workbench.
+		namespaces := map[string]string{
+			"pod-security-enforce": "pod-security.kubernetes.io/enforce",
+			"pod-security-audit":   "pod-security.kubernetes.io/audit",
+			"pod-security-warn":    "pod-security.kubernetes.io/warn",
+		}
+
+		BeforeEach(func() {
+			for name, label := range namespaces {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+						Labels: map[string]string{
+							label: "restricted",
+						},
+					},
+				}
+				Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, namespace))).To(Succeed())
+
+				workbench := &defaultv1alpha1.Workbench{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: name,
+					},
+				}
+				workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+					{Name: "wezterm"},
+					{Name: "kitty", State: defaultv1alpha1.WorkbenchAppStateStopped},
+				}
+				Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			for name := range namespaces {
+				deleteWorkbench(ctx, types.NamespacedName{Name: resourceName, Namespace: name})
+			}
+		})
+
+		reconcileIn := func(namespace string, recorder *record.FakeRecorder) *metav1.Condition {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			key := types.NamespacedName{Name: resourceName, Namespace: namespace}
+			reconcileOnce(controllerReconciler, key)
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, key, workbench)).To(Succeed())
+
+			return meta.FindStatusCondition(workbench.Status.Conditions, conditionBlocked)
+		}
+
+		It("should not create the refused jobs when enforced", func() {
+			recorder := record.NewFakeRecorder(100)
+			condition := reconcileIn("pod-security-enforce", recorder)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("PodSecurityRestricted"))
+			Expect(condition.Message).To(ContainSubstring(`the server, the app "wezterm" would be refused`))
+			Expect(condition.Message).NotTo(ContainSubstring("kitty"))
+
+			Expect(recorder.Events).To(Receive(ContainSubstring("PodSecurityRestricted")))
+
+			job := &batchv1.Job{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-0-wezterm", Namespace: "pod-security-enforce"}, job)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			// The event is not repeated on requeues.
+			reconcileIn("pod-security-enforce", recorder)
+			Expect(recorder.Events).NotTo(Receive(ContainSubstring("PodSecurityRestricted")))
+		})
+
+		It("should only be informative when audited or warned", func() {
+			for _, namespace := range []string{"pod-security-audit", "pod-security-warn"} {
+				condition := reconcileIn(namespace, record.NewFakeRecorder(100))
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse), namespace)
+
+				job := &batchv1.Job{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-0-wezterm", Namespace: namespace}, job)).To(Succeed())
+			}
+		})
+	})
+
+	Context("When a reference reaches out of the namespace", func() {
+		const resourceName = "foreign-reference"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.ServiceAccount = "workbench-operator-system/controller-manager"
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should refuse it with a condition", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			reconcileAndGet := func() *defaultv1alpha1.Workbench {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+
+				workbench := &defaultv1alpha1.Workbench{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+				return workbench
+			}
+
+			workbench := reconcileAndGet()
+
+			condition := meta.FindStatusCondition(workbench.Status.Conditions, conditionReferences)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("CrossNamespaceReference"))
+			Expect(condition.Message).To(ContainSubstring("spec.serviceAccountName"))
+
+			Expect(recorder.Events).To(Receive(ContainSubstring("CrossNamespaceReference")))
+
+			deployment := &appsv1.Deployment{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}, deployment)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			// The event is not repeated on requeues.
+			reconcileAndGet()
+			Expect(recorder.Events).NotTo(Receive())
+
+			// Once fixed, the workbench is created.
+			workbench.Spec.ServiceAccount = ""
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			workbench = reconcileAndGet()
+			Expect(meta.IsStatusConditionTrue(workbench.Status.Conditions, conditionReferences)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}, deployment)).To(Succeed())
+		})
+	})
+
+	Context("When validating the app images", func() {
+		DescribeTable("the image references",
+			func(image string, valid bool) {
+				if valid {
+					Expect(validateAppImage(image)).To(Succeed())
+				} else {
+					Expect(validateAppImage(image)).NotTo(Succeed())
+				}
+			},
+			Entry("a bare name", "wezterm:latest", true),
+			Entry("a repository", "apps/wezterm:1.0.0", true),
+			Entry("a registry", "quay.io/chorus/apps/wezterm:1.0.0", true),
+			Entry("a registry with a port", "localhost:5000/wezterm:1.0", true),
+			Entry("separators", "my-registry/chorus_apps/wez.term:v1_2-3", true),
+			Entry("a capital in the name", "apps/WezTerm:latest", false),
+			Entry("a capital in the bare name", "WezTerm:latest", false),
+			Entry("no tag", "apps/wezterm", false),
+			Entry("an empty tag", "apps/wezterm:", false),
+			Entry("a leading separator", "apps/_wezterm:latest", false),
+			Entry("a space", "apps/wez term:latest", false),
+		)
+	})
+
+	Context("When an app image is invalid", func() {
+		const resourceName = "invalid-image"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{
+					Name:  "kitty",
+					Image: &defaultv1alpha1.Image{Registry: "quay.io", Repository: "Chorus/Kitty"},
+				},
+				{Name: "alacritty"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should only fail that app", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			for _, name := range []string{"0-wezterm", "2-alacritty"} {
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-" + name, Namespace: "default"}, &batchv1.Job{})).To(Succeed())
+			}
+
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-1-kitty", Namespace: "default"}, &batchv1.Job{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Apps[1].Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusFailed))
+			Expect(workbench.Status.Apps[1].Message).To(ContainSubstring("Chorus/Kitty"))
+
+			found := false
+			for len(recorder.Events) > 0 {
+				if event := <-recorder.Events; strings.Contains(event, "InvalidAppImage") {
+					found = true
+				}
+			}
+			Expect(found).To(BeTrue())
+		})
+	})
+
+	Context("When no registry is configured", func() {
+		const resourceName = "no-registry"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{
+					Name:  "kitty",
+					Image: &defaultv1alpha1.Image{Registry: "quay.io", Repository: "chorus/kitty"},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should fail the apps without an image", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{})
+			controllerReconciler.Recorder = recorder
+
+			for range 2 {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+			}
+
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-0-wezterm", Namespace: "default"}, &batchv1.Job{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-1-kitty", Namespace: "default"}, &batchv1.Job{})).To(Succeed())
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusFailed))
+			Expect(workbench.Status.Apps[0].Message).To(HavePrefix("NoRegistryConfigured"))
+
+			// Only once.
+			Expect(recorder.Events).To(HaveLen(1))
+			Expect(<-recorder.Events).To(ContainSubstring("--apps-repository"))
+		})
+	})
+
+	Context("When the API server rejects a job", func() {
+		const resourceName = "rejected-job"
+		const namespaceName = "rejected-job-quota"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: namespaceName,
+		}
+
+		BeforeEach(func() {
+			namespace := &corev1.Namespace{}
+			namespace.Name = namespaceName
+			Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, namespace))).To(Succeed())
+
+			// No job fits, the quota controller is faked by setting the status.
+			quota := &corev1.ResourceQuota{}
+			quota.Name = "no-jobs"
+			quota.Namespace = namespaceName
+			quota.Spec.Hard = corev1.ResourceList{
+				"count/jobs.batch": resource.MustParse("0"),
+			}
+			Expect(k8sClient.Create(ctx, quota)).To(Succeed())
+
+			quota.Status.Hard = quota.Spec.Hard
+			quota.Status.Used = corev1.ResourceList{
+				"count/jobs.batch": resource.MustParse("0"),
+			}
+			Expect(k8sClient.Status().Update(ctx, quota)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, quota)).To(Succeed())
+			})
+
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{Name: "kitty"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should fail only the rejected apps", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			reconcileAndGet := func() *defaultv1alpha1.Workbench {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+
+				workbench := &defaultv1alpha1.Workbench{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+				return workbench
+			}
+
+			workbench := reconcileAndGet()
+
+			Expect(workbench.Status.Apps).To(HaveLen(2))
+			for _, app := range workbench.Status.Apps {
+				Expect(app.Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusFailed))
+				Expect(app.Message).To(ContainSubstring("exceeded quota"))
+			}
+
+			// The rest of the workbench is there.
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: namespaceName}, deployment)).To(Succeed())
+
+			Expect(recorder.Events).To(Receive(ContainSubstring("JobRejected")))
+			Expect(recorder.Events).To(Receive(ContainSubstring("JobRejected")))
+
+			// The rejection is not repeated on requeues.
+			reconcileAndGet()
+			for len(recorder.Events) > 0 {
+				Expect(<-recorder.Events).NotTo(ContainSubstring("JobRejected"))
+			}
+		})
+
+		It("should fail the reconcile without the dry run", func() {
+			controllerReconciler := newTestReconciler(Config{
+				Images: ImagesConfig{
+					AppsRepository: "apps",
+				},
+				Apps: AppConfig{
+					DisableJobDryRun: true,
+				},
+			})
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(errors.IsForbidden(err)).To(BeTrue())
+		})
+	})
+
+	Context("When replicating the shared pull secret", func() {
+		const resourceName = "replicated-pull-secret"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "pull-secret-copy",
+		}
+
+		sourceNamespacedName := types.NamespacedName{
+			Name:      "registry",
+			Namespace: "pull-secret-source",
+		}
+
+		copyNamespacedName := types.NamespacedName{
+			Name:      sourceNamespacedName.Name,
+			Namespace: typeNamespacedName.Namespace,
+		}
+
+		config := Config{
+			Images: ImagesConfig{
+				ReplicatePullSecret: sourceNamespacedName,
+				AppsRepository:      "apps",
+			},
+		}
+
+		BeforeEach(func() {
+			for _, name := range []string{sourceNamespacedName.Namespace, typeNamespacedName.Namespace} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, namespace))).To(Succeed())
+			}
+
+			source := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceNamespacedName.Name,
+					Namespace: sourceNamespacedName.Namespace,
+				},
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`),
+				},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      typeNamespacedName.Name,
+					Namespace: typeNamespacedName.Namespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			for _, key := range []types.NamespacedName{sourceNamespacedName, copyNamespacedName} {
+				secret := &corev1.Secret{}
+				if err := k8sClient.Get(ctx, key, secret); err == nil {
+					Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+				}
+			}
+		})
+
+		It("should copy, rotate, and clean it up", func() {
+			reconcileOnce(newTestReconciler(config), typeNamespacedName)
+
+			source := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, sourceNamespacedName, source)).To(Succeed())
+
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, copyNamespacedName, secret)).To(Succeed())
+			Expect(secret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+			Expect(secret.Data).To(Equal(source.Data))
+			Expect(secret.Labels).To(HaveKeyWithValue(replicatedPullSecretLabel, "true"))
+			Expect(secret.Annotations).To(HaveKeyWithValue(replicatedFromVersionAnnotation, source.ResourceVersion))
+
+			// The pods use it without being told.
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: typeNamespacedName.Namespace}, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.ImagePullSecrets).To(ContainElement(corev1.LocalObjectReference{Name: copyNamespacedName.Name}))
+
+			// The source changes are watched.
+			reconciler := &WorkbenchReconciler{Client: k8sClient, Config: config}
+			Expect(reconciler.workbenchesForPullSecret(ctx, source)).To(ConsistOf(reconcile.Request{NamespacedName: typeNamespacedName}))
+			Expect(reconciler.workbenchesForPullSecret(ctx, secret)).To(BeEmpty())
+
+			source.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"quay.io":{}}}`)
+			Expect(k8sClient.Update(ctx, source)).To(Succeed())
+
+			reconcileOnce(newTestReconciler(config), typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, copyNamespacedName, secret)).To(Succeed())
+			Expect(secret.Data).To(Equal(source.Data))
+			Expect(secret.Annotations).To(HaveKeyWithValue(replicatedFromVersionAnnotation, source.ResourceVersion))
+
+			// It goes with the last workbench of the namespace.
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, workbench)).To(Succeed())
+
+			Eventually(func() bool {
+				reconcileOnce(newTestReconciler(config), typeNamespacedName)
+
+				err := k8sClient.Get(ctx, typeNamespacedName, workbench)
+				return errors.IsNotFound(err)
+			}).Should(BeTrue())
+
+			err := k8sClient.Get(ctx, copyNamespacedName, secret)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			// The source is not touched.
+			Expect(k8sClient.Get(ctx, sourceNamespacedName, source)).To(Succeed())
+		})
+	})
+
+	Context("When persisting the Xpra session", func() {
+		const resourceName = "persistent-session"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		claimNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-xpra-session",
+			Namespace: "default",
+		}
+
+		deploymentNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-server",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+			workbench.Spec.Server.PersistentSession = true
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+
+			// There is no garbage collection in envtest.
+			claim := &corev1.PersistentVolumeClaim{}
+			if err := k8sClient.Get(ctx, claimNamespacedName, claim); err == nil {
+				claim.Finalizers = nil
+				Expect(k8sClient.Update(ctx, claim)).To(Succeed())
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, claim))).To(Succeed())
+			}
+		})
+
+		hasSessionMount := func(deployment *appsv1.Deployment) bool {
+			for _, mount := range deployment.Spec.Template.Spec.Containers[0].VolumeMounts {
+				if mount.Name == sessionStateVolumeName {
+					return mount.MountPath == sessionStateMountPath
+				}
+			}
+
+			return false
+		}
+
+		It("should mount the claim until disabled", func() {
+			reconcileOnce(newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}}), typeNamespacedName)
+
+			claim := &corev1.PersistentVolumeClaim{}
+			Expect(k8sClient.Get(ctx, claimNamespacedName, claim)).To(Succeed())
+			Expect(claim.OwnerReferences).To(HaveLen(1))
+			Expect(claim.Spec.Resources.Requests.Storage().Equal(sessionStateStorage)).To(BeTrue())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(hasSessionMount(deployment)).To(BeTrue())
+
+			// Nothing changes on the next pass.
+			reconcileOnce(newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}}), typeNamespacedName)
+			resourceVersion := deployment.ResourceVersion
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.ResourceVersion).To(Equal(resourceVersion))
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.Server.PersistentSession = false
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}}), typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(hasSessionMount(deployment)).To(BeFalse())
+			for _, volume := range deployment.Spec.Template.Spec.Volumes {
+				Expect(volume.Name).NotTo(Equal(sessionStateVolumeName))
+			}
+
+			// The state is kept, should it be enabled again.
+			Expect(k8sClient.Get(ctx, claimNamespacedName, claim)).To(Succeed())
+		})
+	})
+
+	Context("When the server selector changed", func() {
+		const resourceName = "selector-migration"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		deploymentNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-server",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+
+			// A deployment made by an older operator, with other selector labels.
+			labels := map[string]string{"app": resourceName}
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      deploymentNamespacedName.Name,
+					Namespace: deploymentNamespacedName.Namespace,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: labels,
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "xpra-server", Image: "xpra-server:old"},
+							},
+						},
+					},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+
+			// There is no garbage collection in envtest.
+			deployment := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, deploymentNamespacedName, deployment); err == nil {
+				Expect(k8sClient.Delete(ctx, deployment)).To(Succeed())
+			}
+		})
+
+		reconcileWith := func(config Config, recorder *record.FakeRecorder) *metav1.Condition {
+			controllerReconciler := newTestReconciler(config)
+			controllerReconciler.Recorder = recorder
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+			return meta.FindStatusCondition(workbench.Status.Conditions, conditionSelectorMigration)
+		}
+
+		It("should report it without touching the deployment", func() {
+			recorder := record.NewFakeRecorder(100)
+			condition := reconcileWith(Config{}, recorder)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("SelectorChanged"))
+
+			Expect(recorder.Events).To(Receive(ContainSubstring("SelectorChanged")))
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("xpra-server:old"))
+
+			// The event is not repeated on requeues.
+			reconcileWith(Config{}, recorder)
+			Expect(recorder.Events).NotTo(Receive(ContainSubstring("SelectorChanged")))
+		})
+
+		It("should recreate the deployment when allowed", func() {
+			config := Config{Images: ImagesConfig{AppsRepository: "apps"}, Server: ServerConfig{RecreateOnSelectorChange: true}}
+
+			recorder := record.NewFakeRecorder(100)
+			reconcileWith(config, recorder)
+			Expect(recorder.Events).To(Receive(ContainSubstring("SelectorChanged")))
+			Expect(recorder.Events).To(Receive(ContainSubstring("RecreatingDeployment")))
+
+			// Without the garbage collector, the deletion is immediate.
+			deployment := &appsv1.Deployment{}
+			err := k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			// The next pass creates it with the new selector.
+			reconcileWith(config, recorder)
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Selector.MatchLabels).NotTo(HaveKeyWithValue("app", resourceName))
+
+			condition := reconcileWith(config, recorder)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		})
+	})
+
+	Context("When the deletion is blocked", func() {
+		const resourceName = "stuck-deletion"
+		const stuckFinalizer = "test.chorus-tre.ch/stuck"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		jobNamespacedName := types.NamespacedName{
+			Name:      resourceName + "-0-wezterm",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		It("should explain what is pending", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{
+				Images: ImagesConfig{
+					AppsRepository: "apps",
+				},
+				Finalization: FinalizationConfig{
+					WarningAfter: time.Minute,
+				},
+			})
+			controllerReconciler.Recorder = recorder
+
+			fakeClock := clocktesting.NewFakeClock(time.Now().Truncate(time.Second))
+			controllerReconciler.Clock = fakeClock
+
+			reconcileAndGet := func() *defaultv1alpha1.Workbench {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+
+				workbench := &defaultv1alpha1.Workbench{}
+				if err := k8sClient.Get(ctx, typeNamespacedName, workbench); errors.IsNotFound(err) {
+					return nil
+				}
+
+				return workbench
+			}
+
+			reconcileAndGet()
+
+			// Without the garbage collector, the finalizer keeps the job around.
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, jobNamespacedName, job)).To(Succeed())
+			job.Finalizers = append(job.Finalizers, stuckFinalizer)
+			Expect(k8sClient.Update(ctx, job)).To(Succeed())
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, workbench)).To(Succeed())
+
+			workbench = reconcileAndGet()
+			Expect(workbench).NotTo(BeNil())
+			Expect(workbench.Status.FirstDeletionAttempt).NotTo(BeNil())
+			Expect(workbench.Status.FirstDeletionAttempt.Time).To(BeTemporally("==", fakeClock.Now()))
+			firstDeletionAttempt := *workbench.Status.FirstDeletionAttempt
+
+			condition := meta.FindStatusCondition(workbench.Status.Conditions, conditionDeleting)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).NotTo(Equal("FinalizationStuck"))
+			Expect(condition.Message).To(ContainSubstring("1 Job(s): Job/" + jobNamespacedName.Name))
+
+			// The next pass past the warning threshold escalates, once.
+			fakeClock.Step(2 * time.Minute)
+			workbench = reconcileAndGet()
+			condition = meta.FindStatusCondition(workbench.Status.Conditions, conditionDeleting)
+			Expect(condition.Reason).To(Equal("FinalizationStuck"))
+			Expect(workbench.Status.FirstDeletionAttempt.Equal(&firstDeletionAttempt)).To(BeTrue())
+
+			reconcileAndGet()
+			warnings := 0
+			for len(recorder.Events) > 0 {
+				if event := <-recorder.Events; strings.Contains(event, "FinalizationStuck") {
+					warnings++
+				}
+			}
+			Expect(warnings).To(Equal(1))
+
+			// Once released, the deployment goes, then the workbench.
+			Expect(k8sClient.Get(ctx, jobNamespacedName, job)).To(Succeed())
+			job.Finalizers = nil
+			Expect(k8sClient.Update(ctx, job)).To(Succeed())
+
+			workbench = reconcileAndGet()
+			condition = meta.FindStatusCondition(workbench.Status.Conditions, conditionDeleting)
+			Expect(condition.Message).To(ContainSubstring("1 Deployment(s): Deployment/" + resourceName + "-server"))
+
+			// Then the service and the session secret, left otherwise to the garbage collector.
+			workbench = reconcileAndGet()
+			condition = meta.FindStatusCondition(workbench.Status.Conditions, conditionDeleting)
+			Expect(condition.Message).To(ContainSubstring("1 Service(s)"))
+			Expect(condition.Message).To(ContainSubstring("Secret/" + resourceName + "-xpra-auth"))
+
+			Expect(reconcileAndGet()).To(BeNil())
+		})
+	})
+
+	Context("When the service is recreated", func() {
+		const resourceName = "recreated-service"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		jobName := resourceName + "-0-wezterm"
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should warn, and restart the apps only when asked", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			reconcileAndGet := func() *defaultv1alpha1.Workbench {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+
+				workbench := &defaultv1alpha1.Workbench{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+				return workbench
+			}
+
+			// recreateService removes the service, the next reconcile creates a new one.
+			recreateService := func() {
+				service := &corev1.Service{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, service)).To(Succeed())
+			}
+
+			recreatedEvents := func() int {
+				count := 0
+				for len(recorder.Events) > 0 {
+					if event := <-recorder.Events; strings.Contains(event, "ServiceRecreated") {
+						count++
+					}
+				}
+
+				return count
+			}
+
+			workbench := reconcileAndGet()
+
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(workbench.Status.ServiceUID).To(Equal(service.UID))
+			Expect(recreatedEvents()).To(Equal(0))
+
+			// Without a job controller, the app pod is faked.
+			pod := &corev1.Pod{}
+			pod.Name = jobName + "-pod"
+			pod.Namespace = "default"
+			pod.Labels = map[string]string{batchv1.JobNameLabel: jobName}
+			pod.Spec.Containers = []corev1.Container{{Name: "wezterm", Image: "wezterm"}}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pod))).To(Succeed())
+			})
+
+			recreateService()
+			workbench = reconcileAndGet()
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(workbench.Status.ServiceUID).To(Equal(service.UID))
+			Expect(recreatedEvents()).To(Equal(1))
+
+			// The pod is kept by default.
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+
+			// The same service is not reported twice.
+			reconcileAndGet()
+			Expect(recreatedEvents()).To(Equal(0))
+
+			controllerReconciler.Config.Apps.RestartOnServiceRecreation = true
+			recreateService()
+			reconcileAndGet()
+
+			Expect(recreatedEvents()).To(Equal(1))
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), pod)
+				return errors.IsNotFound(err)
+			}).Should(BeTrue())
+		})
+	})
+
+	Context("When the job of a completed app is reaped", func() {
+		const resourceName = "reaped-job"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		jobName := types.NamespacedName{
+			Name:      resourceName + "-0-wezterm",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm", Version: "1.0"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should not start the app again, until its image changes", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, jobName, job)).To(Succeed())
+
+			// There is no job controller in envtest, the completion is reported as observed
+			// before the TTL controller reaped the job.
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+			completionTime := metav1.Now()
+			workbench.Status.Apps[0].Status = defaultv1alpha1.WorkbenchStatusAppStatusComplete
+			workbench.Status.Apps[0].CompletionTime = &completionTime
+			workbench.Status.Apps[0].CompletedImage = job.Spec.Template.Spec.Containers[0].Image
+			Expect(k8sClient.Status().Update(ctx, workbench)).To(Succeed())
+
+			Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, jobName, &batchv1.Job{}))
+			}).Should(BeTrue())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, jobName, &batchv1.Job{}))).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusComplete))
+
+			// Another version is a new app to run.
+			workbench.Spec.Apps[0].Version = "2.0"
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, jobName, job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("apps/wezterm:2.0"))
+		})
+	})
+
+	Context("When the image of an app changes", func() {
+		const resourceName = "app-update"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		jobName := types.NamespacedName{
+			Name:      resourceName + "-0-wezterm",
+			Namespace: "default",
+		}
+
+		newApp := func(tag string) defaultv1alpha1.WorkbenchApp {
+			return defaultv1alpha1.WorkbenchApp{
+				Name:  "wezterm",
+				Image: &defaultv1alpha1.Image{Registry: "registry.local", Repository: "apps/wezterm", Tag: tag},
+			}
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{newApp("1.0")}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should replace the job with one running the new image", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			updatedEvents := func() int {
+				count := 0
+				for len(recorder.Events) > 0 {
+					if event := <-recorder.Events; strings.Contains(event, "AppUpdated") {
+						count++
+					}
+				}
+
+				return count
+			}
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, jobName, job)).To(Succeed())
+			Expect(updatedEvents()).To(Equal(0))
+			oldUID := job.UID
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.Apps[0].Image.Tag = "2.0"
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(updatedEvents()).To(Equal(1))
+
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, jobName, &batchv1.Job{}))
+			}).Should(BeTrue())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, jobName, job)).To(Succeed())
+			Expect(job.UID).NotTo(Equal(oldUID))
+			Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.local/apps/wezterm:2.0"))
+
+			// Once replaced, it stays.
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(updatedEvents()).To(Equal(0))
+		})
+	})
+
+	Context("When an app is not ready in time", func() {
+		const resourceName = "startup-deadline"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		jobName := types.NamespacedName{
+			Name:      resourceName + "-0-wezterm",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm", StartupDeadlineSeconds: ptr.To(int32(60))},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should fail it once, and stop its job", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}, Apps: AppConfig{KillOnStartupDeadline: true}})
+			controllerReconciler.Recorder = recorder
+
+			lateEvents := func() int {
+				count := 0
+				for len(recorder.Events) > 0 {
+					if event := <-recorder.Events; strings.Contains(event, "StartupDeadlineExceeded") {
+						count++
+					}
+				}
+
+				return count
+			}
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			// There is no job controller in envtest, the job started two minutes ago.
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, jobName, job)).To(Succeed())
+
+			startTime := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+			job.Status.StartTime = &startTime
+			job.Status.Active = 1
+			job.Status.Ready = ptr.To(int32(0))
+			Expect(k8sClient.Status().Update(ctx, job)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(lateEvents()).To(Equal(1))
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Apps[0].Status).To(Equal(defaultv1alpha1.WorkbenchStatusAppStatusFailed))
+			Expect(workbench.Status.Apps[0].Message).To(HavePrefix(defaultv1alpha1.StartupDeadlineExceeded))
+
+			Expect(k8sClient.Get(ctx, jobName, job)).To(Succeed())
+			Expect(job.Spec.ActiveDeadlineSeconds).NotTo(BeNil())
+			Expect(*job.Spec.ActiveDeadlineSeconds).To(BeNumerically(">=", 120))
+
+			// Reported once, from the status.
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(lateEvents()).To(Equal(0))
+		})
+	})
+
+	Context("When the apps run in a target namespace", func() {
+		const resourceName = "targeted"
+		const target = "targeted-compute"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		deploymentName := types.NamespacedName{
+			Name:      resourceName + "-server",
+			Namespace: target,
+		}
+
+		BeforeEach(func() {
+			namespace := &corev1.Namespace{}
+			namespace.Name = target
+			Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, namespace))).To(Succeed())
+
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.TargetNamespace = target
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{{Name: "wezterm"}}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err := k8sClient.Get(ctx, typeNamespacedName, &defaultv1alpha1.Workbench{})
+			if !errors.IsNotFound(err) {
+				deleteWorkbench(ctx, typeNamespacedName)
+			}
+		})
+
+		It("should only create the children once let in, and delete them", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			By("waiting for the target namespace to allow it")
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			condition := meta.FindStatusCondition(workbench.Status.Conditions, conditionReferences)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("TargetNamespaceNotAllowed"))
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, deploymentName, &appsv1.Deployment{}))).To(BeTrue())
+
+			namespace := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: target}, namespace)).To(Succeed())
+			namespace.Annotations = map[string]string{allowedSourceNamespacesAnnotation: "research, default"}
+			Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+
+			By("creating the children in the target namespace")
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(workbench.Status.Conditions, conditionReferences)).To(BeTrue())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentName, deployment)).To(Succeed())
+			Expect(deployment.OwnerReferences).To(BeEmpty())
+			Expect(deployment.Labels).To(HaveKeyWithValue(sourceNamespaceLabel, "default"))
+			Expect(workbenchForTargetedChild(ctx, deployment)).To(Equal([]reconcile.Request{
+				{NamespacedName: typeNamespacedName},
+			}))
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-0-wezterm", Namespace: target}, job)).To(Succeed())
+			Expect(job.OwnerReferences).To(BeEmpty())
+
+			By("deleting the children with the workbench")
+			Expect(k8sClient.Delete(ctx, workbench)).To(Succeed())
+
+			Eventually(func() bool {
+				reconcileOnce(controllerReconciler, typeNamespacedName)
+
+				return errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &defaultv1alpha1.Workbench{}))
+			}).Should(BeTrue())
+
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, deploymentName, &appsv1.Deployment{}))).To(BeTrue())
+		})
+	})
+
+	Context("When the server container restarts", func() {
+		const resourceName = "server-restarts"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			pod := &corev1.Pod{}
+			pod.Name = resourceName + "-server"
+			pod.Namespace = "default"
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pod, client.GracePeriodSeconds(0)))).To(Succeed())
+
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should report it without being asked to reconcile", func() {
+			mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+				Scheme:     k8sClient.Scheme(),
+				Metrics:    metricsserver.Options{BindAddress: "0"},
+				Controller: config.Controller{SkipNameValidation: ptr.To(true)},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect((&WorkbenchReconciler{
+				Client:   mgr.GetClient(),
+				Scheme:   mgr.GetScheme(),
+				Recorder: mgr.GetEventRecorderFor("workbench-controller"),
+				Config:   Config{Images: ImagesConfig{AppsRepository: "apps"}},
+			}).SetupWithManager(mgr)).To(Succeed())
+
+			mgrCtx, cancel := context.WithCancel(ctx)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+
+				Expect(mgr.Start(mgrCtx)).To(Succeed())
+			}()
+
+			// Stopped before the workbench is deleted, so the finalizer is not put back.
+			stop := func() {
+				cancel()
+				<-done
+			}
+			DeferCleanup(stop)
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}, &appsv1.Deployment{})
+			}).Should(Succeed())
+
+			// There is no deployment controller in envtest, the pod is made up.
+			pod := &corev1.Pod{}
+			pod.Name = resourceName + "-server"
+			pod.Namespace = "default"
+			pod.Labels = map[string]string{matchingLabel: resourceName}
+			pod.Spec.Containers = []corev1.Container{{Name: serverContainerName, Image: "xpra"}}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name:         serverContainerName,
+					Image:        "xpra",
+					RestartCount: 2,
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			Eventually(func(g Gomega) {
+				workbench := &defaultv1alpha1.Workbench{}
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+				g.Expect(workbench.Status.Server.Restarts).To(Equal(int32(2)))
+				g.Expect(workbench.Status.Server.Message).To(Equal("Error, exit code 1"))
+			}).Should(Succeed())
+
+			stop()
+		})
+	})
+
+	Context("When listing the workbenches", func() {
+		const resourceName = "printer-columns"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{Name: "kitty", State: defaultv1alpha1.WorkbenchAppStateStopped},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should show the running apps", func() {
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+
+			workbench.Status.Server = defaultv1alpha1.WorkbenchStatusServer{
+				Revision: -1,
+				Status:   defaultv1alpha1.WorkbenchStatusServerStatusRunning,
+			}
+			workbench.Status.Apps = []defaultv1alpha1.WorkbenchStatusApp{
+				{Revision: -1, Status: defaultv1alpha1.WorkbenchStatusAppStatusRunning},
+				{Revision: -1, Status: defaultv1alpha1.WorkbenchStatusAppStatusStopped},
+			}
+			Expect(workbench.UpdateStatusAppCounts()).To(BeTrue())
+			Expect(workbench.UpdateStatusPhase()).To(BeTrue())
+			Expect(k8sClient.Status().Update(ctx, workbench)).To(Succeed())
+
+			// What kubectl get shows.
+			clientset, err := kubernetes.NewForConfig(cfg)
+			Expect(err).NotTo(HaveOccurred())
+
+			raw, err := clientset.RESTClient().Get().
+				AbsPath("/apis/default.chorus-tre.ch/v1alpha1/namespaces/default/workbenches", resourceName).
+				SetHeader("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io").
+				DoRaw(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			table := metav1.Table{}
+			Expect(json.Unmarshal(raw, &table)).To(Succeed())
+			Expect(table.Rows).To(HaveLen(1))
+
+			columns := map[string]int{}
+			for index, definition := range table.ColumnDefinitions {
+				columns[definition.Name] = index
+			}
+			Expect(columns).To(HaveKey("Running"))
+			Expect(columns).To(HaveKey("Phase"))
+
+			// JSON numbers are floats.
+			Expect(table.Rows[0].Cells[columns["Running"]]).To(BeNumerically("==", 1))
+			Expect(table.Rows[0].Cells[columns["Phase"]]).To(Equal(string(defaultv1alpha1.WorkbenchPhaseReady)))
+		})
+	})
+
+	Context("When the workbench is suspended", func() {
+		const resourceName = "suspended"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{Name: "kitty", State: defaultv1alpha1.WorkbenchAppStateStopped},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should scale everything to zero, and back", func() {
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+
+			replicas := func() int32 {
+				deployment := &appsv1.Deployment{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}, deployment)).To(Succeed())
+
+				return *deployment.Spec.Replicas
+			}
+
+			suspended := func(uid string) bool {
+				job := &batchv1.Job{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-" + uid, Namespace: "default"}, job)).To(Succeed())
+
+				return isSuspended(*job)
+			}
+
+			setSuspended := func(value bool) {
+				workbench := &defaultv1alpha1.Workbench{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+				workbench.Spec.Suspended = value
+				Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+			}
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(replicas()).To(Equal(int32(1)))
+			Expect(suspended("0-wezterm")).To(BeFalse())
+
+			setSuspended(true)
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(replicas()).To(Equal(int32(0)))
+			Expect(suspended("0-wezterm")).To(BeTrue())
+			Expect(suspended("1-kitty")).To(BeTrue())
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(workbench.Status.Conditions, conditionSuspended)).To(BeTrue())
+			Expect(workbench.Status.Phase).To(Equal(defaultv1alpha1.WorkbenchPhaseHibernated))
+
+			// The service is kept for a fast resume.
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &corev1.Service{})).To(Succeed())
+
+			setSuspended(false)
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+
+			Expect(replicas()).To(Equal(int32(1)))
+			Expect(suspended("0-wezterm")).To(BeFalse())
+			Expect(suspended("1-kitty")).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(meta.FindStatusCondition(workbench.Status.Conditions, conditionSuspended)).To(BeNil())
+		})
+	})
+
+	Context("When the workbench is idle", func() {
+		const resourceName = "idle"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Server.IdleTimeout = &metav1.Duration{Duration: time.Hour}
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should suspend it after the timeout, until woken up", func() {
+			recorder := record.NewFakeRecorder(100)
+
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			replicas := func() int32 {
+				deployment := &appsv1.Deployment{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-server", Namespace: "default"}, deployment)).To(Succeed())
+
+				return *deployment.Spec.Replicas
+			}
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(replicas()).To(Equal(int32(1)))
+
+			// The idle time starts with the first pass.
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Status.Server.LastActivity).NotTo(BeNil())
+
+			Expect(reconcileOnce(controllerReconciler, typeNamespacedName).RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+
+			lastActivity := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			workbench.Status.Server.LastActivity = &lastActivity
+			Expect(k8sClient.Status().Update(ctx, workbench)).To(Succeed())
+
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(replicas()).To(Equal(int32(0)))
+			Expect(recorder.Events).To(Receive(ContainSubstring("IdleSuspended")))
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-0-wezterm", Namespace: "default"}, job)).To(Succeed())
+			Expect(isSuspended(*job)).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			condition := meta.FindStatusCondition(workbench.Status.Conditions, conditionSuspended)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(idleReason))
+			Expect(workbench.Status.Phase).To(Equal(defaultv1alpha1.WorkbenchPhaseHibernated))
+
+			// It stays suspended, as long as nobody wakes it up.
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(replicas()).To(Equal(int32(0)))
+
+			workbench.Annotations = map[string]string{wakeAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(replicas()).To(Equal(int32(1)))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(workbench.Annotations).NotTo(HaveKey(wakeAnnotation))
+			Expect(meta.FindStatusCondition(workbench.Status.Conditions, conditionSuspended)).To(BeNil())
+			Expect(workbench.Status.Server.LastActivity.Time).To(BeTemporally(">", lastActivity.Time))
+		})
+	})
+
+	Context("When the apps come and go", func() {
+		const resourceName = "app-events"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{
+				{Name: "wezterm"},
+				{Name: "kitty", State: defaultv1alpha1.WorkbenchAppStateStopped},
+				{Name: "alacritty"},
+			}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteWorkbench(ctx, typeNamespacedName)
+		})
+
+		It("should tell the starts, the stops and the removals", func() {
+			recorder := record.NewFakeRecorder(100)
+
+			controllerReconciler := newTestReconciler(Config{Images: ImagesConfig{AppsRepository: "apps"}})
+			controllerReconciler.Recorder = recorder
+
+			events := func() []string {
+				received := []string{}
+				for len(recorder.Events) > 0 {
+					received = append(received, <-recorder.Events)
+				}
+
+				return received
+			}
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(events()).To(ContainElements(
+				`Normal AppStarted The app "wezterm" is started by the job "app-events-0-wezterm"`,
+				`Normal AppSuspended The app "kitty" is stopped, its job is not created`,
+				`Normal AppStarted The app "alacritty" is started by the job "app-events-2-alacritty"`,
+			))
+
+			// Nothing changed, nothing is told again.
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(events()).NotTo(ContainElement(MatchRegexp("AppStarted|AppSuspended")))
+
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			workbench.Spec.Apps = workbench.Spec.Apps[:2]
+			Expect(k8sClient.Update(ctx, workbench)).To(Succeed())
+
+			reconcileOnce(controllerReconciler, typeNamespacedName)
+			Expect(events()).To(ContainElement(`Normal AppRemoved The job "app-events-2-alacritty" of a removed app was deleted`))
+		})
+	})
 })
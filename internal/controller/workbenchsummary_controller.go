@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// WorkbenchSummaryReconciler maintains the summary of the workbenches of each namespace.
+type WorkbenchSummaryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Debounce coalesces the workbench events, a busy namespace is summarized once per period.
+	Debounce time.Duration
+
+	// Clock tells the time of the summary updates, the real one unless set.
+	Clock clock.PassiveClock
+}
+
+// +kubebuilder:rbac:groups=default.chorus-tre.ch,resources=workbenchsummaries,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=default.chorus-tre.ch,resources=workbenchsummaries/status,verbs=get;update;patch
+
+// Reconcile recomputes the summary of the namespace.
+func (r *WorkbenchSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	workbenchList := defaultv1alpha1.WorkbenchList{}
+	if err := r.List(ctx, &workbenchList, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := summarize(workbenchList.Items)
+
+	summary := defaultv1alpha1.WorkbenchSummary{}
+	err := r.Get(ctx, types.NamespacedName{Name: defaultv1alpha1.WorkbenchSummaryName, Namespace: req.Namespace}, &summary)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		// Nothing to summarize, nothing to create.
+		if len(workbenchList.Items) == 0 {
+			return ctrl.Result{}, nil
+		}
+
+		summary.Name = defaultv1alpha1.WorkbenchSummaryName
+		summary.Namespace = req.Namespace
+
+		log.V(1).Info("Creating the workbench summary", "namespace", req.Namespace)
+
+		if err := r.Create(ctx, &summary); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// The time only moves when the counts do.
+	status.LastUpdateTime = summary.Status.LastUpdateTime
+	if summary.Status.LastUpdateTime != nil && equality.Semantic.DeepEqual(summary.Status, status) {
+		return ctrl.Result{}, nil
+	}
+
+	now := r.now()
+	status.LastUpdateTime = &now
+	summary.Status = status
+
+	log.V(1).Info("Updating the workbench summary", "namespace", req.Namespace)
+
+	return ctrl.Result{}, r.Status().Update(ctx, &summary)
+}
+
+// now is the time of the summary updates.
+func (r *WorkbenchSummaryReconciler) now() metav1.Time {
+	if r.Clock == nil {
+		return metav1.Now()
+	}
+
+	return metav1.NewTime(r.Clock.Now())
+}
+
+// summarize counts the workbenches and their running apps.
+func summarize(workbenches []defaultv1alpha1.Workbench) defaultv1alpha1.WorkbenchSummaryStatus {
+	status := defaultv1alpha1.WorkbenchSummaryStatus{
+		Workbenches: len(workbenches),
+	}
+
+	for _, workbench := range workbenches {
+		serverStatus := string(workbench.Status.Server.Status)
+		if serverStatus == "" {
+			serverStatus = "Unknown"
+		}
+
+		if status.ServerStatuses == nil {
+			status.ServerStatuses = map[string]int{}
+		}
+
+		status.ServerStatuses[serverStatus]++
+		status.RunningApps += workbench.Status.RunningAppCount
+	}
+
+	return status
+}
+
+// debouncedSummaryHandler enqueues the summary of the namespace of the workbench, after the delay.
+//
+// The delayed requests are deduplicated by the queue, so a burst of events gives a single reconcile.
+func debouncedSummaryHandler(delay time.Duration) handler.EventHandler {
+	enqueue := func(object client.Object, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		queue.AddAfter(reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      defaultv1alpha1.WorkbenchSummaryName,
+				Namespace: object.GetNamespace(),
+			},
+		}, delay)
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(_ context.Context, e event.CreateEvent, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.Object, queue)
+		},
+		UpdateFunc: func(_ context.Context, e event.UpdateEvent, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.ObjectNew, queue)
+		},
+		DeleteFunc: func(_ context.Context, e event.DeleteEvent, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.Object, queue)
+		},
+		GenericFunc: func(_ context.Context, e event.GenericEvent, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.Object, queue)
+		},
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WorkbenchSummaryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&defaultv1alpha1.WorkbenchSummary{}).
+		Watches(&defaultv1alpha1.Workbench{}, debouncedSummaryHandler(r.Debounce)).
+		Complete(r)
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("WorkbenchSummary Controller", func() {
+	const namespace = "summary"
+
+	ctx := context.Background()
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      defaultv1alpha1.WorkbenchSummaryName,
+			Namespace: namespace,
+		},
+	}
+
+	BeforeEach(func() {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+		}
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns))).To(Succeed())
+	})
+
+	createWorkbench := func(name string, serverStatus defaultv1alpha1.WorkbenchStatusServerStatus, runningApps int) {
+		workbench := &defaultv1alpha1.Workbench{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		}
+		Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+
+		workbench.Status.Server = defaultv1alpha1.WorkbenchStatusServer{
+			Revision: -1,
+			Status:   serverStatus,
+		}
+		workbench.Status.RunningAppCount = runningApps
+		Expect(k8sClient.Status().Update(ctx, workbench)).To(Succeed())
+	}
+
+	getSummary := func() *defaultv1alpha1.WorkbenchSummary {
+		summary := &defaultv1alpha1.WorkbenchSummary{}
+		Expect(k8sClient.Get(ctx, request.NamespacedName, summary)).To(Succeed())
+
+		return summary
+	}
+
+	It("should converge after creating and deleting workbenches", func() {
+		fakeClock := clocktesting.NewFakePassiveClock(time.Now().Truncate(time.Second))
+		controllerReconciler := &WorkbenchSummaryReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			Clock:  fakeClock,
+		}
+
+		createWorkbench("first", defaultv1alpha1.WorkbenchStatusServerStatusRunning, 2)
+		createWorkbench("second", defaultv1alpha1.WorkbenchStatusServerStatusRunning, 1)
+		createWorkbench("third", defaultv1alpha1.WorkbenchStatusServerStatusProgressing, 0)
+
+		_, err := controllerReconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		summary := getSummary()
+		Expect(summary.Status.Workbenches).To(Equal(3))
+		Expect(summary.Status.RunningApps).To(Equal(3))
+		Expect(summary.Status.ServerStatuses).To(Equal(map[string]int{"Running": 2, "Progressing": 1}))
+		Expect(summary.Status.LastUpdateTime).NotTo(BeNil())
+		Expect(summary.Status.LastUpdateTime.Time).To(BeTemporally("==", fakeClock.Now()))
+
+		// Nothing changed, nothing is written.
+		resourceVersion := summary.ResourceVersion
+
+		_, err = controllerReconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getSummary().ResourceVersion).To(Equal(resourceVersion))
+
+		for _, name := range []string{"first", "third"} {
+			deleteWorkbench(ctx, types.NamespacedName{Name: name, Namespace: namespace})
+		}
+
+		_, err = controllerReconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		summary = getSummary()
+		Expect(summary.Status.Workbenches).To(Equal(1))
+		Expect(summary.Status.RunningApps).To(Equal(1))
+		Expect(summary.Status.ServerStatuses).To(Equal(map[string]int{"Running": 1}))
+
+		deleteWorkbench(ctx, types.NamespacedName{Name: "second", Namespace: namespace})
+	})
+})
+
+var _ = Describe("WorkbenchSummary events", func() {
+	ctx := context.Background()
+
+	It("should coalesce the bursts", func() {
+		queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		defer queue.ShutDown()
+
+		eventHandler := debouncedSummaryHandler(100 * time.Millisecond)
+
+		for i := 0; i < 10; i++ {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("burst-%d", i),
+					Namespace: "summary",
+				},
+			}
+
+			eventHandler.Create(ctx, event.CreateEvent{Object: workbench}, queue)
+		}
+
+		// Nothing before the delay.
+		Expect(queue.Len()).To(Equal(0))
+
+		Eventually(queue.Len).Should(Equal(1))
+		Consistently(queue.Len, 200*time.Millisecond).Should(Equal(1))
+
+		item, _ := queue.Get()
+		Expect(item.Namespace).To(Equal("summary"))
+		Expect(item.Name).To(Equal(defaultv1alpha1.WorkbenchSummaryName))
+	})
+})
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// workbenchWrites is how a pass writes the workbench it reconciles.
+//
+// The spec is never written. The metadata (finalizers, labels and annotations) and the
+// status are changed in memory, then merge patched from the workbench as last written,
+// so a pass neither sends the whole object nor fails on a concurrent spec update.
+type workbenchWrites struct {
+	// written is the workbench as known by the API server.
+	written *defaultv1alpha1.Workbench
+	// metadata is the metadata as last written by the pass, without the changes made
+	// concurrently by the others.
+	metadata *defaultv1alpha1.Workbench
+}
+
+// newWorkbenchWrites starts the writes of a pass, from the workbench as read.
+func newWorkbenchWrites(workbench defaultv1alpha1.Workbench) *workbenchWrites {
+	return &workbenchWrites{
+		written:  workbench.DeepCopy(),
+		metadata: workbench.DeepCopy(),
+	}
+}
+
+// metadataChanged tells whether the finalizers, labels or annotations have to be written.
+func (w *workbenchWrites) metadataChanged(workbench defaultv1alpha1.Workbench) bool {
+	return !equality.Semantic.DeepEqual(w.metadata.Finalizers, workbench.Finalizers) ||
+		!equality.Semantic.DeepEqual(w.metadata.Labels, workbench.Labels) ||
+		!equality.Semantic.DeepEqual(w.metadata.Annotations, workbench.Annotations)
+}
+
+// patchMetadata writes the metadata changes, if any.
+//
+// It is done once at the end of the pass, only the finalizer is written beforehand as
+// it must be there before any child is created.
+//
+// A merge patch replaces the whole list of finalizers: when they change, the patch is
+// locked on the resource version, and on a conflict, the changes of the pass are applied
+// again onto the workbench as read anew. The finalizers of the others are kept that way.
+func (w *workbenchWrites) patchMetadata(ctx context.Context, c client.Client, workbench defaultv1alpha1.Workbench) error {
+	if !w.metadataChanged(workbench) {
+		return nil
+	}
+
+	base := w.written
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		written := base.DeepCopy()
+		applyMetadataChanges(*w.metadata, workbench, written)
+
+		patch := client.MergeFrom(base)
+		if !equality.Semantic.DeepEqual(base.Finalizers, written.Finalizers) {
+			patch = client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})
+		}
+
+		// The response is not kept, the other fields may have changed in the meantime.
+		err := c.Patch(ctx, written.DeepCopy(), patch)
+		if apierrors.IsConflict(err) {
+			fresh := &defaultv1alpha1.Workbench{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(base), fresh); err != nil {
+				return err
+			}
+
+			base = fresh
+		}
+
+		if err != nil {
+			return err
+		}
+
+		w.written = written
+		w.metadata = workbench.DeepCopy()
+
+		return nil
+	})
+}
+
+// applyMetadataChanges applies onto the target the finalizers, labels and annotations
+// changed from the previous workbench to the next one, leaving the others alone.
+func applyMetadataChanges(previous defaultv1alpha1.Workbench, next defaultv1alpha1.Workbench, target *defaultv1alpha1.Workbench) {
+	for _, finalizer := range previous.Finalizers {
+		if !slices.Contains(next.Finalizers, finalizer) {
+			controllerutil.RemoveFinalizer(target, finalizer)
+		}
+	}
+
+	for _, finalizer := range next.Finalizers {
+		if !slices.Contains(previous.Finalizers, finalizer) {
+			controllerutil.AddFinalizer(target, finalizer)
+		}
+	}
+
+	target.Labels = applyMapChanges(previous.Labels, next.Labels, target.Labels)
+	target.Annotations = applyMapChanges(previous.Annotations, next.Annotations, target.Annotations)
+}
+
+// applyMapChanges applies onto the target the keys set, changed or removed from the
+// previous map to the next one.
+func applyMapChanges(previous map[string]string, next map[string]string, target map[string]string) map[string]string {
+	target = maps.Clone(target)
+
+	for key := range previous {
+		if _, ok := next[key]; !ok {
+			delete(target, key)
+		}
+	}
+
+	for key, value := range next {
+		if current, ok := previous[key]; !ok || current != value {
+			if target == nil {
+				target = map[string]string{}
+			}
+
+			target[key] = value
+		}
+	}
+
+	return target
+}
+
+// patchStatus writes the status, it is done once at the end of the pass.
+func (w *workbenchWrites) patchStatus(ctx context.Context, c client.Client, workbench defaultv1alpha1.Workbench) error {
+	written := w.written.DeepCopy()
+	written.Status = *workbench.Status.DeepCopy()
+
+	if err := c.Status().Patch(ctx, written.DeepCopy(), client.MergeFrom(w.written)); err != nil {
+		return err
+	}
+
+	w.written = written
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// workbenchWriteCounter counts the writes of the workbenches, by kind.
+type workbenchWriteCounter struct {
+	client.Client
+
+	// patches holds the merge patches sent, when there is no API server behind.
+	patches []map[string]any
+	// counts of the writes: "update", "patch", "status".
+	counts map[string]int
+}
+
+func newWorkbenchWriteCounter(c client.Client) *workbenchWriteCounter {
+	return &workbenchWriteCounter{Client: c, counts: map[string]int{}}
+}
+
+func (c *workbenchWriteCounter) record(kind string, obj client.Object, patch client.Patch) error {
+	if _, ok := obj.(*defaultv1alpha1.Workbench); !ok {
+		return nil
+	}
+
+	c.counts[kind]++
+
+	if patch != nil && c.Client == nil {
+		data, err := patch.Data(obj)
+		if err != nil {
+			return err
+		}
+
+		decoded := map[string]any{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return err
+		}
+
+		c.patches = append(c.patches, decoded)
+	}
+
+	return nil
+}
+
+func (c *workbenchWriteCounter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.record("update", obj, nil); err != nil {
+		return err
+	}
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *workbenchWriteCounter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.record("patch", obj, patch); err != nil || c.Client == nil {
+		return err
+	}
+
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *workbenchWriteCounter) Status() client.SubResourceWriter {
+	return &workbenchStatusWriteCounter{counter: c}
+}
+
+type workbenchStatusWriteCounter struct {
+	counter *workbenchWriteCounter
+}
+
+func (w *workbenchStatusWriteCounter) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	return w.counter.Client.Status().Create(ctx, obj, subResource, opts...)
+}
+
+func (w *workbenchStatusWriteCounter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	if err := w.counter.record("status", obj, nil); err != nil {
+		return err
+	}
+
+	return w.counter.Client.Status().Update(ctx, obj, opts...)
+}
+
+func (w *workbenchStatusWriteCounter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if err := w.counter.record("status", obj, patch); err != nil || w.counter.Client == nil {
+		return err
+	}
+
+	return w.counter.Client.Status().Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("Workbench writes", func() {
+	newWorkbench := func() defaultv1alpha1.Workbench {
+		workbench := defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Namespace = "default"
+		workbench.ResourceVersion = "1"
+		workbench.Labels = map[string]string{"team": "imaging"}
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{{Name: "wezterm"}}
+
+		return workbench
+	}
+
+	It("should only patch the changed metadata", func() {
+		ctx := context.Background()
+		counter := newWorkbenchWriteCounter(nil)
+
+		workbench := newWorkbench()
+		writes := newWorkbenchWrites(workbench)
+
+		Expect(writes.patchMetadata(ctx, counter, workbench)).To(Succeed())
+		Expect(counter.patches).To(BeEmpty())
+
+		workbench.Finalizers = append(workbench.Finalizers, finalizer)
+		workbench.Spec.Apps[0].State = "Stopped"
+		workbench.Status.AppCount = 1
+
+		Expect(writes.metadataChanged(workbench)).To(BeTrue())
+		Expect(writes.patchMetadata(ctx, counter, workbench)).To(Succeed())
+		Expect(counter.patches).To(HaveLen(1))
+		// Locked, the finalizers are written as a whole.
+		Expect(counter.patches[0]).To(Equal(map[string]any{
+			"metadata": map[string]any{"finalizers": []any{finalizer}, "resourceVersion": "1"},
+		}))
+
+		// Written, and not aliased with the workbench.
+		Expect(writes.metadataChanged(workbench)).To(BeFalse())
+
+		delete(workbench.Labels, "team")
+		Expect(writes.metadataChanged(workbench)).To(BeTrue())
+	})
+
+	It("should keep the finalizers added concurrently by the others", func() {
+		ctx := context.Background()
+
+		testScheme := runtime.NewScheme()
+		Expect(defaultv1alpha1.AddToScheme(testScheme)).To(Succeed())
+
+		workbench := newWorkbench()
+		workbench.ResourceVersion = ""
+		c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&workbench).Build()
+
+		read := defaultv1alpha1.Workbench{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(&workbench), &read)).To(Succeed())
+		writes := newWorkbenchWrites(read)
+
+		// Another controller puts its finalizer after the read.
+		foreign := read.DeepCopy()
+		foreign.Finalizers = append(foreign.Finalizers, "backup.example.com/snapshot")
+		Expect(c.Update(ctx, foreign)).To(Succeed())
+
+		controllerutil.AddFinalizer(&read, finalizer)
+		read.Labels["team"] = "genomics"
+		Expect(writes.patchMetadata(ctx, c, read)).To(Succeed())
+
+		written := defaultv1alpha1.Workbench{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(&workbench), &written)).To(Succeed())
+		Expect(written.Finalizers).To(ConsistOf("backup.example.com/snapshot", finalizer))
+		Expect(written.Labels).To(HaveKeyWithValue("team", "genomics"))
+
+		// Removing ours leaves theirs.
+		Expect(writes.metadataChanged(read)).To(BeFalse())
+		controllerutil.RemoveFinalizer(&read, finalizer)
+		Expect(writes.patchMetadata(ctx, c, read)).To(Succeed())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(&workbench), &written)).To(Succeed())
+		Expect(written.Finalizers).To(ConsistOf("backup.example.com/snapshot"))
+	})
+
+	It("should only patch the status", func() {
+		ctx := context.Background()
+		counter := newWorkbenchWriteCounter(nil)
+
+		workbench := newWorkbench()
+		writes := newWorkbenchWrites(workbench)
+
+		workbench.Labels["team"] = "genomics"
+		workbench.Status.AppCount = 1
+
+		Expect(writes.patchStatus(ctx, counter, workbench)).To(Succeed())
+		Expect(counter.counts).To(Equal(map[string]int{"status": 1}))
+		Expect(counter.patches).To(Equal([]map[string]any{
+			{"status": map[string]any{"appCount": float64(1)}},
+		}))
+
+		// The labels are still to be written.
+		Expect(writes.metadataChanged(workbench)).To(BeTrue())
+	})
+
+	Context("When reconciling a workbench", func() {
+		const resourceName = "workbench-writes"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			workbench := &defaultv1alpha1.Workbench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+			}
+
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{{Name: "wezterm"}}
+
+			Expect(k8sClient.Create(ctx, workbench)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err := k8sClient.Get(ctx, typeNamespacedName, &defaultv1alpha1.Workbench{})
+			if !apierrors.IsNotFound(err) {
+				deleteWorkbench(ctx, typeNamespacedName)
+			}
+		})
+
+		It("should write it at most once per kind, and never update it", func() {
+			counter := newWorkbenchWriteCounter(k8sClient)
+			controllerReconciler := &WorkbenchReconciler{
+				Client:   counter,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Config:   Config{Images: ImagesConfig{AppsRepository: "apps"}},
+			}
+
+			reconcileOnce := func() map[string]int {
+				counter.counts = map[string]int{}
+
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				return counter.counts
+			}
+
+			By("creating the children of a new workbench")
+			Expect(reconcileOnce()).To(Equal(map[string]int{"patch": 1, "status": 1}))
+
+			By("reading the status of the children")
+			Expect(reconcileOnce()).NotTo(HaveKey("update"))
+
+			By("being in a steady state")
+			Expect(reconcileOnce()).To(BeEmpty())
+
+			By("deleting the workbench")
+			workbench := &defaultv1alpha1.Workbench{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, workbench)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, workbench)).To(Succeed())
+
+			// The children are deleted, and reported.
+			Expect(reconcileOnce()).To(Equal(map[string]int{"status": 1}))
+
+			// There is no garbage collection in envtest, the children are gone already.
+			Expect(reconcileOnce()).To(Equal(map[string]int{"patch": 1}))
+		})
+	})
+})
@@ -0,0 +1,38 @@
+package v1alpha1
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// rejectionsTotal counts the rejected workbenches, once per distinct error type.
+	rejectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workbench_webhook_rejections_total",
+			Help: "Number of Workbench creations and updates rejected by the validating webhook, by operation, namespace and error type.",
+		},
+		[]string{"operation", "namespace", "code"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		rejectionsTotal,
+	)
+}
+
+// countRejection records a rejection under each of the error types it contains.
+func countRejection(operation string, namespace string, errs field.ErrorList) {
+	seen := map[field.ErrorType]bool{}
+
+	for _, err := range errs {
+		if seen[err.Type] {
+			continue
+		}
+
+		seen[err.Type] = true
+		rejectionsTotal.WithLabelValues(operation, namespace, string(err.Type)).Inc()
+	}
+}
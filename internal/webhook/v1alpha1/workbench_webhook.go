@@ -0,0 +1,284 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+// log is for logging in this package.
+var workbenchlog = logf.Log.WithName("workbench-resource")
+
+// minCPURequest is the smallest CPU request that is not almost certainly a typo of the unit.
+var minCPURequest = resource.MustParse("1m")
+
+// maxObservers bounds the observers of a session, each one encoding the whole display.
+const maxObservers = 3
+
+// reservedMountPaths are the paths the operator mounts into the app container, the extra mounts
+// may neither shadow them nor go below them.
+var reservedMountPaths = []string{defaultv1alpha1.ShmMountPath}
+
+// SetupWorkbenchWebhookWithManager registers the webhook for Workbench in the manager.
+//
+// A zero maxShmSize does not bound the /dev/shm size of the apps. Without an apps registry,
+// the apps without an image are accepted with a warning. The apps may only name one of the
+// resourceProfiles.
+func SetupWorkbenchWebhookWithManager(mgr ctrl.Manager, maxShmSize resource.Quantity, hasAppsRegistry bool, resourceProfiles []string) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&defaultv1alpha1.Workbench{}).
+		WithValidator(&WorkbenchCustomValidator{
+			MaxShmSize:       maxShmSize,
+			NoAppsRegistry:   !hasAppsRegistry,
+			ResourceProfiles: resourceProfiles,
+		}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-default-chorus-tre-ch-v1alpha1-workbench,mutating=false,failurePolicy=fail,sideEffects=None,groups=default.chorus-tre.ch,resources=workbenches,verbs=create;update,versions=v1alpha1,name=vworkbench-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// WorkbenchCustomValidator rejects the workbenches the kubelet would only refuse late.
+//
+// The quantities arrive parsed, but nothing prevents them from being zero or negative.
+type WorkbenchCustomValidator struct {
+	// MaxShmSize bounds the /dev/shm size of the apps, unless zero.
+	MaxShmSize resource.Quantity
+	// NoAppsRegistry warns about the apps without an image, as the operator cannot complete their name.
+	NoAppsRegistry bool
+	// ResourceProfiles are the names of the resource profiles configured on the operator.
+	ResourceProfiles []string
+}
+
+var _ webhook.CustomValidator = &WorkbenchCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Workbench.
+func (v *WorkbenchCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	workbench, ok := obj.(*defaultv1alpha1.Workbench)
+	if !ok {
+		return nil, fmt.Errorf("expected a Workbench object but got %T", obj)
+	}
+	workbenchlog.V(1).Info("Validation for Workbench upon creation", "name", workbench.GetName())
+
+	return v.validate(workbench, nil, "create")
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Workbench.
+func (v *WorkbenchCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	workbench, ok := newObj.(*defaultv1alpha1.Workbench)
+	if !ok {
+		return nil, fmt.Errorf("expected a Workbench object for the newObj but got %T", newObj)
+	}
+	oldWorkbench, ok := oldObj.(*defaultv1alpha1.Workbench)
+	if !ok {
+		return nil, fmt.Errorf("expected a Workbench object for the oldObj but got %T", oldObj)
+	}
+	workbenchlog.V(1).Info("Validation for Workbench upon update", "name", workbench.GetName())
+
+	return v.validate(workbench, oldWorkbench, "update")
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Workbench.
+func (v *WorkbenchCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the quantities of all the apps, the references, and on update what cannot change.
+//
+// The rejections are counted per operation, so the misbehaving clients stand out.
+func (v *WorkbenchCustomValidator) validate(workbench, oldWorkbench *defaultv1alpha1.Workbench, operation string) (admission.Warnings, error) {
+	warnings := admission.Warnings{}
+	errs := field.ErrorList{}
+
+	// The paths are only built for the errors and warnings, as the large workbenches applied
+	// over and over are mostly valid.
+	appsPath := field.NewPath("spec").Child("apps")
+	for index, app := range workbench.Spec.Apps {
+		// Not an error, the operator may be redeployed with a registry.
+		if v.NoAppsRegistry && app.Image == nil && app.State != defaultv1alpha1.WorkbenchAppStateStopped {
+			warnings = append(warnings, fmt.Sprintf("%s: no image and no registry configured on the operator, the app will fail with NoRegistryConfigured", appsPath.Index(index).Child("image")))
+		}
+
+		if app.ShmSize != nil {
+			if app.ShmSize.Sign() <= 0 {
+				errs = append(errs, field.Invalid(appsPath.Index(index).Child("shmSize"), app.ShmSize.String(), "must be positive"))
+			} else if !v.MaxShmSize.IsZero() && app.ShmSize.Cmp(v.MaxShmSize) > 0 {
+				errs = append(errs, field.Invalid(appsPath.Index(index).Child("shmSize"), app.ShmSize.String(), fmt.Sprintf("must not exceed %s", v.MaxShmSize.String())))
+			}
+		}
+
+		if app.Resources != nil {
+			appWarnings, appErrs := validateResources(*app.Resources, func() *field.Path {
+				return appsPath.Index(index).Child("resources")
+			})
+			warnings = append(warnings, appWarnings...)
+			errs = append(errs, appErrs...)
+		}
+
+		if ttl := app.JobTTLSecondsAfterFinished; ttl != nil && *ttl < 0 {
+			errs = append(errs, field.Invalid(appsPath.Index(index).Child("jobTTLSecondsAfterFinished"), *ttl, "must not be negative"))
+		}
+
+		if app.ResourceProfile != "" && !slices.Contains(v.ResourceProfiles, app.ResourceProfile) {
+			errs = append(errs, field.NotSupported(appsPath.Index(index).Child("resourceProfile"), app.ResourceProfile, v.ResourceProfiles))
+		}
+
+		if len(app.ExtraMounts) > 0 {
+			errs = append(errs, validateExtraMounts(app.ExtraMounts, appsPath.Index(index).Child("extraMounts"))...)
+		}
+
+		for sidecarIndex, sidecar := range app.SidecarContainers {
+			// The operator hardens the sidecars, it would silently override it.
+			if sidecar.SecurityContext != nil {
+				errs = append(errs, field.Forbidden(appsPath.Index(index).Child("sidecarContainers").Index(sidecarIndex).Child("securityContext"), "is set by the operator"))
+			}
+
+			sidecarWarnings, sidecarErrs := validateResources(sidecar.Resources, func() *field.Path {
+				return appsPath.Index(index).Child("sidecarContainers").Index(sidecarIndex).Child("resources")
+			})
+			warnings = append(warnings, sidecarWarnings...)
+			errs = append(errs, sidecarErrs...)
+		}
+	}
+
+	if resources := workbench.Spec.Server.Resources; resources != nil {
+		serverWarnings, serverErrs := validateResources(*resources, func() *field.Path {
+			return field.NewPath("spec").Child("server").Child("resources")
+		})
+		warnings = append(warnings, serverWarnings...)
+		errs = append(errs, serverErrs...)
+	}
+
+	// The observers shadow a session that survives the server restarts.
+	if observers := workbench.Spec.Server.Observers; observers < 0 || observers > maxObservers {
+		errs = append(errs, field.Invalid(field.NewPath("spec").Child("server").Child("observers"), observers, fmt.Sprintf("must be between 0 and %d", maxObservers)))
+	} else if observers > 0 && !workbench.Spec.Server.PersistentSession {
+		errs = append(errs, field.Invalid(field.NewPath("spec").Child("server").Child("observers"), observers, "requires spec.server.persistentSession"))
+	}
+
+	// A zero timeout would suspend the workbench on every pass.
+	if timeout := workbench.Spec.Server.IdleTimeout; timeout != nil && timeout.Duration <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("spec").Child("server").Child("idleTimeout"), timeout.Duration.String(), "must be positive"))
+	}
+
+	if strings.IndexFunc(workbench.Spec.DisplayName, unicode.IsControl) >= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("spec").Child("displayName"), workbench.Spec.DisplayName, "must not contain control characters"))
+	}
+
+	// The references may not reach out of the workbench namespace.
+	errs = append(errs, workbench.ValidateReferences()...)
+
+	// The children already created would be left behind in the former target.
+	if oldWorkbench != nil && workbench.Spec.TargetNamespace != oldWorkbench.Spec.TargetNamespace {
+		errs = append(errs, field.Forbidden(field.NewPath("spec").Child("targetNamespace"), "cannot be changed"))
+	}
+
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+
+	countRejection(operation, workbench.Namespace, errs)
+
+	return warnings, apierrors.NewInvalid(
+		defaultv1alpha1.GroupVersion.WithKind("Workbench").GroupKind(),
+		workbench.Name,
+		errs,
+	)
+}
+
+// validateResources rejects the negative quantities and the requests above their limits.
+//
+// A CPU request below a millicore is accepted with a warning. The path of the resources is
+// only built when there is something to report.
+func validateResources(resources corev1.ResourceRequirements, path func() *field.Path) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var errs field.ErrorList
+
+	for name, limit := range resources.Limits {
+		if limit.Sign() < 0 {
+			errs = append(errs, field.Invalid(path().Child("limits").Key(string(name)), limit.String(), "must not be negative"))
+		}
+	}
+
+	for name, request := range resources.Requests {
+		requestPath := func() *field.Path {
+			return path().Child("requests").Key(string(name))
+		}
+
+		if request.Sign() < 0 {
+			errs = append(errs, field.Invalid(requestPath(), request.String(), "must not be negative"))
+			continue
+		}
+
+		if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(requestPath(), request.String(), fmt.Sprintf("must not exceed the limit %s", limit.String())))
+		}
+
+		if name == corev1.ResourceCPU && request.Cmp(minCPURequest) < 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: %s is less than %s, is the unit right?", requestPath(), request.String(), minCPURequest.String()))
+		}
+	}
+
+	return warnings, errs
+}
+
+// validateExtraMounts rejects the mounts of anything else than exactly one ConfigMap or Secret,
+// and those colliding with each other or with the operator managed paths.
+func validateExtraMounts(mounts []defaultv1alpha1.AppMount, mountsPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	mountPaths := map[string]bool{}
+
+	for index, mount := range mounts {
+		mountPath := mountsPath.Index(index)
+
+		if (mount.ConfigMap == "") == (mount.Secret == "") {
+			errs = append(errs, field.Invalid(mountPath, mount.ConfigMap+mount.Secret, "must set exactly one of configMap and secret"))
+		}
+
+		if !path.IsAbs(mount.MountPath) {
+			errs = append(errs, field.Invalid(mountPath.Child("mountPath"), mount.MountPath, "must be an absolute path"))
+			continue
+		}
+
+		cleaned := path.Clean(mount.MountPath)
+
+		if mountPaths[cleaned] {
+			errs = append(errs, field.Duplicate(mountPath.Child("mountPath"), mount.MountPath))
+			continue
+		}
+
+		mountPaths[cleaned] = true
+
+		if reserved, ok := reservedMountPath(cleaned); ok {
+			errs = append(errs, field.Invalid(mountPath.Child("mountPath"), mount.MountPath, fmt.Sprintf("collides with %s, managed by the operator", reserved)))
+		}
+	}
+
+	return errs
+}
+
+// reservedMountPath tells the operator managed path the cleaned mount path would shadow or
+// go into, if any.
+func reservedMountPath(mountPath string) (string, bool) {
+	for _, reserved := range reservedMountPaths {
+		if mountPath == reserved || mountPath == "/" || strings.HasPrefix(reserved, mountPath+"/") || strings.HasPrefix(mountPath, reserved+"/") {
+			return reserved, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,378 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+
+	defaultv1alpha1 "github.com/CHORUS-TRE/workbench-operator/api/v1alpha1"
+)
+
+var _ = Describe("Workbench Webhook", func() {
+	validator := &WorkbenchCustomValidator{
+		MaxShmSize:       resource.MustParse("1Gi"),
+		ResourceProfiles: []string{"large", "small"},
+	}
+
+	quantity := func(value string) *resource.Quantity {
+		q := resource.MustParse(value)
+		return &q
+	}
+
+	withShmSize := func(value string) defaultv1alpha1.WorkbenchApp {
+		return defaultv1alpha1.WorkbenchApp{Name: "app", ShmSize: quantity(value)}
+	}
+
+	withSidecarResources := func(resources corev1.ResourceRequirements) defaultv1alpha1.WorkbenchApp {
+		return defaultv1alpha1.WorkbenchApp{
+			Name: "app",
+			SidecarContainers: []corev1.Container{
+				{Name: "sidecar", Resources: resources},
+			},
+		}
+	}
+
+	withExtraMounts := func(mounts ...defaultv1alpha1.AppMount) defaultv1alpha1.WorkbenchApp {
+		return defaultv1alpha1.WorkbenchApp{Name: "app", ExtraMounts: mounts}
+	}
+
+	DescribeTable("validating the apps",
+		func(app defaultv1alpha1.WorkbenchApp, invalidField string, warning string) {
+			workbench := &defaultv1alpha1.Workbench{}
+			workbench.Name = "workbench"
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{app}
+
+			warnings, err := validator.ValidateCreate(context.Background(), workbench)
+			if invalidField == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(invalidField)))
+			}
+
+			if warning == "" {
+				Expect(warnings).To(BeEmpty())
+			} else {
+				Expect(warnings).To(ContainElement(ContainSubstring(warning)))
+			}
+
+			// Updates follow the same rules.
+			_, updateErr := validator.ValidateUpdate(context.Background(), workbench, workbench)
+			Expect(updateErr == nil).To(Equal(err == nil))
+		},
+		Entry("without quantities", defaultv1alpha1.WorkbenchApp{Name: "app"}, "", ""),
+		Entry("a valid shm size", withShmSize("512Mi"), "", ""),
+		Entry("a zero shm size", withShmSize("0"), "spec.apps[0].shmSize", ""),
+		Entry("a negative shm size", withShmSize("-1Mi"), "spec.apps[0].shmSize", ""),
+		Entry("a too large shm size", withShmSize("2Gi"), "must not exceed 1Gi", ""),
+		Entry("valid sidecar resources", withSidecarResources(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		}), "", ""),
+		Entry("a request above its limit", withSidecarResources(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		}), "spec.apps[0].sidecarContainers[0].resources.requests[memory]", ""),
+		Entry("a negative request", withSidecarResources(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("-1Mi")},
+		}), "resources.requests[memory]", ""),
+		Entry("a negative limit", withSidecarResources(corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("-1")},
+		}), "resources.limits[cpu]", ""),
+		Entry("a tiny CPU request", withSidecarResources(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100u")},
+		}), "", "is the unit right?"),
+		Entry("a sidecar security context", defaultv1alpha1.WorkbenchApp{
+			Name: "app",
+			SidecarContainers: []corev1.Container{
+				{Name: "sidecar", SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(true)}},
+			},
+		}, "spec.apps[0].sidecarContainers[0].securityContext: Forbidden", ""),
+		Entry("an app request above its limit", defaultv1alpha1.WorkbenchApp{
+			Name: "app",
+			Resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			},
+		}, "spec.apps[0].resources.requests[cpu]", ""),
+		Entry("a zero job ttl", defaultv1alpha1.WorkbenchApp{Name: "app", JobTTLSecondsAfterFinished: ptr.To(int32(0))}, "", ""),
+		Entry("a negative job ttl", defaultv1alpha1.WorkbenchApp{Name: "app", JobTTLSecondsAfterFinished: ptr.To(int32(-1))}, "spec.apps[0].jobTTLSecondsAfterFinished", ""),
+		Entry("a config map and a secret mount", withExtraMounts(
+			defaultv1alpha1.AppMount{ConfigMap: "matplotlib", MountPath: "/etc/matplotlibrc", SubPath: "matplotlibrc"},
+			defaultv1alpha1.AppMount{Secret: "freesurfer", MountPath: "/opt/freesurfer/license.txt", ReadOnly: true},
+		), "", ""),
+		Entry("a mount without a source", withExtraMounts(
+			defaultv1alpha1.AppMount{MountPath: "/etc/app"},
+		), "must set exactly one of configMap and secret", ""),
+		Entry("a mount of both sources", withExtraMounts(
+			defaultv1alpha1.AppMount{ConfigMap: "settings", Secret: "license", MountPath: "/etc/app"},
+		), "spec.apps[0].extraMounts[0]", ""),
+		Entry("a relative mount path", withExtraMounts(
+			defaultv1alpha1.AppMount{ConfigMap: "settings", MountPath: "etc/app"},
+		), "must be an absolute path", ""),
+		Entry("the same mount path twice", withExtraMounts(
+			defaultv1alpha1.AppMount{ConfigMap: "settings", MountPath: "/etc/app"},
+			defaultv1alpha1.AppMount{Secret: "license", MountPath: "/etc/app/"},
+		), "spec.apps[0].extraMounts[1].mountPath: Duplicate value", ""),
+		Entry("a mount over the shm", withExtraMounts(
+			defaultv1alpha1.AppMount{ConfigMap: "settings", MountPath: "/dev"},
+		), "collides with /dev/shm", ""),
+		Entry("a mount into the shm", withExtraMounts(
+			defaultv1alpha1.AppMount{ConfigMap: "settings", MountPath: "/dev/shm/settings"},
+		), "collides with /dev/shm", ""),
+		Entry("a mount next to the shm", withExtraMounts(
+			defaultv1alpha1.AppMount{ConfigMap: "settings", MountPath: "/dev/shmem"},
+		), "", ""),
+		Entry("a mount into the home, not mounted by the operator", withExtraMounts(
+			defaultv1alpha1.AppMount{Secret: "license", MountPath: "/home/researcher/.license"},
+		), "", ""),
+		Entry("a mount into /mnt, not mounted by the operator", withExtraMounts(
+			defaultv1alpha1.AppMount{Secret: "license", MountPath: "/mnt/data/license"},
+		), "", ""),
+		Entry("a mount over everything", withExtraMounts(
+			defaultv1alpha1.AppMount{Secret: "license", MountPath: "/"},
+		), "spec.apps[0].extraMounts[0].mountPath: Invalid value: \"/\": collides with /dev/shm", ""),
+		Entry("a known resource profile", defaultv1alpha1.WorkbenchApp{Name: "app", ResourceProfile: "small"}, "", ""),
+		Entry("an unknown resource profile", defaultv1alpha1.WorkbenchApp{Name: "app", ResourceProfile: "medium"}, `spec.apps[0].resourceProfile: Unsupported value: "medium"`, ""),
+	)
+
+	It("should validate the server resources like the sidecar ones", func() {
+		workbench := &defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Spec.Server.Resources = &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		}
+
+		_, err := validator.ValidateCreate(context.Background(), workbench)
+		Expect(err).To(MatchError(ContainSubstring("spec.server.resources.requests[memory]")))
+	})
+
+	It("should reject the references to another namespace", func() {
+		workbench := &defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Spec.ImagePullSecrets = []string{"workbench-operator-system/registry"}
+
+		_, err := validator.ValidateCreate(context.Background(), workbench)
+		Expect(err).To(MatchError(ContainSubstring("spec.imagePullSecrets[0]")))
+	})
+
+	DescribeTable("validating the display name",
+		func(displayName string, valid bool) {
+			workbench := &defaultv1alpha1.Workbench{}
+			workbench.Name = "workbench"
+			workbench.Spec.DisplayName = displayName
+
+			_, err := validator.ValidateCreate(context.Background(), workbench)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring("spec.displayName")))
+			}
+		},
+		Entry("a plain name", "My thesis analysis", true),
+		Entry("a unicode name", "Analyse de thèse ✓", true),
+		Entry("surrounding spaces, trimmed later", "  My thesis  ", true),
+		Entry("a new line", "My\nthesis", false),
+		Entry("an escape sequence", "\x1b[31mMy thesis", false),
+	)
+
+	It("should reject all the resource profiles when none is configured", func() {
+		workbench := &defaultv1alpha1.Workbench{}
+		workbench.Name = "workbench"
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{{Name: "app", ResourceProfile: "small"}}
+
+		_, err := (&WorkbenchCustomValidator{}).ValidateCreate(context.Background(), workbench)
+		Expect(err).To(MatchError(ContainSubstring("spec.apps[0].resourceProfile")))
+
+		workbench.Spec.Apps[0].ResourceProfile = ""
+		_, err = (&WorkbenchCustomValidator{}).ValidateCreate(context.Background(), workbench)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should not bound the shm size by default", func() {
+		workbench := &defaultv1alpha1.Workbench{}
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{withShmSize("64Gi")}
+
+		_, err := (&WorkbenchCustomValidator{}).ValidateCreate(context.Background(), workbench)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("warning about the apps without a registry",
+		func(app defaultv1alpha1.WorkbenchApp, warned bool) {
+			workbench := &defaultv1alpha1.Workbench{}
+			workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{app}
+
+			warnings, err := (&WorkbenchCustomValidator{NoAppsRegistry: true}).ValidateCreate(context.Background(), workbench)
+			Expect(err).NotTo(HaveOccurred())
+
+			if warned {
+				Expect(warnings).To(ConsistOf(ContainSubstring("NoRegistryConfigured")))
+			} else {
+				Expect(warnings).To(BeEmpty())
+			}
+		},
+		Entry("an app without an image", defaultv1alpha1.WorkbenchApp{Name: "app"}, true),
+		Entry("an app with an image", defaultv1alpha1.WorkbenchApp{
+			Name:  "app",
+			Image: &defaultv1alpha1.Image{Registry: "quay.io", Repository: "chorus/app"},
+		}, false),
+		Entry("a stopped app", defaultv1alpha1.WorkbenchApp{Name: "app", State: defaultv1alpha1.WorkbenchAppStateStopped}, false),
+	)
+
+	It("should not warn about the apps when a registry is configured", func() {
+		workbench := &defaultv1alpha1.Workbench{}
+		workbench.Spec.Apps = []defaultv1alpha1.WorkbenchApp{{Name: "app"}}
+
+		warnings, err := validator.ValidateCreate(context.Background(), workbench)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("should bound the observers, and require the persistent session", func() {
+		workbench := &defaultv1alpha1.Workbench{}
+		workbench.Spec.Server.Observers = 2
+
+		_, err := validator.ValidateCreate(context.Background(), workbench)
+		Expect(err).To(MatchError(ContainSubstring("requires spec.server.persistentSession")))
+
+		workbench.Spec.Server.PersistentSession = true
+		_, err = validator.ValidateCreate(context.Background(), workbench)
+		Expect(err).NotTo(HaveOccurred())
+
+		workbench.Spec.Server.Observers = 4
+		_, err = validator.ValidateCreate(context.Background(), workbench)
+		Expect(err).To(MatchError(ContainSubstring("spec.server.observers")))
+	})
+
+	It("should only accept a positive idle timeout", func() {
+		workbench := &defaultv1alpha1.Workbench{}
+		workbench.Spec.Server.IdleTimeout = &metav1.Duration{Duration: 8 * time.Hour}
+
+		_, err := validator.ValidateCreate(context.Background(), workbench)
+		Expect(err).NotTo(HaveOccurred())
+
+		workbench.Spec.Server.IdleTimeout.Duration = 0
+		_, err = validator.ValidateCreate(context.Background(), workbench)
+		Expect(err).To(MatchError(ContainSubstring("spec.server.idleTimeout")))
+	})
+
+	It("should not let the target namespace change", func() {
+		oldWorkbench := &defaultv1alpha1.Workbench{}
+		oldWorkbench.Name = "workbench"
+		oldWorkbench.Spec.TargetNamespace = "compute"
+
+		workbench := oldWorkbench.DeepCopy()
+		_, err := validator.ValidateUpdate(context.Background(), oldWorkbench, workbench)
+		Expect(err).NotTo(HaveOccurred())
+
+		workbench.Spec.TargetNamespace = "elsewhere"
+		_, err = validator.ValidateUpdate(context.Background(), oldWorkbench, workbench)
+		Expect(err).To(MatchError(ContainSubstring("spec.targetNamespace")))
+
+		workbench.Spec.TargetNamespace = ""
+		_, err = validator.ValidateUpdate(context.Background(), oldWorkbench, workbench)
+		Expect(err).To(MatchError(ContainSubstring("spec.targetNamespace")))
+	})
+
+	DescribeTable("counting the rejections",
+		func(apps []defaultv1alpha1.WorkbenchApp, rejected bool) {
+			workbench := &defaultv1alpha1.Workbench{}
+			workbench.Name = "workbench"
+			workbench.Namespace = "rejections"
+			workbench.Spec.Apps = apps
+
+			invalid := string(field.ErrorTypeInvalid)
+			creates := rejectionsTotal.WithLabelValues("create", "rejections", invalid)
+			updates := rejectionsTotal.WithLabelValues("update", "rejections", invalid)
+
+			expected := testutil.ToFloat64(creates)
+			if rejected {
+				expected++
+			}
+
+			_, _ = validator.ValidateCreate(context.Background(), workbench)
+			Expect(testutil.ToFloat64(creates)).To(Equal(expected))
+
+			// Updates are counted apart.
+			_, _ = validator.ValidateUpdate(context.Background(), workbench, workbench)
+			Expect(testutil.ToFloat64(creates)).To(Equal(expected))
+			Expect(testutil.ToFloat64(updates)).To(Equal(expected))
+		},
+		Entry("a valid workbench", []defaultv1alpha1.WorkbenchApp{withShmSize("512Mi")}, false),
+		Entry("a zero shm size", []defaultv1alpha1.WorkbenchApp{withShmSize("0")}, true),
+		Entry("a too large shm size", []defaultv1alpha1.WorkbenchApp{withShmSize("2Gi")}, true),
+		Entry("a request above its limit", []defaultv1alpha1.WorkbenchApp{withSidecarResources(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		})}, true),
+		Entry("a negative request", []defaultv1alpha1.WorkbenchApp{withSidecarResources(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("-1Mi")},
+		})}, true),
+		Entry("a negative limit", []defaultv1alpha1.WorkbenchApp{withSidecarResources(corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("-1")},
+		})}, true),
+		Entry("a tiny CPU request", []defaultv1alpha1.WorkbenchApp{withSidecarResources(corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100u")},
+		})}, false),
+		// Many errors of the same type are a single rejection.
+		Entry("many invalid apps", []defaultv1alpha1.WorkbenchApp{withShmSize("0"), withShmSize("2Gi")}, true),
+	)
+})
+
+// BenchmarkValidateUpdateNoop is the no-op apply of a large workbench, as done by the backend.
+func BenchmarkValidateUpdateNoop(b *testing.B) {
+	validator := &WorkbenchCustomValidator{
+		MaxShmSize:     resource.MustParse("1Gi"),
+		NoAppsRegistry: true,
+	}
+
+	shmSize := resource.MustParse("512Mi")
+
+	workbench := &defaultv1alpha1.Workbench{}
+	workbench.Name = "workbench"
+	workbench.Spec.Server.Resources = &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+	}
+
+	for index := range 200 {
+		workbench.Spec.Apps = append(workbench.Spec.Apps, defaultv1alpha1.WorkbenchApp{
+			Name:    fmt.Sprintf("app%d", index),
+			Image:   &defaultv1alpha1.Image{Registry: "registry.build.chorus-tre.local", Repository: "apps/wezterm"},
+			ShmSize: &shmSize,
+			SidecarContainers: []corev1.Container{
+				{
+					Name: "sidecar",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+						Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+					},
+				},
+			},
+		})
+	}
+
+	oldWorkbench := workbench.DeepCopy()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if _, err := validator.ValidateUpdate(context.Background(), oldWorkbench, workbench); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if perUpdate := b.Elapsed() / time.Duration(b.N); perUpdate > time.Millisecond {
+		b.Errorf("a no-op update took %s, more than a millisecond", perUpdate)
+	}
+}